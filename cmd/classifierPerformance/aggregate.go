@@ -0,0 +1,157 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "encoding/json"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Import all performance objects and summaries of scalar metrics (i.e.
+// metrics.json of output directories) from a JSON file, which may contain
+// several concatenated objects
+func import_aggregate_json(config Config, filename string) ([]Performance, []OutputMetrics) {
+  perfs   := []Performance{}
+  metrics := []OutputMetrics{}
+  import_file(config, filename, func(reader io.Reader) error {
+    decoder := json.NewDecoder(reader)
+    for decoder.More() {
+      var b json.RawMessage
+      if err := decoder.Decode(&b); err != nil {
+        return err
+      }
+      // summaries are recognized by their metrics field
+      probe := struct {
+        Metrics map[string]JSONFloat `json:"metrics"`
+      }{}
+      if err := json.Unmarshal(b, &probe); err != nil {
+        return err
+      }
+      if probe.Metrics != nil {
+        m := OutputMetrics{}
+        if err := json.Unmarshal(b, &m); err != nil {
+          return err
+        }
+        metrics = append(metrics, m)
+      } else {
+        perf := Performance{}
+        if err := json.Unmarshal(b, &perf); err != nil {
+          return err
+        }
+        perfs = append(perfs, perf)
+      }
+    }
+    return nil
+  })
+  return perfs, metrics
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Combine performance objects from several files, e.g. computed on different
+// shards of the data or by repeated runs. Targets are evaluated on the merged
+// performance, the summary target also reports the mean and standard
+// deviation over all objects. The summary target also accepts summaries of
+// scalar metrics (metrics.json of output directories), which only contribute
+// to mean and standard deviation, since they cannot be merged.
+func aggregate(config Config, target string, filenames []string) {
+  perfs     := []Performance{}
+  summaries := []OutputMetrics{}
+  for _, filename := range filenames {
+    p, m := import_aggregate_json(config, filename)
+    perfs     = append(perfs, p...)
+    summaries = append(summaries, m...)
+  }
+  target = strings.ToLower(target)
+  if len(summaries) > 0 && target != "summary" {
+    log.Fatalf("target `%s' requires performance objects, summaries of metrics are only supported by the summary target", target)
+  }
+  merged := Performance{}
+  for _, perf := range perfs {
+    merged = Merge(merged, perf)
+  }
+  if merged.P + merged.N == 0 && len(summaries) == 0 {
+    log.Fatal("no predictions found")
+  }
+  if _, ok := LookupMetric(target); config.Format != "text" && (!ok || !supports_format(config.Format, target)) {
+    log.Fatalf("--format %s is not supported by target `%s'", config.Format, target)
+  }
+  switch target {
+  case "summary":
+    names := summary_metrics(config, false)
+    // metrics of the merged performance are undefined if some inputs are
+    // summaries
+    r_merged := make([]float64, len(names))
+    for i := range r_merged {
+      r_merged[i] = math.NaN()
+    }
+    if len(summaries) == 0 {
+      if v, err := new_metrics(config, merged, nil, nil).Eval(names...); err != nil {
+        log.Fatal(err)
+      } else {
+        r_merged = v
+      }
+    } else {
+      fmt.Fprintf(os.Stderr, "notice: %d inputs are summaries of metrics, which cannot be merged\n", len(summaries))
+    }
+    r := make([][]float64, len(names))
+    for _, perf := range perfs {
+      if v, err := new_metrics(config, perf, nil, nil).Eval(names...); err != nil {
+        log.Fatal(err)
+      } else {
+        for i := range names {
+          r[i] = append(r[i], v[i])
+        }
+      }
+    }
+    for _, summary := range summaries {
+      for i, name := range names {
+        v, ok := summary.Metrics[name]
+        if !ok {
+          log.Fatalf("summary does not contain metric `%s'", name)
+        }
+        r[i] = append(r[i], float64(v))
+      }
+    }
+    if config.PrintHeader {
+      fmt.Println("metric merged mean sd")
+    }
+    for i := range names {
+      mean, sd := MeanSd(r[i])
+      fmt.Printf("%s %s %f %f\n", names[i], format_group_value(r_merged[i]), mean, sd)
+    }
+  case "performance":
+    if err := json.NewEncoder(os.Stdout).Encode(merged); err != nil {
+      log.Fatal(err)
+    }
+  default:
+    if metric, ok := LookupMetric(target); !ok {
+      log.Fatalf("invalid target: %s", target)
+    } else {
+      export_metric(config, os.Stdout, metric, new_metrics(config, merged, nil, nil))
+    }
+  }
+}
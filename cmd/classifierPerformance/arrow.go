@@ -0,0 +1,108 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "io"
+import   "log"
+import   "math"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+import   "github.com/apache/arrow-go/v18/arrow"
+import   "github.com/apache/arrow-go/v18/arrow/array"
+import   "github.com/apache/arrow-go/v18/arrow/ipc"
+import   "github.com/apache/arrow-go/v18/arrow/memory"
+
+/* -------------------------------------------------------------------------- */
+
+// Arrow IPC files (including Feather version 2) and streams are recognized
+// by their extension, which may be followed by a compression extension
+func is_arrow_file(filename string) bool {
+  name := strings.ToLower(filename)
+  name  = strings.TrimSuffix(name, ".gz")
+  name  = strings.TrimSuffix(name, ".bz2")
+  for _, ext := range []string{".arrow", ".arrows", ".feather", ".ipc"} {
+    if strings.HasSuffix(name, ext) {
+      return true
+    }
+  }
+  return false
+}
+
+// Import an Arrow file, which is memory-mapped if possible
+func import_arrow(config Config, filename string, f func([]byte) error) {
+  PrintStderr(config, 1, "Reading predictions from `%s'... ", filename)
+  data, close, err := load_file(filename); if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  if err := f(data); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatalf("reading `%s' failed: %v", filename, err)
+  }
+  if err := close(); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  PrintStderr(config, 1, "done\n")
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Export a table as an Arrow IPC stream. If keys is not nil, the table is
+// preceded by a string column with the given name, e.g. the names of
+// metrics. Undefined values are exported as nulls if degenerate inputs are
+// allowed.
+func export_arrow(config Config, writer io.Writer, name string, keys []string, table Table) {
+  fields := []arrow.Field{}
+  if keys != nil {
+    fields = append(fields, arrow.Field{Name: name, Type: arrow.BinaryTypes.String})
+  }
+  for _, name := range table.Names {
+    fields = append(fields, arrow.Field{Name: name, Type: arrow.PrimitiveTypes.Float64, Nullable: config.AllowDegenerate})
+  }
+  schema  := arrow.NewSchema(fields, nil)
+  builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+  defer builder.Release()
+  offset  := 0
+  if keys != nil {
+    builder.Field(0).(*array.StringBuilder).AppendValues(keys, nil)
+    offset = 1
+  }
+  for j, column := range table.Columns {
+    b := builder.Field(offset+j).(*array.Float64Builder)
+    for _, v := range column {
+      if config.AllowDegenerate && math.IsNaN(v) {
+        b.AppendNull()
+      } else {
+        b.Append(v)
+      }
+    }
+  }
+  record := builder.NewRecordBatch()
+  defer record.Release()
+  w := ipc.NewWriter(writer, ipc.WithSchema(schema))
+  if err := w.Write(record); err != nil {
+    log.Fatal(err)
+  }
+  if err := w.Close(); err != nil {
+    log.Fatal(err)
+  }
+}
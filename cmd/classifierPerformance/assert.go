@@ -0,0 +1,141 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "strconv"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Exit status if assertions fail, which differs from the status of errors
+// (1) and runtime panics (2), so that pipelines can tell failed quality
+// gates from failed evaluations
+const assertion_exit_status = 3
+
+/* -------------------------------------------------------------------------- */
+
+// Quality gate on a scalar metric or an operating point, e.g. roc-auc>=0.85
+// or precision@recall(0.9)>=0.5
+type assertion struct {
+  Expr   string
+  Metric string
+  Op     string
+  Value  float64
+}
+
+func parse_assertion(s string) (assertion, error) {
+  i := strings.IndexAny(s, "<>=!")
+  if i <= 0 {
+    return assertion{}, fmt.Errorf("invalid assertion `%s'", s)
+  }
+  r  := assertion{Expr: s, Metric: strings.ToLower(strings.TrimSpace(s[:i]))}
+  op := s[i:]
+  for _, o := range []string{">=", "<=", "==", "!=", ">", "<"} {
+    if strings.HasPrefix(op, o) {
+      r.Op = o; break
+    }
+  }
+  if r.Op == "" {
+    return assertion{}, fmt.Errorf("invalid assertion `%s': unknown operator", s)
+  }
+  v, err := strconv.ParseFloat(strings.TrimSpace(op[len(r.Op):]), 64); if err != nil {
+    return assertion{}, fmt.Errorf("invalid assertion `%s': %v", s, err)
+  }
+  r.Value = v
+  if metric, ok := LookupMetric(r.Metric); ok {
+    if metric.Kind() != ScalarMetric {
+      return assertion{}, fmt.Errorf("invalid assertion `%s': `%s' is not a scalar metric", s, r.Metric)
+    }
+  } else
+  if _, _, _, err := ParseOperatingPoint(r.Metric); err != nil {
+    return assertion{}, fmt.Errorf("invalid assertion `%s': %v", s, err)
+  }
+  return r, nil
+}
+
+func parse_assertions(config Config) []assertion {
+  r := []assertion{}
+  for _, s := range config.Assert {
+    if a, err := parse_assertion(s); err != nil {
+      log.Fatal(err)
+    } else {
+      r = append(r, a)
+    }
+  }
+  return r
+}
+
+// Value of the asserted metric, where non-finite values never satisfy an
+// assertion
+func (obj assertion) Eval(data *Metrics) (float64, error) {
+  if _, ok := LookupMetric(obj.Metric); ok {
+    r, err := data.Eval(obj.Metric); if err != nil {
+      return math.NaN(), err
+    }
+    return r[0], nil
+  }
+  measure, constraint, x, err := ParseOperatingPoint(obj.Metric); if err != nil {
+    return math.NaN(), err
+  }
+  return data.Perf.OperatingPoint(measure, constraint, x)
+}
+
+func (obj assertion) Check(v float64) bool {
+  if math.IsNaN(v) {
+    return false
+  }
+  switch obj.Op {
+  case ">=": return v >= obj.Value
+  case "<=": return v <= obj.Value
+  case ">" : return v >  obj.Value
+  case "<" : return v <  obj.Value
+  case "==": return v == obj.Value
+  default  : return v != obj.Value
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Evaluate all assertions given by --assert and exit with status
+// assertion_exit_status listing the failed assertions
+func check_assertions(config Config, data *Metrics) {
+  assertions := parse_assertions(config)
+  failed     := 0
+  for _, a := range assertions {
+    v, err := a.Eval(data); if err != nil {
+      log.Fatal(err)
+    }
+    if a.Check(v) {
+      PrintStderr(config, 1, "Assertion `%s' passed (%s = %f)\n", a.Expr, a.Metric, v)
+    } else {
+      fmt.Fprintf(os.Stderr, "assertion failed: %s (%s = %f)\n", a.Expr, a.Metric, v)
+      failed++
+    }
+  }
+  if failed > 0 {
+    fmt.Fprintf(os.Stderr, "%d of %d assertions failed\n", failed, len(assertions))
+    os.Exit(assertion_exit_status)
+  }
+}
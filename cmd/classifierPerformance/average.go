@@ -0,0 +1,87 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+func classifier_performance_average(config Config, filename, target string) {
+  // average over all folds of all repeats
+  perfs := []Performance{}
+  for _, repeat := range import_folds(config, filename) {
+    for _, fold := range repeat.Folds {
+      perfs = append(perfs, fold.Perf)
+    }
+  }
+  // pooled thresholds of all folds
+  pooled := Performance{}
+  for _, perf := range perfs {
+    pooled = Merge(pooled, perf)
+  }
+  // two-sided normal quantile of the confidence band
+  z := math.Sqrt2*math.Erfinv(config.Confidence)
+  switch target {
+  case "roc-average":
+    switch config.Averaging {
+    case "", "vertical":
+      curves := make([]Curve, len(perfs))
+      for k, perf := range perfs {
+        curves[k] = Roc(perf)
+      }
+      r := VerticalAverage(curves, UnitGrid(config.AveragingPoints))
+      lower, upper := r.Band(z)
+      export_table(config, os.Stdout, nil, Table{
+        Names  : []string{"FPR", "TPR", "TPR_sd", "TPR_lower", "TPR_upper"},
+        Columns: [][]float64{r.X, r.Y, r.YSd, lower, upper} })
+    case "threshold":
+      r := ThresholdAverage(perfs, pooled.Tr, Roc)
+      export_table(config, os.Stdout, nil, Table{
+        Names  : []string{"FPR", "FPR_sd", "TPR", "TPR_sd", "threshold"},
+        Columns: [][]float64{r.X, r.XSd, r.Y, r.YSd, r.Tr} })
+    default:
+      log.Fatalf("invalid averaging method: %s", config.Averaging)
+    }
+  case "precision-recall-average":
+    switch config.Averaging {
+    case "threshold":
+      r := ThresholdAverage(perfs, pooled.Tr, func(perf Performance) Curve {
+        return PrecisionRecall(perf, config.NormalizePrecision)
+      })
+      export_table(config, os.Stdout, nil, Table{
+        Names  : []string{"recall", "recall_sd", "precision", "precision_sd", "threshold"},
+        Columns: [][]float64{r.X, r.XSd, r.Y, r.YSd, r.Tr} })
+    case "", "vertical":
+      r := VerticalAveragePrecisionRecall(perfs, UnitGrid(config.AveragingPoints), config.NormalizePrecision)
+      lower, upper := r.Band(z)
+      export_table(config, os.Stdout, nil, Table{
+        Names  : []string{"recall", "precision", "precision_sd", "precision_lower", "precision_upper"},
+        Columns: [][]float64{r.X, r.Y, r.YSd, lower, upper} })
+    default:
+      log.Fatalf("invalid averaging method: %s", config.Averaging)
+    }
+  default:
+    panic(fmt.Sprintf("internal error: invalid target `%s'", target))
+  }
+}
@@ -0,0 +1,166 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "math/rand"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+func new_rng(config Config) *rand.Rand {
+  return rand.New(rand.NewSource(config.Seed))
+}
+
+// Bootstrap confidence intervals of scalar metrics. If clusters are given,
+// clusters instead of single predictions are resampled.
+func bootstrap_metrics(config Config, values []float64, labels []int, clusters []int, names []string) ([]float64, []float64) {
+  f := func(values []float64, labels []int) ([]float64, error) {
+    perf, err := eval_performance(config, values, labels); if err != nil {
+      return nil, err
+    }
+    if perf.P == 0 || perf.N == 0 {
+      // replicate contains only a single class
+      r := make([]float64, len(names))
+      for i := range r {
+        r[i] = math.NaN()
+      }
+      return r, nil
+    }
+    return new_metrics(config, perf, values, labels).Eval(names...)
+  }
+  var r [][]float64
+  var err  error
+  if clusters != nil {
+    r, err = BootstrapClusters(values, labels, clusters, config.Bootstrap, new_rng(config), f)
+  } else {
+    r, err = Bootstrap(values, labels, config.Bootstrap, new_rng(config), f)
+  }
+  if err != nil {
+    log.Fatal(err)
+  }
+  return bootstrap_intervals(config, r, len(names))
+}
+
+// Evaluate a metric on bootstrap replicates of the predictions. If clusters
+// are given, clusters instead of single predictions are resampled.
+// Replicates that contain only a single class are skipped.
+func bootstrap_tables(config Config, values []float64, labels []int, clusters []int, metric Metric) ([]int, []Table) {
+  ids    := []int{}
+  tables := []Table{}
+  i      := 0
+  f := func(values []float64, labels []int) ([]float64, error) {
+    i++
+    perf, err := eval_performance(config, values, labels); if err != nil {
+      return nil, err
+    }
+    if perf.P == 0 || perf.N == 0 {
+      return nil, nil
+    }
+    table, err := metric.Eval(new_metrics(config, perf, values, labels)); if err != nil {
+      return nil, fmt.Errorf("replicate %d: %v", i, err)
+    }
+    ids    = append(ids, i)
+    tables = append(tables, table)
+    return nil, nil
+  }
+  var err error
+  if clusters != nil {
+    _, err = BootstrapClusters(values, labels, clusters, config.Bootstrap, new_rng(config), f)
+  } else {
+    _, err = Bootstrap(values, labels, config.Bootstrap, new_rng(config), f)
+  }
+  if err != nil {
+    log.Fatal(err)
+  }
+  return ids, tables
+}
+
+// Print tables of bootstrap replicates in long format, i.e. rows of all
+// replicates are concatenated and the first column is the replicate ID
+func export_replicates(config Config, writer io.Writer, ids []int, tables []Table) {
+  for k, table := range tables {
+    if !config.PrintThresholds {
+      table = drop_thresholds(table)
+    }
+    if k == 0 && config.PrintHeader {
+      fmt.Fprintln(writer, "replicate " + strings.Join(table.Names, " "))
+    }
+    for i := 0; i < table.Rows(); i++ {
+      fmt.Fprintf(writer, "%d", ids[k])
+      for j := range table.Columns {
+        fmt.Fprint(writer, " ", format_value(config, table.Columns[j][i]))
+      }
+      fmt.Fprintln(writer)
+    }
+  }
+}
+
+// Import predictions together with the cluster of each prediction given by
+// --cluster-column
+func import_clusters(config Config, filename string) ([]float64, []int, []int) {
+  table    := import_table(config, filename, config.ClusterColumn)
+  index    := map[string]int{}
+  clusters := make([]int, len(table.Values))
+  for i, name := range table.Columns[config.ClusterColumn] {
+    k, ok := index[name]
+    if !ok {
+      k = len(index)
+      index[name] = k
+    }
+    clusters[i] = k
+  }
+  PrintStderr(config, 1, "Found %d clusters\n", len(index))
+  return table.Values, table.Labels, clusters
+}
+
+// Bootstrap confidence intervals of statistics computed on groups of
+// predictions, which are resampled independently
+func bootstrap_groups(config Config, groups []Fold, n int, f func(values [][]float64, labels [][]int) ([]float64, error)) ([]float64, []float64) {
+  values := make([][]float64, len(groups))
+  labels := make([][]int,     len(groups))
+  for k, group := range groups {
+    values[k] = group.Values
+    labels[k] = group.Labels
+  }
+  r, err := BootstrapGroups(values, labels, config.Bootstrap, new_rng(config), f)
+  if err != nil {
+    log.Fatal(err)
+  }
+  return bootstrap_intervals(config, r, n)
+}
+
+// Percentile intervals of the n columns of bootstrap replicates r
+func bootstrap_intervals(config Config, r [][]float64, n int) ([]float64, []float64) {
+  lower := make([]float64, n)
+  upper := make([]float64, n)
+  for i := 0; i < n; i++ {
+    x := make([]float64, len(r))
+    for j := range r {
+      x[j] = r[j][i]
+    }
+    lower[i], upper[i] = PercentileInterval(x, config.Confidence)
+  }
+  return lower, upper
+}
@@ -0,0 +1,342 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "encoding/json"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Number of bootstrap replicates for testing differences of metrics, unless
+// given with --bootstrap
+const challenger_bootstrap = 1000
+
+// Version of the challenger report schema, which must be increased whenever
+// the schema changes
+const challenger_report_version = 1
+
+type ChallengerMetric struct {
+  Name       string    `json:"name"`
+  Champion   JSONFloat `json:"champion"`
+  Challenger JSONFloat `json:"challenger"`
+  Delta      JSONFloat `json:"delta"`
+  Lower      JSONFloat `json:"lower"`
+  Upper      JSONFloat `json:"upper"`
+  PValue     JSONFloat `json:"p_value"`
+  Test       string    `json:"test"`
+}
+
+type ChallengerSet struct {
+  N         int       `json:"n"`
+  Positives int       `json:"positives"`
+  Rate      JSONFloat `json:"positive_rate"`
+}
+
+type ChallengerSwap struct {
+  ChampionThreshold   JSONFloat     `json:"champion_threshold"`
+  ChallengerThreshold JSONFloat     `json:"challenger_threshold"`
+  Both                ChallengerSet `json:"both"`
+  SwapIn              ChallengerSet `json:"swap_in"`
+  SwapOut             ChallengerSet `json:"swap_out"`
+  Neither             ChallengerSet `json:"neither"`
+}
+
+type ChallengerDecile struct {
+  Decile    int `json:"decile"`
+  N         int `json:"n"`
+  Positives int `json:"positives"`
+  Same      int `json:"same"`
+  Up        int `json:"up"`
+  Down      int `json:"down"`
+}
+
+type ChallengerReport struct {
+  Version    int                `json:"version"`
+  Champion   string             `json:"champion"`
+  Challenger string             `json:"challenger"`
+  N          int                `json:"n"`
+  Positives  int                `json:"positives"`
+  Metrics    []ChallengerMetric `json:"metrics"`
+  Swap       ChallengerSwap     `json:"swap"`
+  Deciles    []ChallengerDecile `json:"deciles"`
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Roc-auc and ks of predictions, which are not modified
+func challenger_metrics(values []float64, labels []int) ([]float64, error) {
+  v := append([]float64{}, values...)
+  l := append([]int    {}, labels...)
+  perf, err := EvalPerformance(v, l); if err != nil {
+    return nil, err
+  }
+  if perf.P == 0 || perf.N == 0 {
+    return []float64{math.NaN(), math.NaN()}, nil
+  }
+  auc, err := Roc(perf).AUC(); if err != nil {
+    return nil, err
+  }
+  return []float64{auc, KS(perf)}, nil
+}
+
+// Threshold of a model, which is either given by the user or the optimal
+// ROC threshold
+func challenger_threshold(config Config, values []float64, labels []int) float64 {
+  if !math.IsNaN(config.Threshold) {
+    return config.Threshold
+  }
+  perf, err := EvalPerformance(append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    log.Fatal(err)
+  }
+  roc := Roc(perf)
+  return roc.Tr[OptimumRoc(roc.Tr, roc.X, roc.Y)]
+}
+
+func new_challenger_set(n, positives int) ChallengerSet {
+  return ChallengerSet{N: n, Positives: positives, Rate: JSONFloat(float64(positives)/float64(n))}
+}
+
+/* -------------------------------------------------------------------------- */
+
+func eval_challenger_report(config Config, champion, challenger []float64, labels []int) ChallengerReport {
+  r := ChallengerReport{Version: challenger_report_version, Champion: config.ReferenceFile, N: len(labels)}
+  for _, l := range labels {
+    r.Positives += l
+  }
+  // metrics and their differences
+  r1, err := challenger_metrics(champion, labels); if err != nil {
+    log.Fatal(err)
+  }
+  r2, err := challenger_metrics(challenger, labels); if err != nil {
+    log.Fatal(err)
+  }
+  n := config.Bootstrap
+  if n == 0 {
+    n = challenger_bootstrap
+  }
+  v1 := make([]float64, len(labels))
+  v2 := make([]float64, len(labels))
+  l  := make([]int,     len(labels))
+  b, err := BootstrapIndices(len(labels), n, new_rng(config), func(index []int) ([]float64, error) {
+    for i, j := range index {
+      v1[i], v2[i], l[i] = champion[j], challenger[j], labels[j]
+    }
+    x1, err := challenger_metrics(v1, l); if err != nil {
+      return nil, err
+    }
+    x2, err := challenger_metrics(v2, l); if err != nil {
+      return nil, err
+    }
+    return []float64{x2[0] - x1[0], x2[1] - x1[1]}, nil
+  })
+  if err != nil {
+    log.Fatal(err)
+  }
+  lower, upper := bootstrap_intervals(config, b, 2)
+  for i, name := range []string{"roc-auc", "ks"} {
+    m := ChallengerMetric{Name: name, Champion: JSONFloat(r1[i]), Challenger: JSONFloat(r2[i]), Delta: JSONFloat(r2[i] - r1[i]),
+      Lower: JSONFloat(lower[i]), Upper: JSONFloat(upper[i])}
+    if name == "roc-auc" {
+      d, variance, err := DeLongPaired(champion, challenger, labels); if err != nil {
+        log.Fatal(err)
+      }
+      m.PValue = JSONFloat(math.Erfc(math.Abs(d)/math.Sqrt(2.0*variance)))
+      m.Test   = "delong"
+    } else {
+      // two-sided bootstrap p-value
+      n_le, n_ge, n := 0, 0, 0
+      for j := range b {
+        if math.IsNaN(b[j][i]) {
+          continue
+        }
+        if b[j][i] <= 0.0 {
+          n_le++
+        }
+        if b[j][i] >= 0.0 {
+          n_ge++
+        }
+        n++
+      }
+      m.PValue = JSONFloat(math.Min(1.0, 2.0*float64(min(n_le, n_ge))/float64(n)))
+      m.Test   = "bootstrap"
+    }
+    r.Metrics = append(r.Metrics, m)
+  }
+  // swap sets
+  t1 := challenger_threshold(config, champion  , labels)
+  t2 := challenger_threshold(config, challenger, labels)
+  r.Swap.ChampionThreshold   = JSONFloat(t1)
+  r.Swap.ChallengerThreshold = JSONFloat(t2)
+  counts := [2][2][2]int{}
+  for i := range labels {
+    a, b := 0, 0
+    if champion[i] > t1 {
+      a = 1
+    }
+    if challenger[i] > t2 {
+      b = 1
+    }
+    counts[a][b][0]++
+    counts[a][b][1] += labels[i]
+  }
+  r.Swap.Both    = new_challenger_set(counts[1][1][0], counts[1][1][1])
+  r.Swap.SwapIn  = new_challenger_set(counts[0][1][0], counts[0][1][1])
+  r.Swap.SwapOut = new_challenger_set(counts[1][0][0], counts[1][0][1])
+  r.Swap.Neither = new_challenger_set(counts[0][0][0], counts[0][0][1])
+  // shifts between deciles
+  d1, err := QuantileIndex(champion  , config.Bins); if err != nil {
+    log.Fatal(err)
+  }
+  d2, err := QuantileIndex(challenger, config.Bins); if err != nil {
+    log.Fatal(err)
+  }
+  for i := range labels {
+    for len(r.Deciles) <= d1[i] {
+      r.Deciles = append(r.Deciles, ChallengerDecile{Decile: len(r.Deciles)+1})
+    }
+    d := &r.Deciles[d1[i]]
+    d.N++
+    d.Positives += labels[i]
+    switch {
+    case d2[i] == d1[i]: d.Same++
+    case d2[i] <  d1[i]: d.Up++
+    default:             d.Down++
+    }
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+func export_challenger_report_text(writer io.Writer, report ChallengerReport) {
+  fmt.Fprintln(writer, "metric champion challenger delta lower upper p_value test")
+  for _, m := range report.Metrics {
+    fmt.Fprintf(writer, "%s %f %f %f %f %f %g %s\n", m.Name, float64(m.Champion), float64(m.Challenger), float64(m.Delta),
+      float64(m.Lower), float64(m.Upper), float64(m.PValue), m.Test)
+  }
+  fmt.Fprintln(writer)
+  fmt.Fprintf(writer, "set n positives positive_rate (thresholds: champion=%f challenger=%f)\n", float64(report.Swap.ChampionThreshold), float64(report.Swap.ChallengerThreshold))
+  for _, x := range []struct{ name string; set ChallengerSet }{
+    {"both", report.Swap.Both}, {"swap-in", report.Swap.SwapIn}, {"swap-out", report.Swap.SwapOut}, {"neither", report.Swap.Neither}} {
+    fmt.Fprintf(writer, "%s %d %d %f\n", x.name, x.set.N, x.set.Positives, float64(x.set.Rate))
+  }
+  fmt.Fprintln(writer)
+  fmt.Fprintln(writer, "decile n positives same up down")
+  for _, d := range report.Deciles {
+    fmt.Fprintf(writer, "%d %d %d %d %d %d\n", d.Decile, d.N, d.Positives, d.Same, d.Up, d.Down)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Join predictions of two tables by observation IDs, where only IDs present
+// in both tables are kept in the order of the second table
+func join_predictions(table1, table2 PredictionTable, column string) ([]float64, []float64, []int) {
+  index := make(map[string]int, len(table1.Values))
+  for i, id := range table1.Columns[column] {
+    if _, ok := index[id]; ok {
+      log.Fatalf("duplicate ID `%s' in column `%s'", id, column)
+    }
+    index[id] = i
+  }
+  values1 := []float64{}
+  values2 := []float64{}
+  labels  := []int{}
+  seen    := make(map[string]struct{}, len(table2.Values))
+  for j, id := range table2.Columns[column] {
+    if _, ok := seen[id]; ok {
+      log.Fatalf("duplicate ID `%s' in column `%s'", id, column)
+    }
+    seen[id] = struct{}{}
+    i, ok := index[id]
+    if !ok {
+      continue
+    }
+    if table1.Labels[i] != table2.Labels[j] {
+      log.Fatalf("labels of observation `%s' differ", id)
+    }
+    values1 = append(values1, table1.Values[i])
+    values2 = append(values2, table2.Values[j])
+    labels  = append(labels , table2.Labels[j])
+  }
+  if n := len(table1.Values) + len(table2.Values) - 2*len(labels); n > 0 {
+    fmt.Fprintf(os.Stderr, "notice: dropped %d predictions with IDs not present in both files\n", n)
+  }
+  if len(labels) == 0 {
+    log.Fatal("no common IDs found")
+  }
+  return values1, values2, labels
+}
+
+// Import predictions of the champion (reference file) and the challenger
+// model on the same population. Without --id-column, both files must list
+// the same observations in the same order.
+func import_challenger(config Config, filename string) ([]float64, []float64, []int) {
+  if config.ReferenceFile == "" {
+    log.Fatal("no reference file with champion predictions specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("comparing models is not supported in streaming mode")
+  }
+  if config.IdColumn != "" {
+    table1 := import_table(config, config.ReferenceFile, config.IdColumn)
+    table2 := import_table(config, filename, config.IdColumn)
+    return join_predictions(table1, table2, config.IdColumn)
+  }
+  champion  , labels1 := import_predictions_cached(config, config.ReferenceFile)
+  challenger, labels2 := import_predictions_cached(config, filename)
+  if len(labels1) != len(labels2) {
+    log.Fatalf("champion and challenger files have different numbers of predictions")
+  }
+  for i := range labels1 {
+    if labels1[i] != labels2[i] {
+      log.Fatalf("champion and challenger labels differ at prediction %d", i+1)
+    }
+  }
+  return champion, challenger, labels1
+}
+
+// Compare predictions of a challenger model with predictions of the
+// champion model (given as reference file) on the same population
+func classifier_performance_challenger(config Config, filename string) {
+  champion, challenger, labels := import_challenger(config, filename)
+  report := eval_challenger_report(config, champion, challenger, labels)
+  report.Challenger = filename
+  writer := bufio.NewWriter(os.Stdout)
+  defer writer.Flush()
+  switch config.ReportFormat {
+  case "", "json":
+    encoder := json.NewEncoder(writer)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(report); err != nil {
+      log.Fatal(err)
+    }
+  case "text":
+    export_challenger_report_text(writer, report)
+  default:
+    log.Fatalf("invalid report format: %s", config.ReportFormat)
+  }
+}
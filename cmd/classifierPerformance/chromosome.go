@@ -0,0 +1,137 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "sort"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Import predictions grouped by chromosomes, which are given either by
+// --chromosome-column or by IDs of the form chr:start-end in --id-column.
+// Chromosomes are returned in natural order together with the genome-wide
+// predictions.
+func import_chromosomes(config Config, filename string) (Fold, []Fold) {
+  if config.StreamBins > 0 {
+    log.Fatal("chromosomes are not supported in streaming mode")
+  }
+  var chroms []string
+  var table    PredictionTable
+  switch {
+  case config.ChromosomeColumn != "":
+    table  = import_table(config, filename, config.ChromosomeColumn)
+    chroms = table.Columns[config.ChromosomeColumn]
+  case config.IdColumn != "":
+    table  = import_table(config, filename, config.IdColumn)
+    chroms = make([]string, len(table.Values))
+    for i, id := range table.Columns[config.IdColumn] {
+      r, err := ParseGenomicRegion(id); if err != nil {
+        log.Fatalf("column `%s': %v", config.IdColumn, err)
+      }
+      chroms[i] = r.Chrom
+    }
+  default:
+    log.Fatal("no chromosome or id column specified")
+  }
+  names, values, labels := GroupPredictions(table.Values, table.Labels, chroms)
+  chromosomes := make([]Fold, len(names))
+  for k := range names {
+    chromosomes[k] = new_fold(config, names[k], values[k], labels[k])
+  }
+  sort.Slice(chromosomes, func(i, j int) bool { return ChromosomeLess(chromosomes[i].Name, chromosomes[j].Name) })
+  PrintStderr(config, 1, "Found %d chromosomes\n", len(chromosomes))
+  return new_fold(config, "genome-wide", table.Values, table.Labels), chromosomes
+}
+
+// Held-out evaluation of genomic predictions on each chromosome. The
+// ROC-AUC of each chromosome is compared with the median ROC-AUC over
+// chromosomes, which is robust to single deviating chromosomes, using the
+// standard error of DeLong. Chromosomes are flagged as deviating if the
+// Bonferroni corrected confidence interval of the difference excludes zero.
+// Genome-wide results and the mean and median over chromosomes are reported
+// as aggregates.
+func classifier_performance_per_chromosome(config Config, filename string) {
+  pooled, chromosomes := import_chromosomes(config, filename)
+  m   := len(chromosomes)
+  r   := make([][2]float64, m)
+  se  := make([]float64, m)
+  auc := []float64{}
+  for k, chrom := range chromosomes {
+    // metrics of chromosomes with a single class are undefined
+    x, err := new_metrics(config, chrom.Perf, chrom.Values, chrom.Labels).Eval("roc-auc", "precision-recall-auc"); if err != nil {
+      fmt.Fprintf(os.Stderr, "notice: metrics of chromosome `%s' are undefined: %v\n", chrom.Name, err)
+      x = []float64{math.NaN(), math.NaN()}
+    }
+    _, variance, err := DeLong(chrom.Values, chrom.Labels); if err != nil {
+      variance = math.NaN()
+    }
+    r [k] = [2]float64{x[0], x[1]}
+    se[k] = math.Sqrt(variance)
+    if !math.IsNaN(x[0]) {
+      auc = append(auc, x[0])
+    }
+  }
+  // mean and median over chromosomes with defined metrics
+  mean := [2]float64{}
+  for j := range mean {
+    n := 0
+    for k := range r {
+      if !math.IsNaN(r[k][j]) {
+        mean[j] += r[k][j]; n++
+      }
+    }
+    mean[j] /= float64(n)
+  }
+  median := math.NaN()
+  if n := len(auc); n > 0 {
+    sort.Float64s(auc)
+    median = (auc[(n-1)/2] + auc[n/2])/2.0
+  }
+  z := math.Sqrt2*math.Erfinv(1.0 - (1.0 - config.Confidence)/float64(m))
+  if config.PrintHeader {
+    fmt.Printf("chromosome n positives negatives roc-auc precision-recall-auc gap z deviates\n")
+  }
+  for k, chrom := range chromosomes {
+    gap := r[k][0] - median
+    s   := gap/se[k]
+    deviates := "no"
+    if math.IsNaN(s) {
+      deviates = "NA"
+    } else
+    if math.Abs(s) > z {
+      deviates = "yes"
+    }
+    fmt.Printf("%s %d %d %d", chrom.Name, len(chrom.Values), chrom.Perf.P, chrom.Perf.N)
+    for _, v := range []float64{r[k][0], r[k][1], gap, s} {
+      fmt.Printf(" %s", format_group_value(v))
+    }
+    fmt.Printf(" %s\n", deviates)
+  }
+  x, err := new_metrics(config, pooled.Perf, pooled.Values, pooled.Labels).Eval("roc-auc", "precision-recall-auc"); if err != nil {
+    log.Fatal(err)
+  }
+  fmt.Printf("%s %d %d %d %f %f - - -\n", pooled.Name, len(pooled.Values), pooled.Perf.P, pooled.Perf.N, x[0], x[1])
+  fmt.Printf("mean - - - %s %s - - -\n", format_group_value(mean[0]), format_group_value(mean[1]))
+  fmt.Printf("median - - - %s - - - -\n", format_group_value(median))
+}
@@ -18,22 +18,113 @@ package main
 
 /* -------------------------------------------------------------------------- */
 
+import   "bufio"
+import   "context"
+import   "encoding/gob"
+import   "encoding/json"
+import   "errors"
 import   "fmt"
 import   "io"
 import   "log"
+import   "math"
 import   "os"
+import   "path/filepath"
+import   "slices"
+import   "sort"
+import   "strconv"
 import   "strings"
 
 import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+import   "github.com/pbenner/classifierPerformance/pkg/evaluationService"
 import   "github.com/pborman/getopt"
 
 /* -------------------------------------------------------------------------- */
 
 type Config struct {
+  AllowDegenerate    bool
+  Assert             []string
+  Auc                float64
+  Averaging          string
+  AveragingPoints    int
+  Bandwidth          float64
+  Bins               int
+  Bootstrap          int
+  Cache              bool
+  ChromosomeColumn   string
+  ClusterColumn      string
+  Compat             string
+  Confidence         float64
+  CostMatrix         *CostMatrix
+  CvAggregation      string
+  Density            string
+  Duplicates         string
+  EventColumn        string
+  EventTimeColumn    string
+  FairnessCriterion  string
+  FairnessTolerance  float64
+  FeatureColumn      string
+  FoldColumn         string
+  Format             string
+  Fraction           float64
+  GroupColumn        string
+  Grpc               string
+  Hdf5               string
+  Horizons           []float64
+  IdColumn           string
+  ImageColumn        string
+  K                  []int
+  KeepReplicates     bool
+  LabelFlip          []float64
+  Labels             string
+  LabelsDataset      string
+  LesionColumn       string
+  Logo               bool
+  Method             string
+  Metrics            []string
+  MinGroupSize       int
+  MlflowUri          string
+  Model              string
+  NoiseSd            []float64
   NormalizePrecision bool
+  OutputDir          string
+  PerQuery           bool
+  Prevalence         []float64
+  PrevalenceGrid     []float64
   PrintHeader        bool
   PrintThresholds    bool
+  Protocol           string
+  QueryColumn        string
+  ReferenceFile      string
+  ReferenceGroup     string
+  RegionScore        string
+  Regions            string
+  Rejection          string
+  RelevanceColumn    string
+  RelevanceThreshold float64
+  RepeatColumn       string
+  Repetitions        int
+  ReportEvery        string
+  ReportFormat       string
+  RunId              string
+  Scores             string
+  ScoresDataset      string
+  Seed               int64
+  SizeColumn         string
+  SmallGroups        string
+  Step               int64
+  StreamBins         int
+  StreamRange        [2]float64
+  Tensorboard        string
+  Threshold          float64
+  ThresholdGrid      string
+  TimeColumn         string
+  Top                int
   Verbose            int
+  VerifiedColumn     string
+  Wandb              string
+  WeightColumn       string
+  Window             string
+  WindowSize         int
 }
 
 /* -------------------------------------------------------------------------- */
@@ -46,108 +137,834 @@ func PrintStderr(config Config, level int, format string, args ...interface{}) {
 
 /* -------------------------------------------------------------------------- */
 
-func export_table2(config Config, writer io.Writer, x, y []float64, name_x, name_y string) {
-  if config.PrintHeader {
-    fmt.Fprintf(writer, "%s %s\n", name_x, name_y)
+// Remove the threshold column from a table
+func drop_thresholds(table Table) Table {
+  r := Table{}
+  for j, name := range table.Names {
+    if name != "threshold" {
+      r.Names   = append(r.Names  , name)
+      r.Columns = append(r.Columns, table.Columns[j])
+    }
+  }
+  return r
+}
+
+// Format a value of a table, where undefined values are reported as NA if
+// degenerate inputs are allowed. Values are printed with full precision in
+// compatibility mode, so that results can be compared exactly.
+func format_value(config Config, v float64) string {
+  if config.AllowDegenerate && math.IsNaN(v) {
+    return "NA"
   }
-  for i := 0; i < len(x); i++ {
-    fmt.Fprintf(writer, "%f %f\n", x[i], y[i])
+  if config.Compat != "" {
+    return strconv.FormatFloat(v, 'g', -1, 64)
   }
+  return fmt.Sprintf("%f", v)
 }
 
-func export_table3(config Config, writer io.Writer, x, y, z []float64, name_x, name_y, name_z string) {
+// Export a table, where the number of positives and negatives of protobuf
+// results is omitted if perf is nil
+func export_table(config Config, writer io.Writer, perf *Performance, table Table) {
+  if !config.PrintThresholds {
+    table = drop_thresholds(table)
+  }
+  switch config.Format {
+  case "arrow":
+    export_arrow(config, writer, "", nil, table)
+    return
+  case "proto":
+    r := proto_counts(perf)
+    r.Tables = []*evaluationService.Table{proto_table("", table)}
+    export_proto(writer, r)
+    return
+  }
   if config.PrintHeader {
-    fmt.Fprintf(writer, "%s %s %s\n", name_x, name_y, name_z)
+    fmt.Fprintln(writer, strings.Join(table.Names, " "))
+  }
+  for i := 0; i < table.Rows(); i++ {
+    for j := range table.Columns {
+      if j > 0 {
+        fmt.Fprint(writer, " ")
+      }
+      fmt.Fprint(writer, format_value(config, table.Columns[j][i]))
+    }
+    fmt.Fprintln(writer)
+  }
+}
+
+func export_point(config Config, writer io.Writer, perf *Performance, table Table) {
+  switch config.Format {
+  case "arrow":
+    export_arrow(config, writer, "", nil, table)
+    return
+  case "proto":
+    r := proto_counts(perf)
+    r.Tables = []*evaluationService.Table{proto_table("", table)}
+    export_proto(writer, r)
+    return
   }
-  for i := 0; i < len(x); i++ {
-    fmt.Fprintf(writer, "%f %f %f\n", x[i], y[i], z[i])
+  for j, name := range table.Names {
+    if j > 0 {
+      fmt.Fprint(writer, " ")
+    }
+    if config.PrintHeader {
+      fmt.Fprintf(writer, "%s=%s", name, format_value(config, table.Columns[j][0]))
+    } else {
+      fmt.Fprint(writer, format_value(config, table.Columns[j][0]))
+    }
+  }
+  fmt.Fprintln(writer)
+}
+
+func export_scalar(config Config, writer io.Writer, perf *Performance, name string, v float64) {
+  switch {
+  case config.Format == "arrow":
+    export_arrow(config, writer, "metric", []string{name}, Table{Names: []string{"value"}, Columns: [][]float64{{v}}})
+  case config.Format == "proto":
+    r := proto_counts(perf)
+    r.Metrics = proto_metrics([]string{name}, []float64{v}, nil, nil)
+    export_proto(writer, r)
+  case config.Format == "yaml":
+    export_yaml_metrics(writer, perf, []string{name}, []float64{v}, nil, nil)
+  case config.AllowDegenerate && math.IsNaN(v):
+    fmt.Fprintln(writer, "NA")
+  default:
+    fmt.Fprintln(writer, v)
+  }
+}
+
+func export_metric(config Config, writer io.Writer, metric Metric, data *Metrics) {
+  table, err := metric.Eval(data); if err != nil {
+    if !config.AllowDegenerate || !data.Degenerate() {
+      log.Fatal(err)
+    }
+    // metrics that cannot be evaluated on degenerate inputs are undefined
+    if metric.Kind() == ScalarMetric {
+      export_scalar(config, writer, &data.Perf, metric.Name(), math.NaN())
+    } else {
+      fmt.Fprintf(os.Stderr, "notice: %s is undefined: %v\n", metric.Name(), err)
+      if config.Format == "proto" {
+        export_proto(writer, proto_counts(&data.Perf))
+      }
+    }
+    return
+  }
+  if config.Format == "proto" {
+    if metric.Kind() == CurveMetric && !config.PrintThresholds {
+      table = drop_thresholds(table)
+    }
+    export_proto(writer, proto_results(data.Perf, []string{metric.Name()}, []Table{table}))
+    return
+  }
+  if config.Format == "yaml" {
+    if metric.Kind() == ScalarMetric {
+      export_yaml_metrics(writer, &data.Perf, []string{metric.Name()}, table.Columns[0], nil, nil)
+    } else {
+      export_yaml_point(writer, &data.Perf, metric.Name(), table)
+    }
+    return
+  }
+  switch metric.Kind() {
+  case ScalarMetric:
+    export_scalar(config, writer, &data.Perf, metric.Name(), table.Columns[0][0])
+  case PointMetric:
+    export_point(config, writer, &data.Perf, table)
+  default:
+    export_table(config, writer, &data.Perf, table)
   }
 }
 
 /* -------------------------------------------------------------------------- */
 
-func import_predictions(config Config, filename string) ([]float64, []int) {
+func import_file(config Config, filename string, f func(io.Reader) error) {
   var reader io.Reader
   if filename == "" {
     reader = os.Stdin
   } else {
     PrintStderr(config, 1, "Reading predictions from `%s'... ", filename)
-    f, err := os.Open(filename)
+    file, err := open_file(filename)
     if err != nil {
       PrintStderr(config, 1, "failed\n")
       log.Fatal(err)
     }
-    defer f.Close()
-    reader = f
+    defer file.Close()
+    reader = file
   }
-  if values, labels, err := ReadPredictions(reader); err != nil {
+  if err := f(reader); err != nil {
     if filename != "" {
       PrintStderr(config, 1, "failed\n")
     }
+    var perr *ParseError
+    if errors.As(err, &perr) {
+      if filename == "" {
+        filename = "<stdin>"
+      }
+      log.Fatalf("parsing `%s' failed at %v:\n  %s", filename, perr, perr.Text)
+    }
     log.Fatal(err)
   } else {
     if filename != "" {
       PrintStderr(config, 1, "done\n")
     }
-    return values, labels
   }
-  return nil, nil
+}
+
+// Scan predictions row by row from a text table, an Arrow file or arrays of
+// predictions and labels
+func scan_predictions(config Config, filename string, f func(value float64, label int) error) {
+  scan_predictions_context(context.Background(), config, filename, f)
+}
+
+// Same as scan_predictions, but text tables are read until ctx is cancelled,
+// which ends the scan without error
+func scan_predictions_context(ctx context.Context, config Config, filename string, f func(value float64, label int) error) {
+  if has_arrays(config, filename) {
+    table := import_arrays(config)
+    for i := range table.Values {
+      if err := f(table.Values[i], table.Labels[i]); err != nil {
+        log.Fatal(err)
+      }
+    }
+  } else
+  if is_arrow_file(filename) {
+    import_arrow(config, filename, func(data []byte) error {
+      return ScanArrowPredictions(data, f)
+    })
+  } else {
+    import_file(config, filename, func(reader io.Reader) error {
+      if err := ScanPredictionsContext(ctx, reader, f); err != nil && err != ctx.Err() {
+        return err
+      }
+      return nil
+    })
+  }
+}
+
+// Import predictions together with additional columns. A column may be
+// given as a comma separated list of column names, in which case the values
+// of all listed columns are joined (e.g. to form intersectional groups).
+func import_table(config Config, filename string, columns ...string) PredictionTable {
+  return import_table_labels(config, filename, false, columns...)
+}
+
+// Same as import_table, but labels may be `NA' if missing is true, in which
+// case they are imported as MissingLabel
+func import_table_labels(config Config, filename string, missing bool, columns ...string) PredictionTable {
+  if config.Regions != "" {
+    log.Fatal("additional columns are not supported with --regions")
+  }
+  if has_arrays(config, filename) {
+    if len(columns) > 0 {
+      log.Fatal("additional columns are not supported with arrays of predictions and labels")
+    }
+    if config.Duplicates != "" {
+      log.Fatal("--duplicates is not supported with arrays of predictions and labels")
+    }
+    return import_arrays(config)
+  }
+  var table PredictionTable
+  names := []string{}
+  for _, column := range columns {
+    names = append(names, strings.Split(column, ",")...)
+  }
+  // duplicates are detected by IDs or, without an ID column, by raw rows
+  if config.IdColumn != "" && !slices.Contains(names, config.IdColumn) {
+    names = append(names, config.IdColumn)
+  }
+  rows := config.IdColumn == ""
+  if is_arrow_file(filename) {
+    import_arrow(config, filename, func(data []byte) (err error) {
+      if rows {
+        table, err = ReadArrowPredictionTableRows(data, config.RelevanceThreshold, names...)
+      } else {
+        table, err = ReadArrowPredictionTable(data, config.RelevanceThreshold, names...)
+      }
+      return
+    })
+  } else {
+    import_file(config, filename, func(reader io.Reader) (err error) {
+      switch {
+      case missing && !math.IsNaN(config.RelevanceThreshold):
+        err = fmt.Errorf("missing labels are not supported with graded relevance values")
+      case missing:
+        table, err = ReadPartialPredictionTable(reader, rows, names...)
+      case rows:
+        table, err = ReadPredictionTableRows(reader, config.RelevanceThreshold, names...)
+      case math.IsNaN(config.RelevanceThreshold):
+        table, err = ReadPredictionTable(reader, names...)
+      default:
+        table, err = ReadGradedPredictionTable(reader, config.RelevanceThreshold, names...)
+      }
+      return
+    })
+  }
+  if len(table.Values) == 0 {
+    log.Fatalf("table `%s' is empty", filename)
+  }
+  table = deduplicate_table(config, filename, table)
+  for _, column := range columns {
+    if parts := strings.Split(column, ","); len(parts) > 1 {
+      r := make([]string, len(table.Values))
+      for i := range r {
+        fields := make([]string, len(parts))
+        for j, part := range parts {
+          fields[j] = table.Columns[part][i]
+        }
+        r[i] = strings.Join(fields, ",")
+      }
+      table.Columns[column] = r
+    }
+  }
+  return table
+}
+
+// Policy for duplicate predictions given by --duplicates. Without a policy,
+// duplicates are still counted and reported, but kept.
+func duplicates_policy(config Config) string {
+  if config.Duplicates == "" {
+    return "keep"
+  }
+  return config.Duplicates
+}
+
+// Detect predictions with duplicate IDs given by --id-column or duplicate
+// rows and handle them according to --duplicates
+func deduplicate_table(config Config, filename string, table PredictionTable) PredictionTable {
+  keys := table.Rows
+  what := "rows"
+  if config.IdColumn != "" {
+    keys = table.Columns[config.IdColumn]
+    what = "IDs"
+  }
+  r, n, err := DeduplicatePredictions(table, keys, duplicates_policy(config)); if err != nil {
+    log.Fatalf("table `%s': %v", filename, err)
+  }
+  if n > 0 {
+    switch duplicates_policy(config) {
+    case "keep":
+      fmt.Fprintf(os.Stderr, "notice: table `%s' contains %d predictions with duplicate %s, which are kept\n", filename, n, what)
+    case "first":
+      fmt.Fprintf(os.Stderr, "notice: table `%s' contains %d predictions with duplicate %s, which are dropped\n", filename, n, what)
+    case "mean":
+      fmt.Fprintf(os.Stderr, "notice: table `%s' contains %d predictions with duplicate %s, which are averaged\n", filename, n, what)
+    }
+  }
+  r.Rows = nil
+  return r
+}
+
+func import_predictions(config Config, filename string) ([]float64, []int) {
+  if config.Regions != "" {
+    if config.Duplicates != "" {
+      log.Fatal("--duplicates is not supported with --regions")
+    }
+    return import_region_predictions(config, filename)
+  }
+  // tables are always checked for duplicates
+  table := import_table(config, filename)
+  return table.Values, table.Labels
 }
 
 /* -------------------------------------------------------------------------- */
 
-func classifier_performance(config Config, filename, target string) {
+type PredictionsCache struct {
+  Size    int64
+  ModTime int64
+  Values  []float64
+  Labels  []int
+}
+
+func cache_filename(filename string) string {
+  return filename + ".cache"
+}
+
+func import_predictions_cache(config Config, filename string, info os.FileInfo) ([]float64, []int, bool) {
+  f, err := os.Open(cache_filename(filename))
+  if err != nil {
+    return nil, nil, false
+  }
+  defer f.Close()
+  PrintStderr(config, 1, "Reading cache `%s'... ", cache_filename(filename))
+  cache := PredictionsCache{}
+  if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&cache); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    return nil, nil, false
+  }
+  if cache.Size != info.Size() || cache.ModTime != info.ModTime().UnixNano() {
+    PrintStderr(config, 1, "outdated\n")
+    return nil, nil, false
+  }
+  PrintStderr(config, 1, "done\n")
+  return cache.Values, cache.Labels, true
+}
+
+// Write the cache to a temporary file, which is renamed once complete so that
+// concurrent runs never read a partially written cache. The cache is only an
+// optimization, hence failures are reported but not fatal.
+func export_predictions_cache(config Config, filename string, info os.FileInfo, values []float64, labels []int) {
+  PrintStderr(config, 1, "Writing cache `%s'... ", cache_filename(filename))
+  if err := write_predictions_cache(filename, info, values, labels); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    fmt.Fprintf(os.Stderr, "warning: writing cache `%s' failed: %v\n", cache_filename(filename), err)
+    return
+  }
+  PrintStderr(config, 1, "done\n")
+}
+
+func write_predictions_cache(filename string, info os.FileInfo, values []float64, labels []int) error {
+  name := cache_filename(filename)
+  f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp"); if err != nil {
+    return err
+  }
+  defer os.Remove(f.Name())
+  w := bufio.NewWriter(f)
+  if err := gob.NewEncoder(w).Encode(PredictionsCache{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Values: values, Labels: labels}); err != nil {
+    f.Close()
+    return err
+  }
+  if err := w.Flush(); err != nil {
+    f.Close()
+    return err
+  }
+  if err := f.Close(); err != nil {
+    return err
+  }
+  return os.Rename(f.Name(), name)
+}
+
+func import_predictions_cached(config Config, filename string) ([]float64, []int) {
+  // cached labels depend on the relevance threshold and the duplicates
+  // policy, and objects in object stores and scores of regions are not cached
+  if !config.Cache || filename == "" || config.Regions != "" || is_object_uri(filename) || !math.IsNaN(config.RelevanceThreshold) || config.Duplicates != "" {
+    return import_predictions(config, filename)
+  }
+  info, err := os.Stat(filename); if err != nil {
+    log.Fatal(err)
+  }
+  if values, labels, ok := import_predictions_cache(config, filename, info); ok {
+    return values, labels
+  }
   values, labels := import_predictions(config, filename)
-  if len(values) == 0 {
-    log.Fatalf("table `%s' is empty", filename)
+  export_predictions_cache(config, filename, info, values, labels)
+  return values, labels
+}
+
+/* -------------------------------------------------------------------------- */
+
+func import_performance_binned(config Config, filename string) Performance {
+  if config.Regions != "" {
+    log.Fatal("regions are not supported in streaming mode")
+  }
+  if !math.IsNaN(config.RelevanceThreshold) {
+    log.Fatal("graded relevance labels are not supported in streaming mode")
+  }
+  if config.Duplicates != "" {
+    log.Fatal("duplicates are not detected in streaming mode")
+  }
+  evaluator, err := NewBinnedEvaluator(config.StreamRange[0], config.StreamRange[1], config.StreamBins); if err != nil {
+    log.Fatal(err)
   }
-  perf, err := EvalPerformance(values, labels); if err != nil {
+  scan_predictions(config, filename, func(value float64, label int) error {
+    evaluator.Add(value, label)
+    return nil
+  })
+  perf, err := evaluator.Performance(); if err != nil {
     log.Fatal(err)
   }
+  return perf
+}
 
-  switch strings.ToLower(target) {
-  case "precision-recall":
-    recall, precision := PrecisionRecall(perf, config.NormalizePrecision)
-    if config.PrintThresholds {
-      export_table3(config, os.Stdout, recall, precision, perf.Tr, "recall", "precision", "threshold")
+/* -------------------------------------------------------------------------- */
+
+func eval_performance(config Config, values []float64, labels []int) (Performance, error) {
+  if config.ThresholdGrid == "" {
+    return EvalPerformance(values, labels)
+  }
+  sort.Sort(Predictions{Values: values, Labels: labels})
+  grid := []float64{}
+  if fields := strings.Split(config.ThresholdGrid, ":"); len(fields) != 2 {
+    return Performance{}, fmt.Errorf("invalid threshold grid: %s", config.ThresholdGrid)
+  } else {
+    switch strings.ToLower(fields[0]) {
+    case "quantile":
+      n, err := strconv.Atoi(fields[1]); if err != nil {
+        return Performance{}, fmt.Errorf("invalid threshold grid: %s", config.ThresholdGrid)
+      }
+      if grid, err = QuantileGrid(values, n); err != nil {
+        return Performance{}, fmt.Errorf("invalid threshold grid `%s': %v", config.ThresholdGrid, err)
+      }
+    case "step":
+      step, err := strconv.ParseFloat(fields[1], 64); if err != nil {
+        return Performance{}, fmt.Errorf("invalid threshold grid: %s", config.ThresholdGrid)
+      }
+      if grid, err = StepGrid(values, step); err != nil {
+        return Performance{}, fmt.Errorf("invalid threshold grid `%s': %v", config.ThresholdGrid, err)
+      }
+    default:
+      return Performance{}, fmt.Errorf("invalid threshold grid: %s", config.ThresholdGrid)
+    }
+  }
+  return EvalPerformanceGrid(values, labels, grid)
+}
+
+/* -------------------------------------------------------------------------- */
+
+func new_metrics(config Config, perf Performance, values []float64, labels []int) *Metrics {
+  metrics := NewMetrics(perf, config.NormalizePrecision)
+  metrics.Values = values
+  metrics.Labels = labels
+  metrics.Method = strings.ToLower(config.Method)
+  metrics.Bins   = config.Bins
+  metrics.TopN        = config.Top
+  metrics.TopFraction = config.Fraction
+  metrics.Costs       = config.CostMatrix
+  metrics.CostPrevalences = config.Prevalence
+  metrics.Prevalences = config.PrevalenceGrid
+  metrics.Density     = config.Density
+  metrics.Threshold   = config.Threshold
+  metrics.Rejection   = config.Rejection
+  metrics.Compat      = config.Compat
+  if !math.IsNaN(config.Bandwidth) {
+    metrics.Bandwidth = config.Bandwidth
+  }
+  return metrics
+}
+
+// Evaluate scalar metrics. If degenerate inputs are allowed, metrics that
+// cannot be evaluated are undefined (NaN) instead of an error.
+func eval_metrics(config Config, data *Metrics, names []string) ([]float64, error) {
+  if !config.AllowDegenerate || !data.Degenerate() {
+    return data.Eval(names...)
+  }
+  r := make([]float64, len(names))
+  for i, name := range names {
+    if v, err := data.Eval(name); err != nil {
+      if _, ok := LookupMetric(name); !ok {
+        return nil, err
+      }
+      r[i] = math.NaN()
     } else {
-      export_table2(config, os.Stdout, recall, precision, "recall", "precision")
-    }
-  case "precision-recall-auc":
-    recall, precision := PrecisionRecall(perf, config.NormalizePrecision)
-    fmt.Println(AUC(recall, precision))
-  case "roc":
-    fpr, tpr := Roc(perf)
-    if config.PrintThresholds {
-      export_table3(config, os.Stdout, fpr, tpr, perf.Tr, "FPR", "TPR", "threshold")
+      r[i] = v[0]
+    }
+  }
+  return r, nil
+}
+
+// Names of metrics computed by the summary target. If raw is false, metrics
+// that require raw predictions are skipped.
+func summary_metrics(config Config, raw bool) []string {
+  names := config.Metrics
+  if len(names) == 0 {
+    for _, name := range MetricNames {
+      if name == "brier" && !raw {
+        continue
+      }
+      names = append(names, name)
+    }
+  }
+  return names
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Targets that print a single table, which may be exported in other formats
+// than text, and the performance target whose confusion matrices are
+// exported as protobuf message. YAML is restricted to the summary, scalar
+// metrics and operating points.
+func supports_format(format, target string) bool {
+  switch target {
+  case "performance":
+    return format == "proto"
+  case "summary":
+    return true
+  }
+  m, ok := LookupMetric(target)
+  if format == "yaml" {
+    return ok && m.Kind() != CurveMetric
+  }
+  switch target {
+  case "roc-average", "precision-recall-average", "froc", "top-k-overlap", "robustness", "time-roc", "time-auc":
+    return true
+  }
+  return ok
+}
+
+func classifier_performance(config Config, filename, target string) {
+  if len(config.Assert) > 0 {
+    parse_assertions(config)
+    // assertions are evaluated on pooled predictions of standard targets
+    _, ok := LookupMetric(strings.ToLower(target))
+    if !ok && strings.ToLower(target) != "summary" && strings.ToLower(target) != "performance" || is_fairness_target(strings.ToLower(target)) || config.FoldColumn != "" {
+      log.Fatalf("assertions are not supported by target `%s'", target)
+    }
+  }
+  if config.Format != "text" && (!supports_format(config.Format, strings.ToLower(target)) || config.FoldColumn != "" || config.KeepReplicates) {
+    log.Fatalf("--format %s is not supported by target `%s'", config.Format, target)
+  }
+  if config.KeepReplicates {
+    _, ok := LookupMetric(strings.ToLower(target))
+    if !ok && strings.ToLower(target) != "summary" || config.Bootstrap <= 0 || config.StreamBins > 0 || config.FoldColumn != "" {
+      log.Fatal("--keep-replicates requires --bootstrap and raw predictions, and is only supported by the summary target and registered metrics")
+    }
+  }
+  if config.ClusterColumn != "" {
+    if !is_scalar_target(strings.ToLower(target)) || config.StreamBins > 0 || config.FoldColumn != "" {
+      log.Fatal("cluster bootstrap is only supported by the summary target and scalar metrics")
+    }
+  }
+  if config.OutputDir != "" {
+    _, ok := LookupMetric(strings.ToLower(target))
+    standard := ok || strings.ToLower(target) == "summary" || strings.ToLower(target) == "performance"
+    if !standard || is_fairness_target(strings.ToLower(target)) || config.FoldColumn != "" && is_scalar_target(strings.ToLower(target)) {
+      export_output_dir_pooled(config, filename, strings.ToLower(target))
+    }
+  }
+  switch strings.ToLower(target) {
+  case "roc-average", "precision-recall-average":
+    classifier_performance_average(config, filename, strings.ToLower(target))
+    return
+  case "learning-curve":
+    classifier_performance_learning_curve(config, filename)
+    return
+  case "psi":
+    classifier_performance_psi(config, filename)
+    return
+  case "drift":
+    classifier_performance_drift(config, filename)
+    return
+  case "woe":
+    classifier_performance_woe(config, filename)
+    return
+  case "challenger":
+    classifier_performance_challenger(config, filename)
+    return
+  case "swap-set":
+    classifier_performance_swap_set(config, filename)
+    return
+  case "rank-correlation":
+    classifier_performance_rank_correlation(config, filename)
+    return
+  case "per-chromosome":
+    classifier_performance_per_chromosome(config, filename)
+    return
+  case "top-k-overlap":
+    classifier_performance_top_k_overlap(config, filename)
+    return
+  case "ndcg":
+    classifier_performance_ndcg(config, filename)
+    return
+  case "err":
+    classifier_performance_err(config, filename)
+    return
+  case "map", "mrr":
+    classifier_performance_map(config, filename, strings.ToLower(target))
+    return
+  case "hit-rate":
+    classifier_performance_hit_rate(config, filename)
+    return
+  case "precision@k", "recall@k":
+    classifier_performance_at_k(config, filename, strings.ToLower(target))
+    return
+  case "diagnostic-report":
+    classifier_performance_diagnostic_report(config, filename)
+    return
+  case "verification-bias":
+    classifier_performance_verification_bias(config, filename)
+    return
+  case "froc":
+    classifier_performance_froc(config, filename)
+    return
+  case "score-stats":
+    classifier_performance_score_stats(config, filename)
+    return
+  case "rolling":
+    classifier_performance_rolling(config, filename)
+    return
+  case "robustness":
+    classifier_performance_robustness(config, filename)
+    return
+  case "per-sample":
+    classifier_performance_per_sample(config, filename)
+    return
+  case "hardest-errors":
+    classifier_performance_hardest_errors(config, filename)
+    return
+  case "time-roc", "time-auc":
+    classifier_performance_time_roc(config, filename, strings.ToLower(target))
+    return
+  }
+  if is_fairness_target(strings.ToLower(target)) {
+    classifier_performance_fairness(config, filename, strings.ToLower(target))
+    return
+  }
+  if config.FoldColumn != "" {
+    if is_scalar_target(target) {
+      classifier_performance_cv(config, filename, strings.ToLower(target))
+      return
+    }
+  }
+  var values   []float64
+  var labels   []int
+  var clusters []int
+  var perf       Performance
+  if config.StreamBins > 0 {
+    perf = import_performance_binned(config, filename)
+    if perf.P + perf.N == 0 {
+      log.Fatalf("table `%s' is empty", filename)
+    }
+  } else {
+    if config.ClusterColumn != "" {
+      values, labels, clusters = import_clusters(config, filename)
     } else {
-      export_table2(config, os.Stdout, fpr, tpr, "FPR", "TPR")
-    }
-  case "roc-auc":
-    fpr, tpr := Roc(perf)
-    fmt.Println(AUC(fpr, tpr))
-  case "optimal-precision-recall":
-    recall, precision := PrecisionRecall(perf, config.NormalizePrecision)
-    i        := Optimum(perf.Tr, recall, precision)
-    if config.PrintHeader {
-      fmt.Printf("recall=%f precision=%f threshold=%f\n", recall[i], precision[i], perf.Tr[i])
+      values, labels = import_predictions_cached(config, filename)
+    }
+    if len(values) == 0 {
+      log.Fatalf("table `%s' is empty", filename)
+    }
+    // predictions are sorted in place, which must not break the alignment
+    // with clusters
+    v, l := values, labels
+    if clusters != nil {
+      v = append([]float64{}, values...)
+      l = append([]int{}, labels...)
+    }
+    if p, err := eval_performance(config, v, l); err != nil {
+      log.Fatal(err)
     } else {
-      fmt.Printf("%f %f %f\n", recall[i], precision[i], perf.Tr[i])
+      perf = p
     }
-  case "optimal-roc":
-    fpr, tpr := Roc(perf)
-    fpr_inv  := make([]float64, len(fpr))
-    for i := 0; i < len(fpr); i++ {
-      fpr_inv[i] = 1.0 - fpr[i]
+  }
+
+  // raw performance counts are well defined if only one class is present
+  if (perf.P == 0 || perf.N == 0) && !config.AllowDegenerate && strings.ToLower(target) != "performance" {
+    class := "positive"
+    if perf.P == 0 {
+      class = "negative"
     }
-    i := Optimum(perf.Tr, fpr_inv, tpr)
-    if config.PrintHeader {
-      fmt.Printf("fpr=%f tpr=%f threshold=%f\n", fpr[i], tpr[i], perf.Tr[i])
+    log.Fatalf("table `%s' contains only %s predictions, use --allow-degenerate to report undefined metrics as NA", filename, class)
+  }
+  metrics := new_metrics(config, perf, values, labels)
+
+  if config.OutputDir != "" {
+    export_output_dir(config, metrics)
+  }
+  if config.MlflowUri != "" {
+    export_mlflow(config, metrics)
+  }
+  if config.Tensorboard != "" {
+    export_tensorboard(config, metrics)
+  }
+  if config.Wandb != "" {
+    export_wandb(config, metrics)
+  }
+
+  if config.KeepReplicates {
+    metric, ok := LookupMetric(strings.ToLower(target))
+    if !ok {
+      names := summary_metrics(config, true)
+      metric = NewMetric("summary", PointMetric, func(data *Metrics) (Table, error) {
+        r, err := eval_metrics(config, data, names); if err != nil {
+          return Table{}, err
+        }
+        table := Table{Names: names}
+        for _, v := range r {
+          table.Columns = append(table.Columns, []float64{v})
+        }
+        return table, nil
+      })
+    }
+    ids, tables := bootstrap_tables(config, values, labels, clusters, metric)
+    export_replicates(config, os.Stdout, ids, tables)
+    if len(config.Assert) > 0 {
+      check_assertions(config, metrics)
+    }
+    return
+  }
+  switch strings.ToLower(target) {
+  case "summary":
+    // raw predictions are not available in streaming mode
+    names := summary_metrics(config, config.StreamBins == 0)
+    if r, err := eval_metrics(config, metrics, names); err != nil {
+      log.Fatal(err)
+    } else
+    if config.Bootstrap > 0 && values != nil {
+      lower, upper := bootstrap_metrics(config, values, labels, clusters, names)
+      if config.Format == "arrow" {
+        export_arrow(config, os.Stdout, "metric", names, Table{Names: []string{"value", "lower", "upper"}, Columns: [][]float64{r, lower, upper}})
+        break
+      }
+      if config.Format == "proto" {
+        export_proto(os.Stdout, &evaluationService.Results{Positives: int64(perf.P), Negatives: int64(perf.N), Metrics: proto_metrics(names, r, lower, upper)})
+        break
+      }
+      if config.Format == "yaml" {
+        export_yaml_metrics(os.Stdout, &perf, names, r, lower, upper)
+        break
+      }
+      if config.PrintHeader {
+        fmt.Println("metric value lower upper")
+      }
+      for i := 0; i < len(r); i++ {
+        fmt.Printf("%s %s %s %s\n", names[i], format_value(config, r[i]), format_value(config, lower[i]), format_value(config, upper[i]))
+      }
     } else {
-      fmt.Printf("%f %f %f\n", fpr[i], tpr[i], perf.Tr[i])
+      if config.Format == "arrow" {
+        export_arrow(config, os.Stdout, "metric", names, Table{Names: []string{"value"}, Columns: [][]float64{r}})
+        break
+      }
+      if config.Format == "proto" {
+        export_proto(os.Stdout, &evaluationService.Results{Positives: int64(perf.P), Negatives: int64(perf.N), Metrics: proto_metrics(names, r, nil, nil)})
+        break
+      }
+      if config.Format == "yaml" {
+        export_yaml_metrics(os.Stdout, &perf, names, r, nil, nil)
+        break
+      }
+      if config.PrintHeader {
+        fmt.Println("metric value")
+      }
+      for i := 0; i < len(r); i++ {
+        fmt.Printf("%s %s\n", names[i], format_value(config, r[i]))
+      }
+    }
+  case "performance":
+    if config.Format == "proto" {
+      export_proto(os.Stdout, &evaluationService.Results{Positives: int64(perf.P), Negatives: int64(perf.N), ConfusionMatrices: proto_confusion_matrices(perf)})
+      break
+    }
+    if err := json.NewEncoder(os.Stdout).Encode(perf); err != nil {
+      log.Fatal(err)
     }
   default:
-    log.Fatalf("invalid target: %s", target)
+    if metric, ok := LookupMetric(strings.ToLower(target)); !ok {
+      log.Fatalf("invalid target: %s", target)
+    } else
+    if metric.Kind() == ScalarMetric && config.Bootstrap > 0 && values != nil {
+      // scalar with confidence interval
+      r, err := eval_metrics(config, metrics, []string{metric.Name()}); if err != nil {
+        log.Fatal(err)
+      }
+      lower, upper := bootstrap_metrics(config, values, labels, clusters, []string{metric.Name()})
+      if config.Format == "arrow" {
+        export_arrow(config, os.Stdout, "metric", []string{metric.Name()}, Table{Names: []string{"value", "lower", "upper"}, Columns: [][]float64{r, lower, upper}})
+      } else
+      if config.Format == "proto" {
+        export_proto(os.Stdout, &evaluationService.Results{Positives: int64(perf.P), Negatives: int64(perf.N), Metrics: proto_metrics([]string{metric.Name()}, r, lower, upper)})
+      } else
+      if config.Format == "yaml" {
+        export_yaml_metrics(os.Stdout, &perf, []string{metric.Name()}, r, lower, upper)
+      } else
+      if config.AllowDegenerate {
+        fmt.Println(format_value(config, r[0]), format_value(config, lower[0]), format_value(config, upper[0]))
+      } else {
+        fmt.Println(r[0], lower[0], upper[0])
+      }
+    } else {
+      export_metric(config, os.Stdout, metric, metrics)
+    }
+  }
+  if len(config.Assert) > 0 {
+    check_assertions(config, metrics)
   }
 }
 
@@ -159,20 +976,101 @@ func main() {
   config  := Config{}
   options := getopt.New()
 
+  optAllowDegen    := options.   BoolLong("allow-degenerate",     0,    "evaluate predictions with only positives or only negatives, reporting undefined values as NA")
+  optAssert        := options.   ListLong("assert",               0,    "exit with a non-zero status unless the given condition holds, e.g. roc-auc>=0.85 or precision@recall(0.9)>=0.5, may be repeated")
+  optAuc           := options. StringLong("auc",                  0, "0.8", "area under the ROC curve of predictions generated by simulate [default: 0.8]")
+  optAveraging     := options. StringLong("averaging",            0, "", "method for averaging curves over folds [vertical (default), threshold]")
+  optAveragingN    := options.    IntLong("averaging-points",     0, 101, "number of grid points for vertical averaging [default: 101]")
+  optBandwidth     := options. StringLong("bandwidth",            0, "", "kernel bandwidth of smoothed ROC curves [auto (default) or a positive number]")
+  optBins          := options.    IntLong("bins",                 0,  10, "number of bins for calibration measures and score tables [default: 10]")
+  optBootstrap     := options.    IntLong("bootstrap",            0,   0, "number of bootstrap replicates for computing confidence intervals")
+  optCache         := options.   BoolLong("cache",                0,    "cache parsed predictions in a binary file next to the input")
+  optCalBins       := options.    IntLong("calibration-bins",     0,  10, "alias of --bins")
+  optChromColumn   := options. StringLong("chromosome-column",    0, "", "name of the column with chromosomes for the per-chromosome target [default: parsed from IDs of the form chr:start-end]")
+  optClusterColumn := options. StringLong("cluster-column",       0, "", "name of the column with clusters of correlated predictions, which are resampled jointly by the bootstrap")
+  optCompat        := options. StringLong("compat",               0, "", "compute roc and precision-recall curves and their areas, and print values with full precision following the conventions of another implementation [sklearn]")
+  optConfidence    := options. StringLong("confidence",           0, "0.95", "confidence level of bootstrap intervals and bands of averaged curves [default: 0.95]")
+  optCostMatrix    := options. StringLong("cost-matrix",          0, "", "costs of true positives, false positives, false negatives and true negatives [default: 0,1,1,0]")
+  optCvAggregation := options. StringLong("cv-aggregation",       0, "", "aggregation of scalar metrics over folds [pooled, average], both are reported by default")
+  optDensity       := options. StringLong("density",              0, "", "estimate of class score distributions for divergence measures [histogram (default) with --bins bins, kde with --bandwidth]")
+  optDuplicates    := options. StringLong("duplicates",           0, "", "policy for duplicate IDs given by --id-column or duplicate rows [error, first, mean, keep]")
+  optEventColumn   := options. StringLong("event-column",         0, "", "name of the column with event indicators (1: event, 0: censored) for time-dependent ROC curves [default: labels]")
+  optEventTime     := options. StringLong("event-time-column",    0, "", "name of the column with event or censoring times for time-dependent ROC curves")
+  optFairCriterion := options. StringLong("fairness-criterion",   0, "", "criterion for optimizing group thresholds [equalized-odds (default), equal-opportunity]")
+  optFairTolerance := options. StringLong("fairness-tolerance",   0, "0.05", "maximum fairness gap when optimizing group thresholds [default: 0.05]")
+  optFeatureColumn := options. StringLong("feature-column",       0, "", "name of a column with feature values binned by the woe target instead of predictions")
+  optFoldColumn    := options. StringLong("fold-column",          0, "", "name of the column with cross-validation folds")
+  optFormat        := options. StringLong("format",               0, "", "output format of tables [text (default), arrow (IPC stream), proto (Results message of pkg/evaluationService/results.proto), yaml (summary, scalar metrics and operating points)]")
+  optFraction      := options. StringLong("fraction",             0, "", "fraction of top scored predictions for lift@k and capture@k, or of approved (lowest scored) predictions for swap-set [default: 0.1]")
+  optGroupColumn   := options. StringLong("group-column",         0, "", "name of the column with groups")
+  optGrpc          := options. StringLong("grpc",                 0, "", "address of the gRPC evaluation service started by serve, e.g. :9090")
+  optHdf5          := options. StringLong("hdf5",                 0, "", "HDF5 file with datasets of predictions and labels given instead of a prediction table (requires building with -tags hdf5)")
+  optHorizons      := options. StringLong("horizons",             0, "", "comma separated list of time horizons for time-dependent ROC curves")
+  optIdColumn      := options. StringLong("id-column",            0, "", "name of the column with observation IDs for joining predictions of two models and for the per-sample and hardest-errors targets")
+  optImageColumn   := options. StringLong("image-column",         0, "", "name of the column with images of candidate detections for the froc target")
+  optK             := options.   ListLong("k",                    0,    "comma separated list of rank cutoffs for ranking measures and top-k-overlap, may be repeated [default: all predictions, 10 for hit-rate]")
+  optKeepReps      := options.   BoolLong("keep-replicates",      0,    "print results of each bootstrap replicate in long format with a replicate column instead of confidence intervals")
+  optLabels        := options. StringLong("labels",               0, "", "NumPy .npy file with labels, or .npz archive with the array name appended (e.g. arrays.npz:labels), given together with --scores")
+  optLabelsData    := options. StringLong("labels-dataset",       0, "labels", "name of the HDF5 dataset with labels [default: labels]")
+  optLabelFlip     := options. StringLong("label-flip",           0, "0", "comma separated list of fractions of flipped labels for the robustness target [default: 0]")
+  optLesionColumn  := options. StringLong("lesion-column",        0, "", "name of the column with the number of lesions in each image for the froc target")
+  optLogo          := options.   BoolLong("logo",                 0,    "leave-one-group-out evaluation, i.e. use groups as folds")
+  optMethod        := options. StringLong("method",               0, "", "method for computing the roc-auc [integration (default), ranksum]")
+  optMetrics       := options. StringLong("metrics",              0, "", "comma separated list of metrics computed by the summary target [default: all]")
+  optMinGroupSize  := options.    IntLong("min-group-size",       0,  -1, "minimum number of predictions in each group [default: 10 for intersectional groups]")
+  optMlflowUri     := options. StringLong("mlflow-uri",           0, "", "log metrics and upload curves as artifacts to the MLflow tracking server with the given URI")
+  optModel         := options. StringLong("model",                0, "", "model of predictions generated by simulate [binormal (default), beta]")
+  optNoiseSd       := options. StringLong("noise-sd",             0, "0,0.01,0.05,0.1", "comma separated list of standard deviations of Gaussian noise added to predictions by the robustness target [default: 0,0.01,0.05,0.1]")
   optNormalizePrec := options.   BoolLong("normalize-precision",  0,    "normalize precision to the interval [0,1]")
+  optOutputDir     := options. StringLong("output-dir",           0, "", "write curves, metrics, plots and options to the given directory")
+  optPerQuery      := options.   BoolLong("per-query",            0,    "print ranking measures of each query in addition to the mean over queries")
+  optPrevalence    := options. StringLong("prevalence",           0, "", "fraction of positives assumed by cost measures or generated by simulate, where cost curves accept a comma separated list [default: prevalence of the data, 0.5 for simulate]")
+  optPrevGrid      := options. StringLong("prevalence-grid",      0, "", "comma separated list of assumed prevalences for the label-shift target [default: 0.01,0.02,...,0.99]")
   optPrintHeader   := options.   BoolLong("print-header",         0,    "print header")
   optPrintThr      := options.   BoolLong("print-thresholds",     0,    "print addition column with thresholds")
+  optProtocol      := options. StringLong("protocol",             0, "", "read a single request from stdin and write the response to stdout [json]")
+  optQueryColumn   := options. StringLong("query-column",         0, "", "name of the column with queries, within which ranking measures are computed before averaging")
+  optReferenceFile := options. StringLong("reference-file",       0, "", "file with reference predictions for the psi target, or champion predictions for the challenger, swap-set, rank-correlation and top-k-overlap targets")
+  optReferenceGroup:= options. StringLong("reference-group",      0, "", "reference group for the disparate impact ratio [default: group with largest selection rate]")
+  optRegionScore   := options. StringLong("region-score",         0, "", "summary of scores within regions given by --regions [max (default), mean]")
+  optRegions       := options. StringLong("regions",              0, "", "BED file with regions labeled in the name column (1 or positive, 0 or negative), whose scores are extracted from the bigWig or bedGraph file given instead of predictions")
+  optRelevanceCol  := options. StringLong("relevance-column",     0, "", "name of the column with graded relevance values for ranking measures [default: labels]")
+  optRelevanceThr  := options. StringLong("relevance-threshold",  0, "", "read labels as graded relevance values, where values of at least the threshold are positive")
+  optRejection     := options. StringLong("rejection",            0, "", "confidence of predictions for the accuracy-rejection target [margin (default): distance to --threshold (default: optimal threshold of the ROC curve), score: prediction value]")
+  optRepeatColumn  := options. StringLong("repeat-column",        0, "", "name of the column with repeats of a cross-validation")
+  optRepetitions   := options.    IntLong("repetitions",          0, 100, "number of noisy replicates for each noise level of the robustness target [default: 100]")
+  optReportEvery   := options. StringLong("report-every",         0, "", "report interval of the rolling target as number of rows or duration, e.g. 30s [default: --window-size rows]")
+  optReportFormat  := options. StringLong("report-format",        0, "", "format of reports [json (default), html (fairness-report), text (challenger, diagnostic-report)]")
+  optRunId         := options. StringLong("run-id",               0, "", "ID of the MLflow run [default: MLFLOW_RUN_ID]")
+  optScores        := options. StringLong("scores",               0, "", "NumPy .npy file with predictions, or .npz archive with the array name appended (e.g. arrays.npz:scores), given together with --labels instead of a prediction table")
+  optScoresData    := options. StringLong("scores-dataset",       0, "predictions", "name of the HDF5 dataset with predictions [default: predictions]")
+  optSeed          := options.    IntLong("seed",                 0,   1, "seed for the random number generator [default: 1]")
+  optSizeColumn    := options. StringLong("size-column",          0,  "", "name of the column with training set sizes for the learning-curve target")
+  optSmallGroups   := options. StringLong("small-groups",         0, "", "handling of groups below the minimum size [suppress (default), merge]")
+  optStep          := options.    IntLong("step",                 0,   0, "step of logged results, e.g. the training epoch, for TensorBoard and MLflow")
+  optStreamBins    := options.    IntLong("stream-bins",          0,  0, "evaluate performance in bounded memory by binning predictions into the given number of bins")
+  optStreamRange   := options. StringLong("stream-range",         0, "0:1", "range of prediction values used for binning [default: 0:1]")
+  optTensorboard   := options. StringLong("tensorboard",          0, "", "write metrics and the precision-recall curve as TensorBoard event file to the given directory")
+  optThreshold     := options. StringLong("threshold",            0, "", "threshold shared by all groups, the operating point of histograms, the prevalence threshold and label-shift tables, the errors listed by hardest-errors, or the deployed threshold exported by the server [default: optimal threshold of pooled predictions]")
+  optThrGrid       := options. StringLong("threshold-grid",       0, "", "evaluate performance on a reduced grid of thresholds [quantile:<N>, step:<WIDTH>]")
+  optTimeColumn    := options. StringLong("time-column",          0, "", "name of the column with time stamps for the drift target")
+  optTop           := options.    IntLong("top",                  0,   0, "number of top scored predictions for lift@k and capture@k, of approved (lowest scored) predictions for swap-set, or of errors listed by hardest-errors")
   optVerbose       := options.CounterLong("verbose",             'v',   "verbose level [-v or -vv]")
+  optVerified      := options. StringLong("verified-column",      0, "", "name of the column indicating verified labels for verification bias correction")
+  optWeightColumn  := options. StringLong("weight-column",        0, "", "name of the column with inverse probability of verification weights [default: estimated within --bins strata of predictions]")
+  optWandb         := options. StringLong("wandb",                0, "", "log metrics and upload curves to the W&B run with the given path [ENTITY/PROJECT/RUN_ID]")
+  optWindow        := options. StringLong("window",               0, "", "time window for the drift target [day, week, month (default), year, or a duration such as 12h]")
+  optWindowSize    := options.    IntLong("window-size",          0,   0, "number of most recent predictions evaluated by the rolling target and by sessions of the server [default: all predictions, 100000 for sessions]")
   optHelp          := options.   BoolLong("help",                'h',   "print help")
 
-  options.SetParameters("<TARGET> [<PREDICTIONS.table>]\n\n" +
-    "TARGETS:\n" +
-    " -> precision-recall\n" +
-    " -> precision-recall-auc\n" +
-    " -> roc\n" +
-    " -> roc-auc\n" +
-    " -> optimal-precision-recall\n" +
-    " -> optimal-roc\n")
+  targets := append(RegisteredMetrics(), "summary", "performance", "roc-average", "precision-recall-average", "learning-curve", "psi", "drift", "woe", "challenger", "swap-set", "rank-correlation", "top-k-overlap", "per-chromosome", "time-roc", "time-auc", "froc", "verification-bias", "diagnostic-report", "ndcg", "err", "map", "mrr", "precision@k", "recall@k", "hit-rate", "rolling", "score-stats", "robustness", "per-sample", "hardest-errors")
+  targets  = append(targets, fairness_targets...)
+  options.SetParameters("<TARGET> [<PREDICTIONS.table>]\n" +
+    "       classifierPerformance [OPTION]... aggregate <TARGET> <PERFORMANCE.json or metrics.json>...\n" +
+    "       classifierPerformance [OPTION]... serve [<ADDRESS>]\n" +
+    "       classifierPerformance [OPTION]... simulate <N>\n" +
+    "       classifierPerformance [OPTION]... --protocol json < <REQUEST.json>\n\n" +
+    "TARGETS:\n -> " + strings.Join(targets, "\n -> ") + "\n")
   options.Parse(os.Args)
 
   // parse options
@@ -184,19 +1082,291 @@ func main() {
   if *optVerbose != 0 {
     config.Verbose = *optVerbose
   }
+  if len(options.Args()) >= 1 && options.Args()[0] == "aggregate" {
+    if len(options.Args()) < 3 {
+      options.PrintUsage(os.Stderr)
+      os.Exit(1)
+    }
+  } else
+  if len(options.Args()) >= 1 && options.Args()[0] == "simulate" {
+    if len(options.Args()) != 2 {
+      options.PrintUsage(os.Stderr)
+      os.Exit(1)
+    }
+  } else
+  if strings.ToLower(*optProtocol) == "json" {
+    if len(options.Args()) != 0 {
+      options.PrintUsage(os.Stderr)
+      os.Exit(1)
+    }
+  } else
   if len(options.Args()) != 1 && len(options.Args()) != 2 {
     options.PrintUsage(os.Stderr)
     os.Exit(1)
   }
+  config.AllowDegenerate    = *optAllowDegen
+  config.Assert             = *optAssert
+  if v, err := strconv.ParseFloat(*optAuc, 64); err != nil || v <= 0.0 || v >= 1.0 {
+    log.Fatalf("invalid auc: %s", *optAuc)
+  } else {
+    config.Auc = v
+  }
+  config.Averaging          = strings.ToLower(*optAveraging)
+  config.AveragingPoints    = *optAveragingN
+  config.Bins               = *optBins
+  // --calibration-bins is kept for compatibility with older scripts
+  if options.IsSet("calibration-bins") {
+    if options.IsSet("bins") && *optBins != *optCalBins {
+      log.Fatal("--bins and --calibration-bins are given with different values")
+    }
+    config.Bins = *optCalBins
+  }
+  config.Bootstrap          = *optBootstrap
+  // NaN if no smoothed ROC curve is requested and zero for automatic
+  // bandwidth selection
+  switch strings.ToLower(*optBandwidth) {
+  case "":
+    config.Bandwidth = math.NaN()
+  case "auto":
+    config.Bandwidth = 0.0
+  default:
+    if v, err := strconv.ParseFloat(*optBandwidth, 64); err != nil || !(v > 0.0) {
+      log.Fatalf("invalid bandwidth: %s", *optBandwidth)
+    } else {
+      config.Bandwidth = v
+    }
+  }
+  config.Cache              = *optCache
+  config.ChromosomeColumn   = *optChromColumn
+  config.ClusterColumn      = *optClusterColumn
+  switch strings.ToLower(*optCompat) {
+  case "":
+  case "sklearn":
+    if *optStreamBins > 0 || *optThrGrid != "" || *optNormalizePrec {
+      log.Fatal("--compat sklearn requires performance at all unique prediction values and cannot be combined with --stream-bins, --threshold-grid or --normalize-precision")
+    }
+    config.Compat = "sklearn"
+  default:
+    log.Fatalf("invalid compatibility mode: %s", *optCompat)
+  }
+  if v, err := strconv.ParseFloat(*optConfidence, 64); err != nil || v <= 0.0 || v >= 1.0 {
+    log.Fatalf("invalid confidence level: %s", *optConfidence)
+  } else {
+    config.Confidence = v
+  }
+  if *optCostMatrix != "" {
+    if c, err := ParseCostMatrix(*optCostMatrix); err != nil {
+      log.Fatal(err)
+    } else {
+      config.CostMatrix = &c
+    }
+  }
+  config.CvAggregation      = strings.ToLower(*optCvAggregation)
+  config.Density            = strings.ToLower(*optDensity)
+  switch strings.ToLower(*optDuplicates) {
+  case "", "error", "first", "mean", "keep":
+    config.Duplicates = strings.ToLower(*optDuplicates)
+  default:
+    log.Fatalf("invalid duplicates policy: %s", *optDuplicates)
+  }
+  config.EventColumn        = *optEventColumn
+  config.EventTimeColumn    = *optEventTime
+  config.FairnessCriterion  = strings.ToLower(*optFairCriterion)
+  if v, err := strconv.ParseFloat(*optFairTolerance, 64); err != nil || v < 0.0 {
+    log.Fatalf("invalid fairness tolerance: %s", *optFairTolerance)
+  } else {
+    config.FairnessTolerance = v
+  }
+  config.FeatureColumn      = *optFeatureColumn
+  config.FoldColumn         = *optFoldColumn
+  switch strings.ToLower(*optFormat) {
+  case "", "text":
+    config.Format = "text"
+  case "arrow":
+    config.Format = "arrow"
+  case "proto":
+    config.Format = "proto"
+  case "yaml":
+    config.Format = "yaml"
+  default:
+    log.Fatalf("invalid format: %s", *optFormat)
+  }
+  if *optFraction != "" {
+    if v, err := strconv.ParseFloat(*optFraction, 64); err != nil || v <= 0.0 || v > 1.0 {
+      log.Fatalf("invalid fraction: %s", *optFraction)
+    } else {
+      config.Fraction = v
+    }
+  }
+  config.GroupColumn        = *optGroupColumn
+  config.Grpc               = *optGrpc
+  config.Hdf5               = *optHdf5
+  if *optHorizons != "" {
+    config.Horizons = parse_horizons(*optHorizons)
+  }
+  config.IdColumn           = *optIdColumn
+  config.ImageColumn        = *optImageColumn
+  for _, s := range *optK {
+    if k, err := strconv.Atoi(s); err != nil || k < 1 {
+      log.Fatalf("invalid rank cutoff: %s", s)
+    } else {
+      config.K = append(config.K, k)
+    }
+  }
+  config.KeepReplicates     = *optKeepReps
+  config.LabelFlip          = parse_levels(*optLabelFlip, "fraction of flipped labels")
+  config.Labels             = *optLabels
+  config.LabelsDataset      = *optLabelsData
+  config.LesionColumn       = *optLesionColumn
+  config.Logo               = *optLogo
+  if config.Logo {
+    if config.GroupColumn == "" {
+      log.Fatal("leave-one-group-out evaluation requires a group column")
+    }
+    if config.FoldColumn != "" {
+      log.Fatal("leave-one-group-out evaluation cannot be combined with a fold column")
+    }
+    // each held-out group is treated as a separate fold
+    config.FoldColumn = config.GroupColumn
+  }
+  config.Method             = *optMethod
+  if *optMetrics != "" {
+    config.Metrics = strings.Split(*optMetrics, ",")
+  }
+  config.MinGroupSize       = *optMinGroupSize
+  config.MlflowUri          = *optMlflowUri
+  config.Model              = strings.ToLower(*optModel)
+  config.NoiseSd            = parse_levels(*optNoiseSd, "noise standard deviation")
+  config.OutputDir          = *optOutputDir
+  if *optPrevGrid != "" {
+    for _, v := range parse_levels(*optPrevGrid, "prevalence") {
+      if v <= 0.0 || v >= 1.0 {
+        log.Fatalf("invalid prevalence: %v", v)
+      }
+      config.PrevalenceGrid = append(config.PrevalenceGrid, v)
+    }
+  }
+  if *optPrevalence != "" {
+    for _, v := range parse_levels(*optPrevalence, "prevalence") {
+      if v <= 0.0 || v >= 1.0 {
+        log.Fatalf("invalid prevalence: %v", v)
+      }
+      config.Prevalence = append(config.Prevalence, v)
+    }
+  }
+  config.PerQuery           = *optPerQuery
   config.PrintHeader        = *optPrintHeader
   config.PrintThresholds    = *optPrintThr
+  config.Protocol           = strings.ToLower(*optProtocol)
+  config.QueryColumn        = *optQueryColumn
+  config.ReferenceFile      = *optReferenceFile
+  config.ReferenceGroup     = *optReferenceGroup
+  config.RelevanceColumn    = *optRelevanceCol
+  config.RelevanceThreshold = math.NaN()
+  if *optRelevanceThr != "" {
+    if v, err := strconv.ParseFloat(*optRelevanceThr, 64); err != nil || math.IsNaN(v) {
+      log.Fatalf("invalid relevance threshold: %s", *optRelevanceThr)
+    } else {
+      config.RelevanceThreshold = v
+    }
+  }
+  config.Rejection          = strings.ToLower(*optRejection)
+  switch strings.ToLower(*optRegionScore) {
+  case "", "max":
+    config.RegionScore = "max"
+  case "mean":
+    config.RegionScore = "mean"
+  default:
+    log.Fatalf("invalid region score: %s", *optRegionScore)
+  }
+  config.Regions            = *optRegions
+  config.RepeatColumn       = *optRepeatColumn
+  config.Repetitions        = *optRepetitions
+  config.ReportEvery        = *optReportEvery
+  config.ReportFormat       = strings.ToLower(*optReportFormat)
+  config.RunId              = *optRunId
+  config.Scores             = *optScores
+  config.ScoresDataset      = *optScoresData
+  if (config.Scores == "") != (config.Labels == "") {
+    log.Fatal("--scores and --labels must be given together")
+  }
+  if config.Scores != "" && config.Hdf5 != "" {
+    log.Fatal("--scores and --labels cannot be combined with --hdf5")
+  }
+  if (config.Scores != "" || config.Hdf5 != "") && config.Regions != "" {
+    log.Fatal("arrays of predictions and labels cannot be combined with --regions")
+  }
+  config.Seed               = int64(*optSeed)
+  config.SizeColumn         = *optSizeColumn
+  config.SmallGroups        = strings.ToLower(*optSmallGroups)
+  config.Tensorboard        = *optTensorboard
+  config.Threshold          = math.NaN()
+  if *optThreshold != "" {
+    if v, err := strconv.ParseFloat(*optThreshold, 64); err != nil {
+      log.Fatalf("invalid threshold: %s", *optThreshold)
+    } else {
+      config.Threshold = v
+    }
+  }
+  config.ThresholdGrid      = *optThrGrid
+  config.TimeColumn         = *optTimeColumn
+  config.Top                = *optTop
+  config.VerifiedColumn     = *optVerified
+  config.WeightColumn       = *optWeightColumn
+  config.Wandb              = *optWandb
+  config.Window             = strings.ToLower(*optWindow)
+  config.WindowSize         = *optWindowSize
+  config.Step               = int64(*optStep)
+  config.StreamBins         = *optStreamBins
+  if config.StreamBins > 0 && config.ThresholdGrid != "" {
+    // thresholds are given by the bins in streaming mode
+    log.Fatal("--threshold-grid cannot be combined with --stream-bins")
+  }
+  if fields := strings.Split(*optStreamRange, ":"); len(fields) != 2 {
+    log.Fatalf("invalid stream range: %s", *optStreamRange)
+  } else {
+    for i := 0; i < 2; i++ {
+      if v, err := strconv.ParseFloat(fields[i], 64); err != nil {
+        log.Fatalf("invalid stream range: %s", *optStreamRange)
+      } else {
+        config.StreamRange[i] = v
+      }
+    }
+  }
   config.NormalizePrecision = *optNormalizePrec
   config.PrintThresholds    = *optPrintThr
 
+  switch config.Protocol {
+  case "":
+  case "json":
+    classifier_performance_protocol_json(config)
+    return
+  default:
+    log.Fatalf("invalid protocol: %s", config.Protocol)
+  }
+  if options.Args()[0] == "aggregate" {
+    aggregate(config, options.Args()[1], options.Args()[2:])
+    return
+  }
+  if options.Args()[0] == "simulate" {
+    classifier_performance_simulate(config, options.Args()[1])
+    return
+  }
+  if options.Args()[0] == "serve" {
+    address := ""
+    if len(options.Args()) == 2 {
+      address = options.Args()[1]
+    }
+    classifier_performance_serve(config, address)
+    return
+  }
   target   := options.Args()[0]
   filename := ""
   if len(options.Args()) == 2 {
     filename = options.Args()[1]
   }
+  if (config.Scores != "" || config.Hdf5 != "") && filename != "" {
+    log.Fatal("no prediction table can be given together with --scores and --labels or --hdf5")
+  }
   classifier_performance(config, filename, target)
 }
@@ -0,0 +1,268 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Pooled and fold-averaged metrics that differ by more than this value are
+// reported
+const cv_divergence_threshold = 0.05
+
+/* -------------------------------------------------------------------------- */
+
+type Fold struct {
+  Name   string
+  Values []float64
+  Labels []int
+  Perf   Performance
+}
+
+// A single repetition of a cross-validation
+type Repeat struct {
+  Name   string
+  Pooled Fold
+  Folds  []Fold
+}
+
+func new_fold(config Config, name string, values []float64, labels []int) Fold {
+  if perf, err := eval_performance(config, values, labels); err != nil {
+    log.Fatal(err)
+    return Fold{}
+  } else {
+    return Fold{Name: name, Values: values, Labels: labels, Perf: perf}
+  }
+}
+
+// Compute performance separately for each fold and repeat. The pooled
+// predictions of all folds within a repeat are returned as well. Without
+// a repeat column, a single repeat is returned.
+func import_folds(config Config, filename string) []Repeat {
+  if config.FoldColumn == "" {
+    log.Fatal("no fold column specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("folds are not supported in streaming mode")
+  }
+  columns := []string{config.FoldColumn}
+  if config.RepeatColumn != "" {
+    columns = append(columns, config.RepeatColumn)
+  }
+  table   := import_table(config, filename, columns...)
+  r_names := []string{""}
+  r_values:= [][]float64{table.Values}
+  r_labels:= [][]int{table.Labels}
+  r_folds := [][]string{table.Columns[config.FoldColumn]}
+  if config.RepeatColumn != "" {
+    // split fold column by repeats
+    r_names, r_values, r_labels = GroupPredictions(table.Values, table.Labels, table.Columns[config.RepeatColumn])
+    r_index := make(map[string]int)
+    for k, name := range r_names {
+      r_index[name] = k
+    }
+    r_folds = make([][]string, len(r_names))
+    for i, name := range table.Columns[config.RepeatColumn] {
+      k := r_index[name]
+      r_folds[k] = append(r_folds[k], table.Columns[config.FoldColumn][i])
+    }
+  }
+  repeats := make([]Repeat, len(r_names))
+  for k := range r_names {
+    names, values, labels := GroupPredictions(r_values[k], r_labels[k], r_folds[k])
+    if config.Logo {
+      // folds are groups
+      names, values, labels = filter_groups(config, names, values, labels)
+    }
+    repeats[k].Name  = r_names[k]
+    repeats[k].Folds = make([]Fold, len(names))
+    for j := range names {
+      repeats[k].Folds[j] = new_fold(config, names[j], values[j], labels[j])
+    }
+    repeats[k].Pooled = new_fold(config, "pooled", r_values[k], r_labels[k])
+    PrintStderr(config, 1, "Found %d folds\n", len(names))
+  }
+  return repeats
+}
+
+/* -------------------------------------------------------------------------- */
+
+func is_scalar_target(target string) bool {
+  if target == "summary" {
+    return true
+  }
+  if metric, ok := LookupMetric(target); ok && metric.Kind() == ScalarMetric {
+    return true
+  }
+  return false
+}
+
+// Evaluate metrics on the pooled predictions and on each fold separately.
+// The result for fold k is stored in r_folds[k]. Metrics of folds with a
+// single class are undefined if degenerate inputs are allowed.
+func eval_cv_metrics(config Config, repeat Repeat, names []string) ([]float64, [][]float64) {
+  r_pooled, err := eval_metrics(config, new_metrics(config, repeat.Pooled.Perf, repeat.Pooled.Values, repeat.Pooled.Labels), names); if err != nil {
+    log.Fatal(err)
+  }
+  r_folds := make([][]float64, len(repeat.Folds))
+  for k, fold := range repeat.Folds {
+    r, err := eval_metrics(config, new_metrics(config, fold.Perf, fold.Values, fold.Labels), names); if err != nil {
+      log.Fatalf("fold `%s': %v", fold.Name, err)
+    }
+    r_folds[k] = r
+  }
+  return r_pooled, r_folds
+}
+
+// Mean and standard deviation of metrics, i.e. over columns of r
+func summarize_metrics(names []string, r [][]float64) ([]float64, []float64) {
+  mean := make([]float64, len(names))
+  sd   := make([]float64, len(names))
+  for i := range names {
+    x := make([]float64, len(r))
+    for k := range r {
+      x[k] = r[k][i]
+    }
+    mean[i], sd[i] = MeanSd(x)
+  }
+  return mean, sd
+}
+
+func standard_error(sd []float64, n int) []float64 {
+  se := make([]float64, len(sd))
+  for i := range sd {
+    se[i] = sd[i]/math.Sqrt(float64(n))
+  }
+  return se
+}
+
+func check_cv_divergence(names []string, r_pooled, r_average []float64) {
+  for i, name := range names {
+    if d := math.Abs(r_pooled[i] - r_average[i]); d > cv_divergence_threshold {
+      fmt.Fprintf(os.Stderr, "warning: pooled and fold-averaged %s differ substantially (%f vs. %f)\n", name, r_pooled[i], r_average[i])
+    }
+  }
+}
+
+// Aggregate metrics over repeats by first averaging over folds within each
+// repeat and afterwards over repeats. Returns the metrics of each repeat, the
+// overall mean, the standard deviation between repeats, the average
+// standard deviation of folds within repeats, and the pooled metrics averaged
+// over repeats.
+func eval_repeated_cv_metrics(config Config, repeats []Repeat, names []string) ([][]float64, []float64, []float64, []float64, []float64) {
+  r_repeats := make([][]float64, len(repeats))
+  r_pooled  := make([][]float64, len(repeats))
+  r_within  := make([][]float64, len(repeats))
+  for k, repeat := range repeats {
+    pooled, folds := eval_cv_metrics(config, repeat, names)
+    mean, sd := summarize_metrics(names, folds)
+    r_repeats[k] = mean
+    r_pooled [k] = pooled
+    // variance within repeat
+    r_within [k] = make([]float64, len(names))
+    for i := range names {
+      r_within[k][i] = sd[i]*sd[i]
+    }
+  }
+  mean, sd_between := summarize_metrics(names, r_repeats)
+  pooled, _        := summarize_metrics(names, r_pooled)
+  var_within, _    := summarize_metrics(names, r_within)
+  sd_within := make([]float64, len(names))
+  for i := range names {
+    sd_within[i] = math.Sqrt(var_within[i])
+  }
+  check_cv_divergence(names, pooled, mean)
+  return r_repeats, mean, sd_between, sd_within, pooled
+}
+
+/* -------------------------------------------------------------------------- */
+
+func print_cv_row(config Config, name string, r []float64) {
+  fmt.Print(name)
+  for i := range r {
+    fmt.Printf(" %s", format_value(config, r[i]))
+  }
+  fmt.Println()
+}
+
+func classifier_performance_cv(config Config, filename, target string) {
+  repeats := import_folds(config, filename)
+  names   := []string{target}
+  if target == "summary" {
+    names = summary_metrics(config, true)
+  }
+  var r_pooled, r_average []float64
+  if config.RepeatColumn == "" {
+    pooled, folds := eval_cv_metrics(config, repeats[0], names)
+    mean, sd := summarize_metrics(names, folds)
+    check_cv_divergence(names, pooled, mean)
+    if target == "summary" {
+      if config.PrintHeader {
+        fmt.Printf("%s %s\n", config.FoldColumn, strings.Join(names, " "))
+      }
+      for k := range folds {
+        print_cv_row(config, repeats[0].Folds[k].Name, folds[k])
+      }
+      print_cv_row(config, "mean"  , mean)
+      print_cv_row(config, "sd"    , sd)
+      print_cv_row(config, "se"    , standard_error(sd, len(folds)))
+      print_cv_row(config, "pooled", pooled)
+      return
+    }
+    r_pooled, r_average = pooled, mean
+  } else {
+    r_repeats, mean, sd_between, sd_within, pooled := eval_repeated_cv_metrics(config, repeats, names)
+    if target == "summary" {
+      if config.PrintHeader {
+        fmt.Printf("%s %s\n", config.RepeatColumn, strings.Join(names, " "))
+      }
+      for k := range r_repeats {
+        print_cv_row(config, repeats[k].Name, r_repeats[k])
+      }
+      print_cv_row(config, "mean"      , mean)
+      print_cv_row(config, "sd_between", sd_between)
+      print_cv_row(config, "sd_within" , sd_within)
+      print_cv_row(config, "se"        , standard_error(sd_between, len(repeats)))
+      print_cv_row(config, "pooled"    , pooled)
+      return
+    }
+    r_pooled, r_average = pooled, mean
+  }
+  switch config.CvAggregation {
+  case "":
+    // report both, so that differences are visible in a single run
+    if config.PrintHeader {
+      fmt.Printf("aggregation %s\n", target)
+    }
+    print_cv_row(config, "pooled" , r_pooled)
+    print_cv_row(config, "average", r_average)
+  case "pooled":
+    fmt.Println(r_pooled[0])
+  case "average":
+    fmt.Println(r_average[0])
+  default:
+    log.Fatalf("invalid cv aggregation: %s", config.CvAggregation)
+  }
+}
@@ -0,0 +1,133 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "encoding/json"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Version of the diagnostic report schema, which must be increased whenever
+// the schema changes
+const diagnostic_report_version = 1
+
+type DiagnosticTable struct {
+  Tp int `json:"tp"`
+  Fp int `json:"fp"`
+  Fn int `json:"fn"`
+  Tn int `json:"tn"`
+}
+
+type DiagnosticEstimate struct {
+  Name        string    `json:"name"`
+  Numerator   *int      `json:"numerator,omitempty"`
+  Denominator *int      `json:"denominator,omitempty"`
+  Value       JSONFloat `json:"value"`
+  Lower       JSONFloat `json:"lower"`
+  Upper       JSONFloat `json:"upper"`
+}
+
+type DiagnosticReport struct {
+  Version    int                  `json:"version"`
+  Threshold  JSONFloat            `json:"threshold"`
+  Confidence float64              `json:"confidence"`
+  Table      DiagnosticTable      `json:"table"`
+  Measures   []DiagnosticEstimate `json:"measures"`
+}
+
+/* -------------------------------------------------------------------------- */
+
+func export_diagnostic_report_text(writer io.Writer, report DiagnosticReport) {
+  t := report.Table
+  fmt.Fprintf(writer, "threshold: %f\n", float64(report.Threshold))
+  fmt.Fprintln(writer)
+  fmt.Fprintf(writer, "%-10s %10s %10s %10s\n", "", "positive", "negative", "total")
+  fmt.Fprintf(writer, "%-10s %10d %10d %10d\n", "test+", t.Tp, t.Fp, t.Tp + t.Fp)
+  fmt.Fprintf(writer, "%-10s %10d %10d %10d\n", "test-", t.Fn, t.Tn, t.Fn + t.Tn)
+  fmt.Fprintf(writer, "%-10s %10d %10d %10d\n", "total", t.Tp + t.Fn, t.Fp + t.Tn, t.Tp + t.Fp + t.Fn + t.Tn)
+  fmt.Fprintln(writer)
+  fmt.Fprintf(writer, "measure counts estimate lower upper (%g%% CI)\n", 100.0*report.Confidence)
+  for _, m := range report.Measures {
+    counts := "-"
+    if m.Denominator != nil {
+      counts = fmt.Sprintf("%d/%d", *m.Numerator, *m.Denominator)
+    }
+    fmt.Fprintf(writer, "%s %s %f %f %f\n", m.Name, counts, float64(m.Value), float64(m.Lower), float64(m.Upper))
+  }
+}
+
+// Diagnostic accuracy summary as required for STARD-compliant reports: the
+// 2x2 table at a threshold, and sensitivity, specificity, predictive values,
+// prevalence, and likelihood ratios with exact confidence intervals
+func classifier_performance_diagnostic_report(config Config, filename string) {
+  if config.StreamBins > 0 {
+    log.Fatal("diagnostic reports are not supported in streaming mode")
+  }
+  values, labels := import_predictions_cached(config, filename)
+  perf, err := EvalPerformance(append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    log.Fatal(err)
+  }
+  t := config.Threshold
+  if math.IsNaN(t) {
+    roc := Roc(perf)
+    t = roc.Tr[OptimumRoc(roc.Tr, roc.X, roc.Y)]
+    PrintStderr(config, 1, "Using optimal threshold %f\n", t)
+  }
+  c, err := EvalConfusionMatrix(values, labels, t); if err != nil {
+    log.Fatal(err)
+  }
+  report := DiagnosticReport{
+    Version   : diagnostic_report_version,
+    Threshold : JSONFloat(t),
+    Confidence: config.Confidence,
+    Table     : DiagnosticTable{Tp: c.Tp, Fp: c.Fp, Fn: c.Fn, Tn: c.Tn} }
+  for _, m := range DiagnosticAccuracy(c, config.Confidence) {
+    e := DiagnosticEstimate{
+      Name : m.Name,
+      Value: JSONFloat(m.Value),
+      Lower: JSONFloat(m.Lower),
+      Upper: JSONFloat(m.Upper) }
+    // counts are only defined for proportions
+    if m.Denominator > 0 {
+      e.Numerator, e.Denominator = &m.Numerator, &m.Denominator
+    }
+    report.Measures = append(report.Measures, e)
+  }
+  writer := bufio.NewWriter(os.Stdout)
+  defer writer.Flush()
+  switch config.ReportFormat {
+  case "", "json":
+    encoder := json.NewEncoder(writer)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(report); err != nil {
+      log.Fatal(err)
+    }
+  case "text":
+    export_diagnostic_report_text(writer, report)
+  default:
+    log.Fatalf("invalid report format: %s", config.ReportFormat)
+  }
+}
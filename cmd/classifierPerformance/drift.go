@@ -0,0 +1,139 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "sort"
+import   "strconv"
+import   "time"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Formats accepted in the time column. Numbers are interpreted as seconds
+// since the Unix epoch.
+var time_formats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+func parse_time(s string) (time.Time, error) {
+  for _, format := range time_formats {
+    if t, err := time.Parse(format, s); err == nil {
+      return t, nil
+    }
+  }
+  if v, err := strconv.ParseFloat(s, 64); err == nil {
+    return time.Unix(int64(v), 0).UTC(), nil
+  }
+  return time.Time{}, fmt.Errorf("invalid time `%s'", s)
+}
+
+// Start of the time window containing t. Windows are calendar days, weeks
+// (starting on Monday), months or years, or intervals of a fixed duration
+// (e.g. 12h) counted from the Unix epoch.
+func window_start(t time.Time, window string) (time.Time, error) {
+  switch window {
+  case "day", "daily":
+    return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+  case "week", "weekly":
+    d := (int(t.Weekday()) + 6) % 7
+    return time.Date(t.Year(), t.Month(), t.Day()-d, 0, 0, 0, 0, t.Location()), nil
+  case "month", "monthly":
+    return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), nil
+  case "year", "yearly":
+    return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location()), nil
+  }
+  d, err := time.ParseDuration(window); if err != nil || d <= 0 {
+    return time.Time{}, fmt.Errorf("invalid window `%s'", window)
+  }
+  return t.Truncate(d), nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Compute metrics, prevalence and the population stability index relative to
+// the first window for each time window
+func classifier_performance_drift(config Config, filename string) {
+  if config.TimeColumn == "" {
+    log.Fatal("no time column specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("drift monitoring is not supported in streaming mode")
+  }
+  window := config.Window
+  if window == "" {
+    window = "month"
+  }
+  table := import_table(config, filename, config.TimeColumn)
+  keys  := make([]string, len(table.Values))
+  start := make(map[string]time.Time)
+  for i, s := range table.Columns[config.TimeColumn] {
+    t, err := parse_time(s); if err != nil {
+      log.Fatal(err)
+    }
+    w, err := window_start(t, window); if err != nil {
+      log.Fatal(err)
+    }
+    keys[i] = w.Format(time.RFC3339)
+    start[keys[i]] = w
+  }
+  names, values, labels := GroupPredictions(table.Values, table.Labels, keys)
+  index := make([]int, len(names))
+  for k := range index {
+    index[k] = k
+  }
+  sort.Slice(index, func(i, j int) bool { return start[names[index[i]]].Before(start[names[index[j]]]) })
+
+  metrics := summary_metrics(config, true)
+  if config.PrintHeader {
+    fmt.Print("window n prevalence")
+    for _, name := range metrics {
+      fmt.Printf(" %s", name)
+    }
+    fmt.Println(" psi")
+  }
+  reference := values[index[0]]
+  for _, k := range index {
+    // compute psi before evaluating performance, which sorts the predictions
+    _, psi, err := PopulationStability(reference, values[k], config.Bins); if err != nil {
+      log.Fatal(err)
+    }
+    perf, err := eval_performance(config, values[k], labels[k]); if err != nil {
+      log.Fatal(err)
+    }
+    // metrics of windows with a single class may be undefined
+    data := new_metrics(config, perf, values[k], labels[k])
+    r    := make([]float64, len(metrics))
+    for i, name := range metrics {
+      if x, err := data.Eval(name); err != nil {
+        fmt.Fprintf(os.Stderr, "notice: %s of window `%s' is undefined: %v\n", name, names[k], err)
+        r[i] = math.NaN()
+      } else {
+        r[i] = x[0]
+      }
+    }
+    fmt.Printf("%s %d %f", names[k], len(values[k]), float64(perf.P)/float64(perf.P + perf.N))
+    for i := range metrics {
+      fmt.Printf(" %s", format_group_value(r[i]))
+    }
+    fmt.Printf(" %f\n", psi)
+  }
+}
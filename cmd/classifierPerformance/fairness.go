@@ -0,0 +1,528 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+// Selection rates below this fraction of the reference group's rate indicate
+// adverse impact (four-fifths rule)
+const disparate_impact_threshold = 0.8
+
+// Default minimum size of intersectional groups
+const min_intersection_size = 10
+
+// Number of true positive rates tested when searching group thresholds
+const group_threshold_grid = 1001
+
+// Number of true and false positive rates tested when searching group
+// thresholds for equalized odds, which uses a two dimensional grid
+const group_threshold_grid_odds = 101
+
+var fairness_targets = []string{
+  "fairness", "fairness-roc", "fairness-precision-recall", "fairness-calibration", "fairness-ece", "fairness-report",
+  "demographic-parity", "disparate-impact", "equalized-odds", "subgroup-auc", "optimal-group-thresholds"}
+
+func is_fairness_target(target string) bool {
+  for _, t := range fairness_targets {
+    if t == target {
+      return true
+    }
+  }
+  return false
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Metrics of groups with only positives or only negatives may be undefined,
+// which are reported as NA
+func format_group_value(v float64) string {
+  if math.IsNaN(v) {
+    return "NA"
+  }
+  return fmt.Sprintf("%f", v)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Groups with less than --min-group-size predictions are either suppressed
+// or merged into a single group called `other'. Without a minimum group size,
+// only intersectional groups with less than min_intersection_size
+// predictions are suppressed.
+func filter_groups(config Config, names []string, values [][]float64, labels [][]int) ([]string, [][]float64, [][]int) {
+  n := config.MinGroupSize
+  if n < 0 {
+    n = 0
+    if strings.Contains(config.GroupColumn, ",") {
+      n = min_intersection_size
+    }
+  }
+  r_names  := []string{}
+  r_values := [][]float64{}
+  r_labels := [][]int{}
+  o_values := []float64{}
+  o_labels := []int{}
+  for k := range names {
+    if len(values[k]) >= n {
+      r_names  = append(r_names , names [k])
+      r_values = append(r_values, values[k])
+      r_labels = append(r_labels, labels[k])
+      continue
+    }
+    switch config.SmallGroups {
+    case "", "suppress":
+      fmt.Fprintf(os.Stderr, "notice: suppressing group `%s' with only %d predictions\n", names[k], len(values[k]))
+    case "merge":
+      fmt.Fprintf(os.Stderr, "notice: merging group `%s' with only %d predictions into group `other'\n", names[k], len(values[k]))
+      o_values = append(o_values, values[k]...)
+      o_labels = append(o_labels, labels[k]...)
+    default:
+      log.Fatalf("invalid small groups policy: %s", config.SmallGroups)
+    }
+  }
+  if len(o_values) > 0 {
+    if len(o_values) < n {
+      fmt.Fprintf(os.Stderr, "notice: suppressing group `other' with only %d predictions\n", len(o_values))
+    } else {
+      r_names  = append(r_names , "other")
+      r_values = append(r_values, o_values)
+      r_labels = append(r_labels, o_labels)
+    }
+  }
+  if len(r_names) == 0 {
+    log.Fatal("no groups left after suppressing small groups")
+  }
+  return r_names, r_values, r_labels
+}
+
+// Compute performance separately for each group in the group column. The
+// performance of the pooled predictions is returned as well. If multiple
+// group columns are given, intersectional groups are formed. Small groups
+// are handled by filter_groups.
+func import_groups(config Config, filename string) (Fold, []Fold) {
+  if config.GroupColumn == "" {
+    log.Fatal("no group column specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("groups are not supported in streaming mode")
+  }
+  table := import_table(config, filename, config.GroupColumn)
+  names, values, labels := GroupPredictions(table.Values, table.Labels, table.Columns[config.GroupColumn])
+  names, values, labels  = filter_groups(config, names, values, labels)
+  groups := make([]Fold, len(names))
+  for k := range names {
+    groups[k] = new_fold(config, names[k], values[k], labels[k])
+  }
+  PrintStderr(config, 1, "Found %d groups\n", len(groups))
+  return new_fold(config, "pooled", table.Values, table.Labels), groups
+}
+
+// Predictions of all groups
+func group_predictions(groups []Fold) ([][]float64, [][]int) {
+  values := make([][]float64, len(groups))
+  labels := make([][]int,     len(groups))
+  for k, group := range groups {
+    values[k] = group.Values
+    labels[k] = group.Labels
+  }
+  return values, labels
+}
+
+// Threshold shared by all groups, which is either given by the user or
+// the optimal ROC threshold of the pooled predictions
+func fairness_threshold(config Config, pooled Fold) float64 {
+  if !math.IsNaN(config.Threshold) {
+    return config.Threshold
+  }
+  roc := Roc(pooled.Perf)
+  t   := roc.Tr[OptimumRoc(roc.Tr, roc.X, roc.Y)]
+  PrintStderr(config, 1, "Using optimal threshold %f of pooled predictions\n", t)
+  return t
+}
+
+/* -------------------------------------------------------------------------- */
+
+func fairness_summary(config Config, pooled Fold, groups []Fold) {
+  t := fairness_threshold(config, pooled)
+  if config.PrintHeader {
+    fmt.Printf("%s n positives negatives roc-auc precision-recall-auc threshold tpr fpr precision accuracy\n", config.GroupColumn)
+  }
+  for _, group := range append(groups, pooled) {
+    metrics := new_metrics(config, group.Perf, group.Values, group.Labels)
+    r, err  := metrics.Eval("roc-auc", "precision-recall-auc"); if err != nil {
+      fmt.Fprintf(os.Stderr, "notice: metrics of group `%s' are undefined: %v\n", group.Name, err)
+      r = []float64{math.NaN(), math.NaN()}
+    }
+    c := group.Perf.At(t)
+    fmt.Printf("%s %d %d %d", group.Name, len(group.Values), group.Perf.P, group.Perf.N)
+    for _, v := range []float64{r[0], r[1], t, c.TPR(), c.FPR(), c.Precision(), c.Accuracy()} {
+      fmt.Printf(" %s", format_group_value(v))
+    }
+    fmt.Println()
+  }
+}
+
+// Print curves of all groups in long format, i.e. with the group name in the
+// first column
+func fairness_curves(config Config, groups []Fold, name string) {
+  metric, _ := LookupMetric(name)
+  header    := config.PrintHeader
+  for _, group := range groups {
+    table, err := metric.Eval(new_metrics(config, group.Perf, group.Values, group.Labels)); if err != nil {
+      fmt.Fprintf(os.Stderr, "notice: skipping group `%s': %v\n", group.Name, err)
+      continue
+    }
+    if !config.PrintThresholds {
+      table = drop_thresholds(table)
+    }
+    if header {
+      fmt.Printf("%s %s\n", config.GroupColumn, strings.Join(table.Names, " "))
+      header = false
+    }
+    for j := 0; j < table.Rows(); j++ {
+      fmt.Print(group.Name)
+      for k := range table.Columns {
+        fmt.Printf(" %s", format_group_value(table.Columns[k][j]))
+      }
+      fmt.Println()
+    }
+  }
+}
+
+// Expected calibration error of each group
+func fairness_ece(config Config, groups []Fold) {
+  if config.PrintHeader {
+    fmt.Printf("%s n ece\n", config.GroupColumn)
+  }
+  for _, group := range groups {
+    r, err := new_metrics(config, group.Perf, group.Values, group.Labels).ECE(); if err != nil {
+      fmt.Fprintf(os.Stderr, "notice: calibration of group `%s' is undefined: %v\n", group.Name, err)
+      r = math.NaN()
+    }
+    fmt.Printf("%s %d %s\n", group.Name, len(group.Values), format_group_value(r))
+  }
+}
+
+// Maximum pairwise difference and minimum pairwise ratio of rates across
+// groups, where undefined rates are ignored
+func fairness_gap(x []float64) (float64, float64) {
+  min := math.Inf( 1)
+  max := math.Inf(-1)
+  for _, v := range x {
+    if !math.IsNaN(v) {
+      min = math.Min(min, v)
+      max = math.Max(max, v)
+    }
+  }
+  if min > max {
+    return math.NaN(), math.NaN()
+  }
+  return max-min, min/max
+}
+
+// Positive prediction rate of each group and the pairwise maximum difference
+// and minimum ratio across groups
+func eval_demographic_parity(groups []Fold, t float64) ([]float64, float64, float64) {
+  x := make([]float64, len(groups))
+  for k, group := range groups {
+    x[k] = group.Perf.At(t).PositiveRate()
+  }
+  difference, ratio := fairness_gap(x)
+  return x, difference, ratio
+}
+
+func fairness_demographic_parity(config Config, pooled Fold, groups []Fold) {
+  x, difference, ratio := eval_demographic_parity(groups, fairness_threshold(config, pooled))
+  if config.PrintHeader {
+    fmt.Printf("%s n positive-rate\n", config.GroupColumn)
+  }
+  for k, group := range groups {
+    fmt.Printf("%s %d %f\n", group.Name, len(group.Values), x[k])
+  }
+  fmt.Printf("difference %d %f\n", len(pooled.Values), difference)
+  fmt.Printf("ratio %d %f\n", len(pooled.Values), ratio)
+}
+
+// Selection rate of each group relative to the selection rate of a reference
+// group, which by default is the group with the largest selection rate
+func fairness_disparate_impact(config Config, pooled Fold, groups []Fold) {
+  x, _, _ := eval_demographic_parity(groups, fairness_threshold(config, pooled))
+  r := -1
+  for k, group := range groups {
+    if config.ReferenceGroup == "" {
+      if r == -1 || x[k] > x[r] {
+        r = k
+      }
+    } else
+    if group.Name == config.ReferenceGroup {
+      r = k
+    }
+  }
+  if r == -1 {
+    log.Fatalf("reference group `%s' not found", config.ReferenceGroup)
+  }
+  PrintStderr(config, 1, "Using reference group `%s'\n", groups[r].Name)
+  if x[r] == 0.0 {
+    // ratios are infinite for groups with selections and undefined otherwise
+    fmt.Fprintf(os.Stderr, "notice: reference group `%s' has no selections, ratios are reported as NA or +Inf\n", groups[r].Name)
+  }
+  if config.PrintHeader {
+    fmt.Printf("%s n positive-rate ratio adverse-impact\n", config.GroupColumn)
+  }
+  for k, group := range groups {
+    ratio   := x[k]/x[r]
+    adverse := "0"
+    if math.IsNaN(ratio) {
+      adverse = "NA"
+    } else
+    if ratio < disparate_impact_threshold {
+      adverse = "1"
+    }
+    fmt.Printf("%s %d %f %s %s\n", group.Name, len(group.Values), x[k], format_group_value(ratio), adverse)
+  }
+}
+
+// True and false positive rates of each group at threshold t, followed by
+// the equal opportunity gap (maximum difference of true positive rates) and
+// the equalized odds gap (maximum of true and false positive rate differences)
+func fairness_odds(values [][]float64, labels [][]int, t float64) ([]float64, error) {
+  tpr := make([]float64, len(values))
+  fpr := make([]float64, len(values))
+  r   := []float64{}
+  for k := range values {
+    c, err := EvalConfusionMatrix(values[k], labels[k], t); if err != nil {
+      return nil, err
+    }
+    tpr[k] = c.TPR()
+    fpr[k] = c.FPR()
+    r = append(r, tpr[k], fpr[k])
+  }
+  tpr_gap, _ := fairness_gap(tpr)
+  fpr_gap, _ := fairness_gap(fpr)
+  return append(r, tpr_gap, math.Max(tpr_gap, fpr_gap)), nil
+}
+
+// Evaluate fairness_odds at threshold t with bootstrap confidence intervals
+// if requested
+func eval_fairness_odds(config Config, groups []Fold, t float64) ([]float64, []float64, []float64) {
+  values, labels := group_predictions(groups)
+  r, err := fairness_odds(values, labels, t); if err != nil {
+    log.Fatal(err)
+  }
+  var lower, upper []float64
+  if config.Bootstrap > 0 {
+    lower, upper = bootstrap_groups(config, groups, len(r), func(values [][]float64, labels [][]int) ([]float64, error) {
+      return fairness_odds(values, labels, t)
+    })
+  }
+  return r, lower, upper
+}
+
+func fairness_equalized_odds(config Config, pooled Fold, groups []Fold) {
+  names := []string{}
+  for _, group := range groups {
+    names = append(names, "tpr:" + group.Name, "fpr:" + group.Name)
+  }
+  names = append(names, "equal-opportunity", "equalized-odds")
+  r, lower, upper := eval_fairness_odds(config, groups, fairness_threshold(config, pooled))
+  if config.PrintHeader {
+    if config.Bootstrap > 0 {
+      fmt.Println("metric value lower upper")
+    } else {
+      fmt.Println("metric value")
+    }
+  }
+  for i, name := range names {
+    if config.Bootstrap > 0 {
+      fmt.Printf("%s %s %s %s\n", name, format_group_value(r[i]), format_group_value(lower[i]), format_group_value(upper[i]))
+    } else {
+      fmt.Printf("%s %s\n", name, format_group_value(r[i]))
+    }
+  }
+}
+
+// Concatenate predictions of all groups and return an indicator for each
+// group
+func fairness_concat(values [][]float64, labels [][]int) ([]float64, []int, [][]bool) {
+  v := []float64{}
+  l := []int{}
+  for k := range values {
+    v = append(v, values[k]...)
+    l = append(l, labels[k]...)
+  }
+  subgroups := make([][]bool, len(values))
+  for k, offset := 0, 0; k < len(values); k++ {
+    subgroups[k] = make([]bool, len(v))
+    for i := range values[k] {
+      subgroups[k][offset+i] = true
+    }
+    offset += len(values[k])
+  }
+  return v, l, subgroups
+}
+
+// ROC-AUC of each group and the difference to the ROC-AUC of all predictions
+// with confidence intervals computed either with the method of DeLong or by
+// bootstrapping
+func eval_subgroup_auc(config Config, groups []Fold) ([]float64, []float64, []float64, []float64) {
+  v, l, subgroups := fairness_concat(group_predictions(groups))
+  auc   := make([]float64, len(groups))
+  gap   := make([]float64, len(groups))
+  lower := make([]float64, len(groups))
+  upper := make([]float64, len(groups))
+  z     := math.Sqrt2*math.Erfinv(config.Confidence)
+  for k, group := range groups {
+    if r, err := AUCRankSum(group.Values, group.Labels); err != nil {
+      log.Fatalf("group `%s': %v", group.Name, err)
+    } else {
+      auc[k] = r
+    }
+    if d, variance, err := SubgroupAUCDifference(v, l, subgroups[k]); err != nil {
+      log.Fatalf("group `%s': %v", group.Name, err)
+    } else {
+      gap  [k] = d
+      lower[k] = d - z*math.Sqrt(variance)
+      upper[k] = d + z*math.Sqrt(variance)
+    }
+  }
+  if config.Bootstrap > 0 {
+    lower, upper = bootstrap_groups(config, groups, len(groups), func(values [][]float64, labels [][]int) ([]float64, error) {
+      v, l, subgroups := fairness_concat(values, labels)
+      r := make([]float64, len(groups))
+      for k := range groups {
+        if d, _, err := SubgroupAUCDifference(v, l, subgroups[k]); err != nil {
+          return nil, err
+        } else {
+          r[k] = d
+        }
+      }
+      return r, nil
+    })
+  }
+  return auc, gap, lower, upper
+}
+
+func fairness_subgroup_auc(config Config, groups []Fold) {
+  auc, gap, lower, upper := eval_subgroup_auc(config, groups)
+  if config.PrintHeader {
+    fmt.Printf("%s n roc-auc gap gap_lower gap_upper\n", config.GroupColumn)
+  }
+  for k, group := range groups {
+    fmt.Printf("%s %d", group.Name, len(group.Values))
+    for _, v := range []float64{auc[k], gap[k], lower[k], upper[k]} {
+      fmt.Printf(" %s", format_group_value(v))
+    }
+    fmt.Println()
+  }
+}
+
+// Search thresholds for each group that satisfy a fairness criterion while
+// maximizing accuracy
+func fairness_optimal_group_thresholds(config Config, groups []Fold) {
+  var criterion FairnessCriterion
+  switch config.FairnessCriterion {
+  case "", "equalized-odds":
+    criterion = EqualizedOdds
+  case "equal-opportunity":
+    criterion = EqualOpportunity
+  default:
+    log.Fatalf("invalid fairness criterion: %s", config.FairnessCriterion)
+  }
+  n := group_threshold_grid
+  if criterion == EqualizedOdds {
+    n = group_threshold_grid_odds
+  }
+  // thresholds are undefined for groups with a single class, which are
+  // excluded from the search
+  perfs := []Performance{}
+  index := make([]int, len(groups))
+  for k, group := range groups {
+    if group.Perf.P == 0 || group.Perf.N == 0 {
+      fmt.Fprintf(os.Stderr, "notice: threshold of group `%s' is undefined: group contains only a single class\n", group.Name)
+      index[k] = -1
+    } else {
+      index[k] = len(perfs)
+      perfs    = append(perfs, group.Perf)
+    }
+  }
+  if len(perfs) == 0 {
+    log.Fatal("no group contains both classes")
+  }
+  thresholds, gap, err := OptimalGroupThresholds(perfs, criterion, config.FairnessTolerance, n); if err != nil {
+    log.Fatal(err)
+  }
+  if gap > config.FairnessTolerance {
+    fmt.Fprintf(os.Stderr, "warning: no thresholds satisfy --fairness-tolerance %f, using thresholds with the smallest fairness gap %f\n", config.FairnessTolerance, gap)
+  }
+  if config.PrintHeader {
+    fmt.Printf("%s n threshold tpr fpr positive-rate accuracy\n", config.GroupColumn)
+  }
+  total := ConfusionMatrix{}
+  for k, group := range groups {
+    if index[k] < 0 {
+      fmt.Printf("%s %d NA NA NA NA NA\n", group.Name, len(group.Values))
+      continue
+    }
+    t := thresholds[index[k]]
+    c := group.Perf.At(t)
+    fmt.Printf("%s %d %f %f %f %f %f\n", group.Name, len(group.Values), t, c.TPR(), c.FPR(), c.PositiveRate(), c.Accuracy())
+    total.Tp += c.Tp; total.Fp += c.Fp
+    total.Tn += c.Tn; total.Fn += c.Fn
+  }
+  // metrics of all groups with defined thresholds, each classified with the
+  // threshold of its group
+  fmt.Printf("pooled %d NA %f %f %f %f\n", total.Tp + total.Fp + total.Tn + total.Fn, total.TPR(), total.FPR(), total.PositiveRate(), total.Accuracy())
+}
+
+/* -------------------------------------------------------------------------- */
+
+func classifier_performance_fairness(config Config, filename, target string) {
+  pooled, groups := import_groups(config, filename)
+  switch target {
+  case "fairness":
+    fairness_summary(config, pooled, groups)
+  case "fairness-roc":
+    fairness_curves(config, groups, "roc")
+  case "fairness-precision-recall":
+    fairness_curves(config, groups, "precision-recall")
+  case "demographic-parity":
+    fairness_demographic_parity(config, pooled, groups)
+  case "disparate-impact":
+    fairness_disparate_impact(config, pooled, groups)
+  case "equalized-odds":
+    fairness_equalized_odds(config, pooled, groups)
+  case "subgroup-auc":
+    fairness_subgroup_auc(config, groups)
+  case "fairness-calibration":
+    fairness_curves(config, groups, "calibration")
+  case "fairness-ece":
+    fairness_ece(config, groups)
+  case "optimal-group-thresholds":
+    fairness_optimal_group_thresholds(config, groups)
+  case "fairness-report":
+    fairness_report(config, pooled, groups)
+  default:
+    log.Fatalf("invalid target: %s", target)
+  }
+}
@@ -0,0 +1,248 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "encoding/json"
+import   "fmt"
+import   "html"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Version of the fairness report schema, which must be increased whenever
+// the schema changes
+const fairness_report_version = 1
+
+type FairnessInterval struct {
+  Value JSONFloat  `json:"value"`
+  Lower *JSONFloat `json:"lower,omitempty"`
+  Upper *JSONFloat `json:"upper,omitempty"`
+}
+
+type FairnessGroup struct {
+  Name               string           `json:"name"`
+  N                  int              `json:"n"`
+  Positives          int              `json:"positives"`
+  Negatives          int              `json:"negatives"`
+  RocAUC             JSONFloat        `json:"roc_auc"`
+  PrecisionRecallAUC JSONFloat        `json:"precision_recall_auc"`
+  RocAUCGap          FairnessInterval `json:"roc_auc_gap"`
+  TPR                FairnessInterval `json:"tpr"`
+  FPR                FairnessInterval `json:"fpr"`
+  Precision          JSONFloat        `json:"precision"`
+  Accuracy           JSONFloat        `json:"accuracy"`
+  PositiveRate       JSONFloat        `json:"positive_rate"`
+  ECE                *JSONFloat       `json:"ece,omitempty"`
+  Roc                Curve            `json:"roc"`
+  PrecisionRecall    Curve            `json:"precision_recall"`
+  Calibration        *Curve           `json:"calibration,omitempty"`
+}
+
+type FairnessReport struct {
+  Version            int              `json:"version"`
+  GroupColumn        string           `json:"group_column"`
+  Threshold          JSONFloat        `json:"threshold"`
+  Confidence         float64          `json:"confidence"`
+  Bootstrap          int              `json:"bootstrap"`
+  Groups             []FairnessGroup  `json:"groups"`
+  ParityDifference   JSONFloat        `json:"demographic_parity_difference"`
+  ParityRatio        JSONFloat        `json:"demographic_parity_ratio"`
+  EqualOpportunity   FairnessInterval `json:"equal_opportunity"`
+  EqualizedOdds      FairnessInterval `json:"equalized_odds"`
+}
+
+func new_fairness_interval(value float64, lower, upper []float64, i int) FairnessInterval {
+  r := FairnessInterval{Value: JSONFloat(value)}
+  if lower != nil {
+    l := JSONFloat(lower[i])
+    u := JSONFloat(upper[i])
+    r.Lower = &l
+    r.Upper = &u
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+func eval_fairness_report(config Config, pooled Fold, groups []Fold) FairnessReport {
+  t := fairness_threshold(config, pooled)
+  r := FairnessReport{
+    Version    : fairness_report_version,
+    GroupColumn: config.GroupColumn,
+    Threshold  : JSONFloat(t),
+    Confidence : config.Confidence,
+    Bootstrap  : config.Bootstrap }
+  // parity metrics
+  rates, difference, ratio := eval_demographic_parity(groups, t)
+  odds, odds_lower, odds_upper := eval_fairness_odds(config, groups, t)
+  _, gap, gap_lower, gap_upper := eval_subgroup_auc(config, groups)
+  r.ParityDifference = JSONFloat(difference)
+  r.ParityRatio      = JSONFloat(ratio)
+  r.EqualOpportunity = new_fairness_interval(odds[2*len(groups)  ], odds_lower, odds_upper, 2*len(groups)  )
+  r.EqualizedOdds    = new_fairness_interval(odds[2*len(groups)+1], odds_lower, odds_upper, 2*len(groups)+1)
+  // metrics of each group
+  for k, group := range groups {
+    metrics := new_metrics(config, group.Perf, group.Values, group.Labels)
+    auc, err := metrics.Eval("roc-auc", "precision-recall-auc"); if err != nil {
+      // metrics are undefined for groups with a single class
+      fmt.Fprintf(os.Stderr, "notice: metrics of group `%s' are undefined: %v\n", group.Name, err)
+      auc = []float64{math.NaN(), math.NaN()}
+    }
+    c := group.Perf.At(t)
+    // calibration is only defined if predictions are probabilities
+    var ece *JSONFloat
+    var calibration *Curve
+    if reliability, err := metrics.Reliability(); err != nil {
+      PrintStderr(config, 1, "Skipping calibration of group `%s': %v\n", group.Name, err)
+    } else {
+      e := JSONFloat(reliability.ECE())
+      ece = &e
+      calibration = &Curve{X: reliability.Predicted, Y: reliability.Observed}
+    }
+    r.Groups = append(r.Groups, FairnessGroup{
+      Name              : group.Name,
+      N                 : len(group.Values),
+      Positives         : group.Perf.P,
+      Negatives         : group.Perf.N,
+      RocAUC            : JSONFloat(auc[0]),
+      PrecisionRecallAUC: JSONFloat(auc[1]),
+      RocAUCGap         : FairnessInterval{Value: JSONFloat(gap[k]), Lower: (*JSONFloat)(&gap_lower[k]), Upper: (*JSONFloat)(&gap_upper[k])},
+      TPR               : new_fairness_interval(odds[2*k  ], odds_lower, odds_upper, 2*k  ),
+      FPR               : new_fairness_interval(odds[2*k+1], odds_lower, odds_upper, 2*k+1),
+      Precision         : JSONFloat(c.Precision()),
+      Accuracy          : JSONFloat(c.Accuracy()),
+      PositiveRate      : JSONFloat(rates[k]),
+      ECE               : ece,
+      Roc               : metrics.Roc(),
+      PrecisionRecall   : metrics.PrecisionRecall(),
+      Calibration       : calibration })
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Undefined values of the HTML report are shown as NA
+func format_report_value(v JSONFloat) string {
+  if math.IsNaN(float64(v)) {
+    return "NA"
+  }
+  return fmt.Sprintf("%.4f", float64(v))
+}
+
+func (obj FairnessInterval) String() string {
+  if obj.Lower == nil {
+    return format_report_value(obj.Value)
+  }
+  return fmt.Sprintf("%s [%s, %s]", format_report_value(obj.Value), format_report_value(*obj.Lower), format_report_value(*obj.Upper))
+}
+
+func export_fairness_report_html(writer io.Writer, report FairnessReport) error {
+  fmt.Fprintln(writer, "<!DOCTYPE html>")
+  fmt.Fprintln(writer, "<html><head><meta charset=\"utf-8\"><title>Fairness report</title>")
+  fmt.Fprintln(writer, "<style>body{font-family:sans-serif} table{border-collapse:collapse} td,th{border:1px solid #ccc;padding:4px 8px;text-align:right}</style>")
+  fmt.Fprintln(writer, "</head><body>")
+  fmt.Fprintf (writer, "<h1>Fairness report: %s</h1>\n", html.EscapeString(report.GroupColumn))
+  fmt.Fprintf (writer, "<p>Threshold: %f, confidence level: %.2f</p>\n", float64(report.Threshold), report.Confidence)
+  fmt.Fprintln(writer, "<h2>Parity metrics</h2>")
+  fmt.Fprintln(writer, "<table>")
+  fmt.Fprintf (writer, "<tr><th>demographic parity difference</th><td>%s</td></tr>\n", format_report_value(report.ParityDifference))
+  fmt.Fprintf (writer, "<tr><th>demographic parity ratio</th><td>%s</td></tr>\n", format_report_value(report.ParityRatio))
+  fmt.Fprintf (writer, "<tr><th>equal opportunity</th><td>%s</td></tr>\n", report.EqualOpportunity)
+  fmt.Fprintf (writer, "<tr><th>equalized odds</th><td>%s</td></tr>\n", report.EqualizedOdds)
+  fmt.Fprintln(writer, "</table>")
+  fmt.Fprintln(writer, "<h2>Groups</h2>")
+  fmt.Fprintln(writer, "<table>")
+  fmt.Fprintln(writer, "<tr><th>group</th><th>n</th><th>positives</th><th>negatives</th><th>roc-auc</th><th>precision-recall-auc</th><th>roc-auc gap</th><th>tpr</th><th>fpr</th><th>precision</th><th>accuracy</th><th>positive rate</th><th>ece</th></tr>")
+  for _, g := range report.Groups {
+    ece := "-"
+    if g.ECE != nil {
+      ece = format_report_value(*g.ECE)
+    }
+    fmt.Fprintf(writer, "<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+      html.EscapeString(g.Name), g.N, g.Positives, g.Negatives, format_report_value(g.RocAUC), format_report_value(g.PrecisionRecallAUC),
+      g.RocAUCGap, g.TPR, g.FPR, format_report_value(g.Precision), format_report_value(g.Accuracy), format_report_value(g.PositiveRate), ece)
+  }
+  fmt.Fprintln(writer, "</table>")
+  fmt.Fprintln(writer, "<h2>Curves</h2>")
+  // curves of groups with a single class are undefined and not shown
+  names     := []string{}
+  roc       := []Curve{}
+  pr_names  := []string{}
+  pr        := []Curve{}
+  cal       := []Curve{}
+  for _, g := range report.Groups {
+    if g.Positives > 0 && g.Negatives > 0 {
+      names, roc = append(names, g.Name), append(roc, g.Roc)
+    }
+    if g.Positives > 0 {
+      pr_names, pr = append(pr_names, g.Name), append(pr, g.PrecisionRecall)
+    }
+    if g.Calibration != nil {
+      cal = append(cal, *g.Calibration)
+    }
+  }
+  if err := export_curve_svg(writer, roc, names, "ROC curve", "FPR", "TPR"); err != nil {
+    return err
+  }
+  if err := export_curve_svg(writer, pr, pr_names, "Precision-recall curve", "recall", "precision"); err != nil {
+    return err
+  }
+  if len(cal) == len(report.Groups) {
+    cal_names := make([]string, len(report.Groups))
+    for k, g := range report.Groups {
+      cal_names[k] = g.Name
+    }
+    if err := export_curve_svg(writer, cal, cal_names, "Calibration", "predicted probability", "observed frequency"); err != nil {
+      return err
+    }
+  }
+  fmt.Fprintln(writer, "</body></html>")
+  return nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Combine per-group metrics and curves with parity metrics in a single
+// report
+func fairness_report(config Config, pooled Fold, groups []Fold) {
+  report := eval_fairness_report(config, pooled, groups)
+  writer := bufio.NewWriter(os.Stdout)
+  defer writer.Flush()
+  switch config.ReportFormat {
+  case "", "json":
+    encoder := json.NewEncoder(writer)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(report); err != nil {
+      log.Fatal(err)
+    }
+  case "html":
+    if err := export_fairness_report_html(writer, report); err != nil {
+      log.Fatal(err)
+    }
+  default:
+    log.Fatalf("invalid report format: %s", config.ReportFormat)
+  }
+}
@@ -0,0 +1,98 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "testing"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+func test_equal_values(a, b []float64) bool {
+  if len(a) != len(b) {
+    return false
+  }
+  for i := range a {
+    if math.IsNaN(a[i]) != math.IsNaN(b[i]) || !math.IsNaN(a[i]) && math.Abs(a[i]-b[i]) > 1e-12 {
+      return false
+    }
+  }
+  return true
+}
+
+/* -------------------------------------------------------------------------- */
+
+func TestFairnessGap(t *testing.T) {
+  if d, r := fairness_gap([]float64{0.5, math.NaN(), 0.25, 0.4}); d != 0.25 || r != 0.5 {
+    t.Errorf("unexpected gap: %v %v", d, r)
+  }
+  if d, r := fairness_gap([]float64{math.NaN()}); !math.IsNaN(d) || !math.IsNaN(r) {
+    t.Errorf("unexpected gap: %v %v", d, r)
+  }
+}
+
+func TestDemographicParity(t *testing.T) {
+  groups := []Fold{
+    {Name: "a", Values: []float64{0.9, 0.8, 0.3, 0.2 }, Labels: []int{1, 0, 1, 0}},
+    {Name: "b", Values: []float64{0.7, 0.6, 0.55, 0.1}, Labels: []int{1, 1, 0, 0}},
+  }
+  for k := range groups {
+    perf, err := EvalPerformance(append([]float64{}, groups[k].Values...), append([]int{}, groups[k].Labels...)); if err != nil {
+      t.Fatal(err)
+    }
+    groups[k].Perf = perf
+  }
+  x, difference, ratio := eval_demographic_parity(groups, 0.5)
+  if !test_equal_values(x, []float64{0.5, 0.75}) || math.Abs(difference - 0.25) > 1e-12 || math.Abs(ratio - 2.0/3.0) > 1e-12 {
+    t.Errorf("unexpected demographic parity: %v %v %v", x, difference, ratio)
+  }
+}
+
+// Groups with a single class have undefined rates, which are ignored by the
+// gaps
+func TestFairnessOdds(t *testing.T) {
+  values := [][]float64{{0.9, 0.8, 0.3, 0.2}, {0.7, 0.6, 0.55, 0.1}, {0.9, 0.1}}
+  labels := [][]int    {{  1,   0,   1,   0}, {  1,   1,    0,   0}, {  1,   1}}
+  r, err := fairness_odds(values, labels, 0.5); if err != nil {
+    t.Fatal(err)
+  }
+  if e := []float64{0.5, 0.5, 1.0, 0.5, 0.5, math.NaN(), 0.5, 0.5}; !test_equal_values(r, e) {
+    t.Errorf("unexpected rates: %v instead of %v", r, e)
+  }
+  if _, err := fairness_odds([][]float64{{0.5}}, [][]int{{2}}, 0.5); err == nil {
+    t.Errorf("invalid label accepted")
+  }
+}
+
+func TestFilterGroups(t *testing.T) {
+  names  := []string   {"a", "b", "c"}
+  values := [][]float64{{0.1, 0.2, 0.3}, {0.4, 0.5}, {0.6, 0.7}}
+  labels := [][]int    {{  0,   1,   0}, {  1,   0}, {  1,   1}}
+  config := Config{MinGroupSize: 3}
+  if r, _, _ := filter_groups(config, names, values, labels); len(r) != 1 || r[0] != "a" {
+    t.Errorf("unexpected groups: %v", r)
+  }
+  config.SmallGroups = "merge"
+  r, r_values, r_labels := filter_groups(config, names, values, labels)
+  if len(r) != 2 || r[1] != "other" || !test_equal_values(r_values[1], []float64{0.4, 0.5, 0.6, 0.7}) || len(r_labels[1]) != 4 {
+    t.Errorf("unexpected groups: %v %v", r, r_values)
+  }
+}
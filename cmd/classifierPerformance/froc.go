@@ -0,0 +1,82 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "log"
+import   "math"
+import   "os"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Free-response ROC curve of candidate detections. Each row is a candidate
+// with its score, a label marking true lesion localizations, the image of
+// the candidate (--image-column), and the number of lesions in the image
+// (--lesion-column). Images without candidates are included with a single
+// row with score -Inf, which is never classified as positive.
+func classifier_performance_froc(config Config, filename string) {
+  if config.ImageColumn == "" || config.LesionColumn == "" {
+    log.Fatal("froc requires an image and a lesion column")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("froc is not supported in streaming mode")
+  }
+  table   := import_table(config, filename, config.ImageColumn, config.LesionColumn)
+  lesions := map[string]int{}
+  hits    := map[string]int{}
+  values  := []float64{}
+  labels  := []int{}
+  for i, image := range table.Columns[config.ImageColumn] {
+    s := table.Columns[config.LesionColumn][i]
+    n, err := strconv.Atoi(s); if err != nil || n < 0 {
+      log.Fatalf("invalid value `%s' in column `%s'", s, config.LesionColumn)
+    }
+    if m, ok := lesions[image]; ok && m != n {
+      log.Fatalf("inconsistent number of lesions for image `%s'", image)
+    }
+    lesions[image] = n
+    if math.IsInf(table.Values[i], -1) {
+      // placeholder for images without candidates
+      continue
+    }
+    hits[image] += table.Labels[i]
+    values = append(values, table.Values[i])
+    labels = append(labels, table.Labels[i])
+  }
+  n_lesions := 0
+  for image, n := range lesions {
+    if hits[image] > n {
+      log.Fatalf("image `%s' has more true localizations than lesions", image)
+    }
+    n_lesions += n
+  }
+  if len(values) == 0 || n_lesions == 0 {
+    log.Fatal("froc requires candidates and lesions")
+  }
+  PrintStderr(config, 1, "Found %d candidates and %d lesions in %d images\n", len(values), n_lesions, len(lesions))
+  perf, err := eval_performance(config, values, labels); if err != nil {
+    log.Fatal(err)
+  }
+  froc := Froc(perf, len(lesions), n_lesions)
+  export_table(config, os.Stdout, &perf, Table{
+    Names  : []string{"fppi", "llf", "threshold"},
+    Columns: [][]float64{froc.X, froc.Y, froc.Tr} })
+}
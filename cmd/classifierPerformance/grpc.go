@@ -0,0 +1,240 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "context"
+import   "errors"
+import   "io"
+import   "log"
+import   "net"
+import   "slices"
+
+import   "google.golang.org/grpc"
+import   "google.golang.org/grpc/codes"
+import   "google.golang.org/grpc/status"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+import   "github.com/pbenner/classifierPerformance/pkg/evaluationService"
+
+/* -------------------------------------------------------------------------- */
+
+// Implementation of the gRPC evaluation service defined in
+// pkg/evaluationService/evaluationService.proto
+type evaluation_server struct {
+  evaluationService.UnimplementedEvaluationServer
+  config   Config
+  sessions *Sessions
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Kind of metrics accepted by the Evaluate method
+const anyMetric MetricKind = -1
+
+/* -------------------------------------------------------------------------- */
+
+func grpc_labels(predictions *evaluationService.Predictions) ([]float64, []int, error) {
+  values := predictions.GetValues()
+  labels := make([]int, len(predictions.GetLabels()))
+  for i, l := range predictions.GetLabels() {
+    labels[i] = int(l)
+  }
+  if err := serve_check_predictions(values, labels); err != nil {
+    return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+  }
+  return values, labels, nil
+}
+
+// Options of a request, where zero values select the options of the server
+func grpc_config(config Config, request *evaluationService.EvaluateRequest) (Config, error) {
+  if request.Method != "" {
+    config.Method = request.Method
+  }
+  if request.Bins < 0 || request.Top < 0 || request.Fraction < 0.0 || request.Fraction > 1.0 {
+    return config, status.Error(codes.InvalidArgument, "invalid options")
+  }
+  if request.Bins > 0 {
+    config.Bins = int(request.Bins)
+  }
+  if request.Top > 0 {
+    config.Top = int(request.Top)
+  }
+  if request.Fraction > 0.0 {
+    config.Fraction = request.Fraction
+  }
+  if request.NormalizePrecision {
+    config.NormalizePrecision = true
+  }
+  return config, nil
+}
+
+// Evaluate the targets of a request on either the predictions of a session
+// or the predictions given with the request
+func (obj *evaluation_server) evaluate(request *evaluationService.EvaluateRequest, kind MetricKind) (Performance, []string, []Table, error) {
+  config, err := grpc_config(obj.config, request); if err != nil {
+    return Performance{}, nil, nil, err
+  }
+  var values []float64
+  var labels []int
+  var perf     Performance
+  if request.Session != "" {
+    session, err := obj.sessions.Get(request.Session, false); if err != nil {
+      return perf, nil, nil, status.Error(codes.InvalidArgument, err.Error())
+    }
+    if session == nil {
+      return perf, nil, nil, status.Errorf(codes.NotFound, "session `%s' not found", request.Session)
+    }
+    perf, err = session.Performance()
+  } else {
+    values, labels, err = grpc_labels(request.Predictions); if err != nil {
+      return perf, nil, nil, err
+    }
+    if len(values) == 0 {
+      return perf, nil, nil, status.Error(codes.InvalidArgument, "no predictions given")
+    }
+    perf, err = eval_performance(config, append([]float64{}, values...), append([]int{}, labels...))
+  }
+  if err != nil {
+    return perf, nil, nil, status.Error(codes.FailedPrecondition, err.Error())
+  }
+  targets := request.Targets
+  if kind == anyMetric && slices.Contains(targets, "performance") {
+    // confusion matrices of the performance target are added by Evaluate
+    targets = slices.DeleteFunc(slices.Clone(targets), func(target string) bool { return target == "performance" })
+    if len(targets) == 0 {
+      return perf, nil, nil, nil
+    }
+  }
+  if kind == ScalarMetric || kind == anyMetric {
+    targets = serve_expand_targets(config, targets, values != nil)
+  }
+  if len(targets) == 0 {
+    return perf, nil, nil, status.Error(codes.InvalidArgument, "no targets given")
+  }
+  for _, target := range targets {
+    if m, ok := LookupMetric(target); ok && kind != anyMetric && (m.Kind() == ScalarMetric) != (kind == ScalarMetric) {
+      return perf, nil, nil, status.Errorf(codes.InvalidArgument, "invalid target for this method: %s", target)
+    }
+  }
+  tables, err := serve_eval(config, perf, values, labels, targets); if err != nil {
+    return perf, nil, nil, status.Error(codes.InvalidArgument, err.Error())
+  }
+  return perf, targets, tables, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+func (obj *evaluation_server) EvaluateScalar(ctx context.Context, request *evaluationService.EvaluateRequest) (*evaluationService.ScalarResponse, error) {
+  perf, targets, tables, err := obj.evaluate(request, ScalarMetric); if err != nil {
+    return nil, err
+  }
+  r := &evaluationService.ScalarResponse{Positives: int64(perf.P), Negatives: int64(perf.N), Values: map[string]float64{}}
+  for i, table := range tables {
+    r.Values[targets[i]] = table.Columns[0][0]
+  }
+  return r, nil
+}
+
+func (obj *evaluation_server) EvaluateCurve(ctx context.Context, request *evaluationService.EvaluateRequest) (*evaluationService.CurveResponse, error) {
+  perf, targets, tables, err := obj.evaluate(request, CurveMetric); if err != nil {
+    return nil, err
+  }
+  r := &evaluationService.CurveResponse{Positives: int64(perf.P), Negatives: int64(perf.N)}
+  for i, table := range tables {
+    r.Tables = append(r.Tables, proto_table(targets[i], table))
+  }
+  return r, nil
+}
+
+func (obj *evaluation_server) Evaluate(ctx context.Context, request *evaluationService.EvaluateRequest) (*evaluationService.Results, error) {
+  perf, targets, tables, err := obj.evaluate(request, anyMetric); if err != nil {
+    return nil, err
+  }
+  r := proto_results(perf, targets, tables)
+  if slices.Contains(request.Targets, "performance") {
+    r.ConfusionMatrices = proto_confusion_matrices(perf)
+  }
+  return r, nil
+}
+
+// Add batches of predictions to sessions until the client closes the
+// stream. The status of the session of the last batch is returned.
+func (obj *evaluation_server) AddPredictions(stream grpc.ClientStreamingServer[evaluationService.PredictionBatch, evaluationService.SessionStatus]) error {
+  var session *Session
+  for {
+    batch, err := stream.Recv()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return err
+    }
+    values, labels, err := grpc_labels(batch.Predictions); if err != nil {
+      return err
+    }
+    session, err = obj.sessions.Get(batch.Session, true); if errors.Is(err, errTooManySessions) {
+      return status.Error(codes.ResourceExhausted, err.Error())
+    } else
+    if err != nil {
+      return status.Error(codes.InvalidArgument, err.Error())
+    }
+    session.Add(values, labels)
+  }
+  if session == nil {
+    return status.Error(codes.InvalidArgument, "no predictions given")
+  }
+  p, n := session.Counts()
+  PrintStderr(obj.config, 2, "Session `%s' has %d predictions\n", session.Name, p + n)
+  return stream.SendAndClose(grpc_session_status(session))
+}
+
+func (obj *evaluation_server) ResetSession(ctx context.Context, request *evaluationService.SessionRequest) (*evaluationService.SessionStatus, error) {
+  session, err := obj.sessions.Get(request.Session, false); if err != nil {
+    return nil, status.Error(codes.InvalidArgument, err.Error())
+  }
+  if session == nil {
+    return nil, status.Errorf(codes.NotFound, "session `%s' not found", request.Session)
+  }
+  if err := session.Reset(obj.config); err != nil {
+    return nil, status.Error(codes.Internal, err.Error())
+  }
+  return grpc_session_status(session), nil
+}
+
+func grpc_session_status(session *Session) *evaluationService.SessionStatus {
+  p, n := session.Counts()
+  return &evaluationService.SessionStatus{Session: session.Name, Positives: int64(p), Negatives: int64(n)}
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Start the gRPC evaluation service in the background
+func classifier_performance_grpc(config Config, address string, sessions *Sessions) {
+  listener, err := net.Listen("tcp", address); if err != nil {
+    log.Fatal(err)
+  }
+  server := grpc.NewServer()
+  evaluationService.RegisterEvaluationServer(server, &evaluation_server{config: config, sessions: sessions})
+  PrintStderr(config, 1, "Serving gRPC on %s\n", address)
+  go func() {
+    if err := server.Serve(listener); err != nil {
+      log.Fatal(err)
+    }
+  }()
+}
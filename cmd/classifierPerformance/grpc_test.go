@@ -0,0 +1,215 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "context"
+import   "fmt"
+import   "math"
+import   "net"
+import   "sync"
+import   "testing"
+
+import   "google.golang.org/grpc"
+import   "google.golang.org/grpc/codes"
+import   "google.golang.org/grpc/credentials/insecure"
+import   "google.golang.org/grpc/status"
+import   "google.golang.org/grpc/test/bufconn"
+
+import   "github.com/pbenner/classifierPerformance/pkg/evaluationService"
+
+/* -------------------------------------------------------------------------- */
+
+// Configuration with the defaults of the command line that matter for
+// evaluating predictions
+func test_config() Config {
+  return Config{Bins: 10, Threshold: math.NaN(), Bandwidth: math.NaN()}
+}
+
+var test_values = []float64{0.9, 0.8, 0.7, 0.6, 0.55, 0.54, 0.53, 0.52, 0.51, 0.4, 0.38, 0.1}
+var test_labels = []int    {  1,   1,   0,   1,    1,    0,    1,    0,    0,   1,    0,   0}
+
+func test_grpc_client(t *testing.T, server *evaluation_server) evaluationService.EvaluationClient {
+  listener := bufconn.Listen(1 << 20)
+  s := grpc.NewServer()
+  evaluationService.RegisterEvaluationServer(s, server)
+  go s.Serve(listener)
+  t.Cleanup(s.Stop)
+  dialer := func(context.Context, string) (net.Conn, error) { return listener.Dial() }
+  conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials())); if err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { conn.Close() })
+  return evaluationService.NewEvaluationClient(conn)
+}
+
+func test_predictions(values []float64, labels []int) *evaluationService.Predictions {
+  r := &evaluationService.Predictions{Values: values, Labels: make([]int32, len(labels))}
+  for i, l := range labels {
+    r.Labels[i] = int32(l)
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+func TestSessions(t *testing.T) {
+  sessions := new_sessions(test_config())
+  if _, err := sessions.Get("", true); err == nil {
+    t.Errorf("session without name accepted")
+  }
+  if s, err := sessions.Get("a", false); err != nil || s != nil {
+    t.Errorf("unexpected session: %v %v", s, err)
+  }
+  a, err := sessions.Get("a", true); if err != nil {
+    t.Fatal(err)
+  }
+  if s, _ := sessions.Get("a", true); s != a {
+    t.Errorf("session `a' created twice")
+  }
+  a.Add(test_values, test_labels)
+  if p, n := a.Counts(); p != 6 || n != 6 {
+    t.Errorf("unexpected counts: %d %d", p, n)
+  }
+  if perf, err := a.Performance(); err != nil || perf.P != 6 || perf.N != 6 {
+    t.Errorf("unexpected performance: %v %v", perf, err)
+  }
+  if err := a.Reset(test_config()); err != nil {
+    t.Fatal(err)
+  }
+  if p, n := a.Counts(); p != 0 || n != 0 {
+    t.Errorf("counts not reset: %d %d", p, n)
+  }
+  for i := 1; i < serve_max_sessions; i++ {
+    if _, err := sessions.Get(fmt.Sprintf("s%d", i), true); err != nil {
+      t.Fatal(err)
+    }
+  }
+  if _, err := sessions.Get("z", true); err == nil {
+    t.Errorf("too many sessions accepted")
+  }
+  if r := sessions.List(); len(r) != serve_max_sessions || r[0].Name != "a" {
+    t.Errorf("unexpected list of sessions")
+  }
+}
+
+func TestSessionWindow(t *testing.T) {
+  config := test_config()
+  config.WindowSize = 4
+  s, err := new_sessions(config).Get("a", true); if err != nil {
+    t.Fatal(err)
+  }
+  s.Add(test_values, test_labels)
+  // counts include all predictions, while only the window is evaluated
+  if p, n := s.Counts(); p != 6 || n != 6 {
+    t.Errorf("unexpected counts: %d %d", p, n)
+  }
+  if perf, err := s.Performance(); err != nil || perf.P + perf.N != 4 {
+    t.Errorf("unexpected performance: %v %v", perf, err)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+func TestGrpcEvaluate(t *testing.T) {
+  client  := test_grpc_client(t, &evaluation_server{config: test_config(), sessions: new_sessions(test_config())})
+  request := &evaluationService.EvaluateRequest{Predictions: test_predictions(test_values, test_labels), Targets: []string{"roc-auc", "ks"}}
+  r, err := client.EvaluateScalar(context.Background(), request); if err != nil {
+    t.Fatal(err)
+  }
+  if r.Positives != 6 || r.Negatives != 6 || len(r.Values) != 2 {
+    t.Errorf("unexpected response: %v", r)
+  }
+  if v := r.Values["roc-auc"]; math.IsNaN(v) || v <= 0.5 || v > 1.0 {
+    t.Errorf("unexpected roc-auc: %v", v)
+  }
+  request.Targets = []string{"roc"}
+  if _, err := client.EvaluateScalar(context.Background(), request); status.Code(err) != codes.InvalidArgument {
+    t.Errorf("curve accepted as scalar target: %v", err)
+  }
+  if r, err := client.EvaluateCurve(context.Background(), request); err != nil || len(r.Tables) != 1 {
+    t.Errorf("unexpected response: %v %v", r, err)
+  }
+  request.Targets = []string{"performance"}
+  if r, err := client.Evaluate(context.Background(), request); err != nil || len(r.ConfusionMatrices) == 0 {
+    t.Errorf("unexpected response: %v %v", r, err)
+  }
+  request.Predictions = test_predictions([]float64{0.5, math.NaN()}, []int{0, 1})
+  if _, err := client.Evaluate(context.Background(), request); status.Code(err) != codes.InvalidArgument {
+    t.Errorf("NaN prediction accepted: %v", err)
+  }
+  request.Predictions = test_predictions([]float64{0.5}, []int{2})
+  if _, err := client.Evaluate(context.Background(), request); status.Code(err) != codes.InvalidArgument {
+    t.Errorf("invalid label accepted: %v", err)
+  }
+  if _, err := client.Evaluate(context.Background(), &evaluationService.EvaluateRequest{Session: "a"}); status.Code(err) != codes.NotFound {
+    t.Errorf("unknown session accepted: %v", err)
+  }
+}
+
+// Batches streamed concurrently to the same session must give the same
+// result as evaluating all predictions at once
+func TestGrpcAddPredictions(t *testing.T) {
+  client := test_grpc_client(t, &evaluation_server{config: test_config(), sessions: new_sessions(test_config())})
+  wg     := sync.WaitGroup{}
+  for i := 0; i < len(test_values); i += 3 {
+    wg.Add(1)
+    go func(i int) {
+      defer wg.Done()
+      stream, err := client.AddPredictions(context.Background()); if err != nil {
+        t.Error(err)
+        return
+      }
+      for j := i; j < i+3; j++ {
+        if err := stream.Send(&evaluationService.PredictionBatch{Session: "a", Predictions: test_predictions(test_values[j:j+1], test_labels[j:j+1])}); err != nil {
+          t.Error(err)
+          return
+        }
+      }
+      if _, err := stream.CloseAndRecv(); err != nil {
+        t.Error(err)
+      }
+    }(i)
+  }
+  wg.Wait()
+  targets := []string{"roc-auc", "precision-recall-auc", "ks"}
+  r1, err := client.EvaluateScalar(context.Background(), &evaluationService.EvaluateRequest{Session: "a", Targets: targets}); if err != nil {
+    t.Fatal(err)
+  }
+  r2, err := client.EvaluateScalar(context.Background(), &evaluationService.EvaluateRequest{Predictions: test_predictions(test_values, test_labels), Targets: targets}); if err != nil {
+    t.Fatal(err)
+  }
+  if r1.Positives != 6 || r1.Negatives != 6 {
+    t.Errorf("unexpected counts: %d %d", r1.Positives, r1.Negatives)
+  }
+  for _, target := range targets {
+    if math.Abs(r1.Values[target] - r2.Values[target]) > 1e-12 {
+      t.Errorf("target `%s': session gives %v instead of %v", target, r1.Values[target], r2.Values[target])
+    }
+  }
+  r, err := client.ResetSession(context.Background(), &evaluationService.SessionRequest{Session: "a"}); if err != nil {
+    t.Fatal(err)
+  }
+  if r.Positives != 0 || r.Negatives != 0 {
+    t.Errorf("session not reset: %v", r)
+  }
+  if _, err := client.ResetSession(context.Background(), &evaluationService.SessionRequest{Session: "b"}); status.Code(err) != codes.NotFound {
+    t.Errorf("unknown session reset: %v", err)
+  }
+}
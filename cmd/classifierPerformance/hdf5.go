@@ -0,0 +1,84 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//go:build hdf5
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+
+import   "gonum.org/v1/hdf5"
+
+/* -------------------------------------------------------------------------- */
+
+func read_hdf5_values[T int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64 | float32 | float64](dataset *hdf5.Dataset, n int) ([]float64, error) {
+  data := make([]T, n)
+  if n > 0 {
+    if err := dataset.Read(&data); err != nil {
+      return nil, err
+    }
+  }
+  r := make([]float64, n)
+  for i := range data {
+    r[i] = float64(data[i])
+  }
+  return r, nil
+}
+
+// Read a one-dimensional numeric dataset from an HDF5 file. Values are read
+// in the native byte order.
+func read_hdf5_dataset(filename, name string) ([]float64, error) {
+  f, err := hdf5.OpenFile(filename, hdf5.F_ACC_RDONLY); if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  dataset, err := f.OpenDataset(name); if err != nil {
+    return nil, fmt.Errorf("opening dataset `%s' failed: %v", name, err)
+  }
+  defer dataset.Close()
+  space := dataset.Space()
+  defer space.Close()
+  dims, _, err := space.SimpleExtentDims(); if err != nil {
+    return nil, err
+  }
+  n := 1
+  for _, d := range dims {
+    if d != 1 && n != 1 {
+      return nil, fmt.Errorf("dataset `%s' with dimensions %v is not one-dimensional", name, dims)
+    }
+    n *= int(d)
+  }
+  dtype, err := dataset.Datatype(); if err != nil {
+    return nil, err
+  }
+  defer dtype.Close()
+  switch {
+  case dtype.Equal(hdf5.T_NATIVE_DOUBLE): return read_hdf5_values[float64](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_FLOAT ): return read_hdf5_values[float32](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_INT8  ): return read_hdf5_values[int8   ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_INT16 ): return read_hdf5_values[int16  ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_INT32 ): return read_hdf5_values[int32  ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_INT64 ): return read_hdf5_values[int64  ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_UINT8 ): return read_hdf5_values[uint8  ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_UINT16): return read_hdf5_values[uint16 ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_UINT32): return read_hdf5_values[uint32 ](dataset, n)
+  case dtype.Equal(hdf5.T_NATIVE_UINT64): return read_hdf5_values[uint64 ](dataset, n)
+  default:
+    return nil, fmt.Errorf("dataset `%s' has an unsupported data type", name)
+  }
+}
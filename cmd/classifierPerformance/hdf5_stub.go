@@ -0,0 +1,31 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//go:build !hdf5
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+
+/* -------------------------------------------------------------------------- */
+
+// HDF5 support requires cgo and the HDF5 library, and is therefore only
+// available when building with -tags hdf5
+func read_hdf5_dataset(filename, name string) ([]float64, error) {
+  return nil, fmt.Errorf("HDF5 support is not available, rebuild with `-tags hdf5'")
+}
@@ -0,0 +1,88 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "sort"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Compute metrics as a function of the training set size given in a separate
+// column
+func classifier_performance_learning_curve(config Config, filename string) {
+  if config.SizeColumn == "" {
+    log.Fatal("no size column specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("learning curves are not supported in streaming mode")
+  }
+  table := import_table(config, filename, config.SizeColumn)
+  names, values, labels := GroupPredictions(table.Values, table.Labels, table.Columns[config.SizeColumn])
+  sizes := make([]float64, len(names))
+  index := make([]int, len(names))
+  for k, name := range names {
+    if v, err := strconv.ParseFloat(name, 64); err != nil {
+      log.Fatalf("invalid training set size `%s'", name)
+    } else {
+      sizes[k] = v
+      index[k] = k
+    }
+  }
+  sort.Slice(index, func(i, j int) bool { return sizes[index[i]] < sizes[index[j]] })
+
+  metrics := summary_metrics(config, true)
+  if config.PrintHeader {
+    fmt.Print("size n")
+    for _, name := range metrics {
+      if config.Bootstrap > 0 {
+        fmt.Printf(" %s %s_lower %s_upper", name, name, name)
+      } else {
+        fmt.Printf(" %s", name)
+      }
+    }
+    fmt.Println()
+  }
+  for _, k := range index {
+    n := len(values[k])
+    // bootstrap before evaluating performance, which sorts the predictions
+    var lower, upper []float64
+    if config.Bootstrap > 0 {
+      lower, upper = bootstrap_metrics(config, values[k], labels[k], nil, metrics)
+    }
+    perf, err := eval_performance(config, values[k], labels[k]); if err != nil {
+      log.Fatal(err)
+    }
+    r, err := new_metrics(config, perf, values[k], labels[k]).Eval(metrics...); if err != nil {
+      log.Fatalf("training set size `%s': %v", names[k], err)
+    }
+    fmt.Printf("%s %d", names[k], n)
+    for i := range metrics {
+      if config.Bootstrap > 0 {
+        fmt.Printf(" %f %f %f", r[i], lower[i], upper[i])
+      } else {
+        fmt.Printf(" %f", r[i])
+      }
+    }
+    fmt.Println()
+  }
+}
@@ -0,0 +1,188 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "encoding/json"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "net/http"
+import   "net/url"
+import   "os"
+import   "strings"
+import   "time"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Client for requests to experiment trackers with a timeout, so that an
+// unreachable server cannot block a run (e.g. in CI) indefinitely
+var tracking_client = &http.Client{Timeout: 2*time.Minute}
+
+/* -------------------------------------------------------------------------- */
+
+// Metric values in JSON requests, where non-finite values are encoded as in
+// the JSON mapping of protocol buffers
+type mlflow_float float64
+
+func (obj mlflow_float) MarshalJSON() ([]byte, error) {
+  switch v := float64(obj); {
+  case math.IsNaN(v):
+    return []byte(`"NaN"`), nil
+  case math.IsInf(v,  1):
+    return []byte(`"Infinity"`), nil
+  case math.IsInf(v, -1):
+    return []byte(`"-Infinity"`), nil
+  default:
+    return json.Marshal(v)
+  }
+}
+
+type mlflow_metric struct {
+  Key       string       `json:"key"`
+  Value     mlflow_float `json:"value"`
+  Timestamp int64        `json:"timestamp"`
+  Step      int64        `json:"step"`
+}
+
+type mlflow_log_batch struct {
+  RunId   string          `json:"run_id"`
+  Metrics []mlflow_metric `json:"metrics"`
+}
+
+type mlflow_run struct {
+  Run struct {
+    Info struct {
+      ArtifactUri string `json:"artifact_uri"`
+    } `json:"info"`
+  } `json:"run"`
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Send a request to the tracking server, where credentials are taken from
+// the environment variables used by MLflow clients
+func mlflow_request(config Config, method, endpoint string, body io.Reader, content_type string) ([]byte, error) {
+  request, err := http.NewRequest(method, strings.TrimRight(config.MlflowUri, "/") + endpoint, body); if err != nil {
+    return nil, err
+  }
+  if content_type != "" {
+    request.Header.Set("Content-Type", content_type)
+  }
+  if token := os.Getenv("MLFLOW_TRACKING_TOKEN"); token != "" {
+    request.Header.Set("Authorization", "Bearer " + token)
+  } else
+  if user := os.Getenv("MLFLOW_TRACKING_USERNAME"); user != "" {
+    request.SetBasicAuth(user, os.Getenv("MLFLOW_TRACKING_PASSWORD"))
+  }
+  response, err := tracking_client.Do(request); if err != nil {
+    return nil, err
+  }
+  defer response.Body.Close()
+  r, err := io.ReadAll(response.Body); if err != nil {
+    return nil, err
+  }
+  if response.StatusCode/100 != 2 {
+    return nil, fmt.Errorf("%s %s failed with status %s: %s", method, endpoint, response.Status, strings.TrimSpace(string(r)))
+  }
+  return r, nil
+}
+
+func mlflow_run_id(config Config) string {
+  if config.RunId != "" {
+    return config.RunId
+  }
+  return os.Getenv("MLFLOW_RUN_ID")
+}
+
+// Path of the artifact directory of a run on the tracking server, which is
+// only available if the server proxies artifacts (mlflow-artifacts scheme)
+func mlflow_artifact_path(config Config, run_id string) (string, error) {
+  b, err := mlflow_request(config, http.MethodGet, "/api/2.0/mlflow/runs/get?run_id=" + url.QueryEscape(run_id), nil, ""); if err != nil {
+    return "", err
+  }
+  run := mlflow_run{}
+  if err := json.Unmarshal(b, &run); err != nil {
+    return "", err
+  }
+  uri := run.Run.Info.ArtifactUri
+  if !strings.HasPrefix(uri, "mlflow-artifacts:") {
+    return "", fmt.Errorf("artifacts stored at `%s' cannot be uploaded through the tracking server", uri)
+  }
+  u, err := url.Parse(uri); if err != nil {
+    return "", err
+  }
+  return strings.Trim(u.Path, "/"), nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Log scalar metrics of the summary target to an MLflow run and upload the
+// files of the output directory (curves and plots) as artifacts
+func export_mlflow(config Config, data *Metrics) {
+  run_id := mlflow_run_id(config)
+  if run_id == "" {
+    log.Fatal("no MLflow run specified")
+  }
+  names, values, err := output_metrics(config, data); if err != nil {
+    log.Fatal(err)
+  }
+  batch := mlflow_log_batch{RunId: run_id}
+  for i, name := range names {
+    batch.Metrics = append(batch.Metrics, mlflow_metric{Key: name, Value: mlflow_float(values[i]), Timestamp: time.Now().UnixMilli(), Step: config.Step})
+  }
+  body, err := json.Marshal(batch); if err != nil {
+    log.Fatal(err)
+  }
+  PrintStderr(config, 1, "Logging %d metrics to MLflow run `%s'... ", len(names), run_id)
+  if _, err := mlflow_request(config, http.MethodPost, "/api/2.0/mlflow/runs/log-batch", bytes.NewReader(body), "application/json"); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  PrintStderr(config, 1, "done\n")
+  dir, err := mlflow_artifact_path(config, run_id); if err != nil {
+    fmt.Fprintf(os.Stderr, "notice: skipping upload of artifacts: %v\n", err)
+    return
+  }
+  // metrics are already logged, hence failed uploads of artifacts are
+  // reported but do not abort the run
+  failed := 0
+  for _, file := range output_files(config, data) {
+    var b bytes.Buffer
+    if err := file.Write(&b); err != nil {
+      fmt.Fprintf(os.Stderr, "warning: creating artifact `%s' failed: %v\n", file.Name, err)
+      failed++
+      continue
+    }
+    PrintStderr(config, 1, "Uploading artifact `%s'... ", file.Name)
+    if _, err := mlflow_request(config, http.MethodPut, "/api/2.0/mlflow-artifacts/artifacts/" + dir + "/" + file.Name, &b, "application/octet-stream"); err != nil {
+      PrintStderr(config, 1, "failed\n")
+      fmt.Fprintf(os.Stderr, "warning: uploading artifact `%s' failed: %v\n", file.Name, err)
+      failed++
+      continue
+    }
+    PrintStderr(config, 1, "done\n")
+  }
+  if failed > 0 {
+    fmt.Fprintf(os.Stderr, "warning: %d artifacts of MLflow run `%s' are missing\n", failed, run_id)
+  }
+}
@@ -0,0 +1,34 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//go:build !unix
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "os"
+
+/* -------------------------------------------------------------------------- */
+
+// Memory-mapping is not supported on this platform, the file is read into
+// memory instead
+func map_file(filename string) ([]byte, func() error, error) {
+  data, err := os.ReadFile(filename); if err != nil {
+    return nil, nil, err
+  }
+  return data, func() error { return nil }, nil
+}
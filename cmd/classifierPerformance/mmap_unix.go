@@ -0,0 +1,44 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//go:build unix
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "os"
+import   "syscall"
+
+/* -------------------------------------------------------------------------- */
+
+// Memory-map a file for reading. The returned function unmaps the file.
+func map_file(filename string) ([]byte, func() error, error) {
+  f, err := os.Open(filename); if err != nil {
+    return nil, nil, err
+  }
+  defer f.Close()
+  info, err := f.Stat(); if err != nil {
+    return nil, nil, err
+  }
+  if info.Size() == 0 {
+    return []byte{}, func() error { return nil }, nil
+  }
+  data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED); if err != nil {
+    return nil, nil, err
+  }
+  return data, func() error { return syscall.Munmap(data) }, nil
+}
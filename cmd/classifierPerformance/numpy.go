@@ -0,0 +1,96 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "log"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Import a one-dimensional array from a .npy file or from an .npz archive,
+// where the array within the archive is selected by appending its name, e.g.
+// arrays.npz:scores
+func import_npy(config Config, filename string) []float64 {
+  name := ""
+  if i := strings.LastIndex(filename, ":"); i > 0 && strings.HasSuffix(strings.ToLower(filename[:i]), ".npz") {
+    filename, name = filename[:i], filename[i+1:]
+  }
+  PrintStderr(config, 1, "Reading array from `%s'... ", filename)
+  data, close, err := load_file(filename); if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  var r []float64
+  if strings.HasSuffix(strings.ToLower(filename), ".npz") {
+    r, err = ReadNpz(bytes.NewReader(data), int64(len(data)), name)
+  } else {
+    r, err = ReadNpy(bytes.NewReader(data))
+  }
+  if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatalf("reading `%s' failed: %v", filename, err)
+  }
+  if err := close(); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  PrintStderr(config, 1, "done\n")
+  return r
+}
+
+// Import a one-dimensional dataset from an HDF5 file
+func import_hdf5(config Config, filename, name string) []float64 {
+  PrintStderr(config, 1, "Reading dataset `%s' from `%s'... ", name, filename)
+  r, err := read_hdf5_dataset(filename, name); if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatalf("reading `%s' failed: %v", filename, err)
+  }
+  PrintStderr(config, 1, "done\n")
+  return r
+}
+
+// Predictions are given as arrays by --scores and --labels or --hdf5
+// instead of a prediction table
+func has_arrays(config Config, filename string) bool {
+  return (config.Scores != "" || config.Hdf5 != "") && filename == ""
+}
+
+// Import predictions and labels from the NumPy arrays given by --scores and
+// --labels or from the datasets of the HDF5 file given by --hdf5
+func import_arrays(config Config) PredictionTable {
+  var values []float64
+  var labels []float64
+  if config.Hdf5 != "" {
+    values = import_hdf5(config, config.Hdf5, config.ScoresDataset)
+    labels = import_hdf5(config, config.Hdf5, config.LabelsDataset)
+  } else {
+    values = import_npy(config, config.Scores)
+    labels = import_npy(config, config.Labels)
+  }
+  table, err := NewPredictionTable(values, labels, config.RelevanceThreshold); if err != nil {
+    log.Fatal(err)
+  }
+  if len(table.Values) == 0 {
+    log.Fatal("arrays of predictions and labels are empty")
+  }
+  return table
+}
@@ -0,0 +1,170 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "compress/bzip2"
+import   "compress/gzip"
+import   "context"
+import   "fmt"
+import   "io"
+import   "net/http"
+import   "net/url"
+import   "os"
+import   "strings"
+
+import   "github.com/aws/aws-sdk-go-v2/aws"
+import   "github.com/aws/aws-sdk-go-v2/config"
+import   "github.com/aws/aws-sdk-go-v2/service/s3"
+import   "golang.org/x/oauth2/google"
+
+/* -------------------------------------------------------------------------- */
+
+// Files in object stores are given by URIs of the form s3://BUCKET/KEY or
+// gs://BUCKET/OBJECT
+func is_object_uri(filename string) bool {
+  return strings.HasPrefix(filename, "s3://") || strings.HasPrefix(filename, "gs://")
+}
+
+func split_object_uri(filename string) (string, string, error) {
+  u, err := url.Parse(filename); if err != nil {
+    return "", "", err
+  }
+  if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+    return "", "", fmt.Errorf("invalid object URI `%s'", filename)
+  }
+  return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Open an S3 object, where credentials and the region are taken from the
+// default credential chain of the AWS SDK (environment, shared config and
+// credentials files, web identity, instance metadata)
+func open_s3(ctx context.Context, filename string) (io.ReadCloser, error) {
+  bucket, key, err := split_object_uri(filename); if err != nil {
+    return nil, err
+  }
+  cfg, err := config.LoadDefaultConfig(ctx); if err != nil {
+    return nil, err
+  }
+  if cfg.Region == "" {
+    cfg.Region = "us-east-1"
+  }
+  client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+    // custom endpoints (e.g. MinIO) usually require path-style addressing
+    o.UsePathStyle = os.Getenv("AWS_ENDPOINT_URL_S3") != "" || os.Getenv("AWS_ENDPOINT_URL") != ""
+  })
+  r, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}); if err != nil {
+    return nil, err
+  }
+  return r.Body, nil
+}
+
+// Open a Google Cloud Storage object with application default credentials,
+// or without authentication if STORAGE_EMULATOR_HOST is set
+func open_gs(ctx context.Context, filename string) (io.ReadCloser, error) {
+  bucket, object, err := split_object_uri(filename); if err != nil {
+    return nil, err
+  }
+  base   := "https://storage.googleapis.com"
+  client := http.DefaultClient
+  if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+    if !strings.Contains(host, "://") {
+      host = "http://" + host
+    }
+    base = strings.TrimRight(host, "/")
+  } else {
+    if client, err = google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_only"); err != nil {
+      return nil, err
+    }
+  }
+  response, err := client.Get(fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", base, url.PathEscape(bucket), url.PathEscape(object))); if err != nil {
+    return nil, err
+  }
+  if response.StatusCode != http.StatusOK {
+    defer response.Body.Close()
+    b, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+    return nil, fmt.Errorf("reading `%s' failed with status %s: %s", filename, response.Status, strings.TrimSpace(string(b)))
+  }
+  return response.Body, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+type decompressed_file struct {
+  io.Reader
+  closers []io.Closer
+}
+
+func (obj decompressed_file) Close() error {
+  var r error
+  for _, c := range obj.closers {
+    if err := c.Close(); err != nil && r == nil {
+      r = err
+    }
+  }
+  return r
+}
+
+// Open a local file or an object in S3 or GCS. Files with extensions .gz and
+// .bz2 are decompressed while reading.
+func open_file(filename string) (io.ReadCloser, error) {
+  var file io.ReadCloser
+  var err  error
+  switch {
+  case strings.HasPrefix(filename, "s3://"):
+    file, err = open_s3(context.Background(), filename)
+  case strings.HasPrefix(filename, "gs://"):
+    file, err = open_gs(context.Background(), filename)
+  default:
+    file, err = os.Open(filename)
+  }
+  if err != nil {
+    return nil, err
+  }
+  switch {
+  case strings.HasSuffix(filename, ".gz"):
+    z, err := gzip.NewReader(file); if err != nil {
+      file.Close()
+      return nil, err
+    }
+    return decompressed_file{z, []io.Closer{z, file}}, nil
+  case strings.HasSuffix(filename, ".bz2"):
+    return decompressed_file{bzip2.NewReader(file), []io.Closer{file}}, nil
+  default:
+    return file, nil
+  }
+}
+
+// Load the content of a file, which is memory-mapped if possible. Compressed
+// files and objects in S3 or GCS are read into memory. The returned function
+// must be called once the content is no longer used.
+func load_file(filename string) ([]byte, func() error, error) {
+  if is_object_uri(filename) || strings.HasSuffix(filename, ".gz") || strings.HasSuffix(filename, ".bz2") {
+    file, err := open_file(filename); if err != nil {
+      return nil, nil, err
+    }
+    defer file.Close()
+    data, err := io.ReadAll(file); if err != nil {
+      return nil, nil, err
+    }
+    return data, func() error { return nil }, nil
+  }
+  return map_file(filename)
+}
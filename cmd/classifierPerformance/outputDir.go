@@ -0,0 +1,295 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "encoding/json"
+import   "fmt"
+import   "html"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+import   "path"
+import   "path/filepath"
+import   "reflect"
+import   "strconv"
+import   "strings"
+import   "unicode"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Version of the output directory layout, which must be increased whenever
+// the layout or the schema of metrics.json changes
+const output_dir_version = 1
+
+type OutputMetrics struct {
+  Version   int                  `json:"version"`
+  Positives int                  `json:"positives"`
+  Negatives int                  `json:"negatives"`
+  Metrics   map[string]JSONFloat `json:"metrics"`
+}
+
+/* -------------------------------------------------------------------------- */
+
+func create_output_file(config Config, filename string, f func(io.Writer) error) {
+  PrintStderr(config, 1, "Writing `%s'... ", filename)
+  file, err := os.Create(filename)
+  if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  defer file.Close()
+  w := bufio.NewWriter(file)
+  if err := f(w); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  if err := w.Flush(); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  PrintStderr(config, 1, "done\n")
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Convert field names of the config to option names, e.g. NormalizePrecision
+// to normalize-precision
+func option_name(field string) string {
+  var b strings.Builder
+  for i, c := range field {
+    if unicode.IsUpper(c) {
+      if i > 0 {
+        b.WriteRune('-')
+      }
+      c = unicode.ToLower(c)
+    }
+    b.WriteRune(c)
+  }
+  return b.String()
+}
+
+// Values of options as YAML flow nodes, i.e. structs as mappings and slices
+// as sequences
+func config_yaml_value(x reflect.Value) string {
+  if x.Kind() == reflect.Ptr {
+    if x.IsNil() {
+      return "null"
+    }
+    x = x.Elem()
+  }
+  switch x.Kind() {
+  case reflect.Struct:
+    items := []string{}
+    for j := 0; j < x.NumField(); j++ {
+      items = append(items, fmt.Sprintf("%s: %s", option_name(x.Type().Field(j).Name), config_yaml_value(x.Field(j))))
+    }
+    return fmt.Sprintf("{%s}", strings.Join(items, ", "))
+  case reflect.Slice, reflect.Array:
+    items := []string{}
+    for j := 0; j < x.Len(); j++ {
+      items = append(items, config_yaml_value(x.Index(j)))
+    }
+    return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+  case reflect.String:
+    return strconv.Quote(x.String())
+  case reflect.Float32, reflect.Float64:
+    switch f := x.Float(); {
+    case math.IsNaN(f):
+      return ".nan"
+    case math.IsInf(f, 1):
+      return ".inf"
+    case math.IsInf(f, -1):
+      return "-.inf"
+    default:
+      return strconv.FormatFloat(f, 'g', -1, 64)
+    }
+  default:
+    return fmt.Sprint(x.Interface())
+  }
+}
+
+func export_config_yaml(config Config, writer io.Writer) error {
+  v := reflect.ValueOf(config)
+  t := v.Type()
+  for i := 0; i < t.NumField(); i++ {
+    fmt.Fprintf(writer, "%s: %s\n", option_name(t.Field(i).Name), config_yaml_value(v.Field(i)))
+  }
+  return nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Colors of curves in plots
+var svg_palette = []string{"steelblue", "darkorange", "forestgreen", "firebrick", "mediumpurple", "saddlebrown", "hotpink", "gray", "olive", "darkturquoise"}
+
+// Plot one or more curves as SVG. A legend is added if names are given.
+func export_curve_svg(writer io.Writer, curves []Curve, names []string, title, name_x, name_y string) error {
+  const size   = 400.0
+  const margin =  50.0
+  px := func(x float64) float64 { return margin + x*(size - 2*margin) }
+  py := func(y float64) float64 { return size - margin - y*(size - 2*margin) }
+  // reserve space for the legend
+  width := size
+  if len(names) > 0 {
+    width += 100
+  }
+  fmt.Fprintf(writer, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", int(width), int(size))
+  fmt.Fprintf(writer, "<rect x=\"%f\" y=\"%f\" width=\"%f\" height=\"%f\" fill=\"none\" stroke=\"black\"/>\n", px(0), py(1), px(1)-px(0), py(0)-py(1))
+  for _, t := range []float64{0.0, 0.5, 1.0} {
+    fmt.Fprintf(writer, "<text x=\"%f\" y=\"%f\" text-anchor=\"middle\">%.1f</text>\n", px(t), py(0)+15, t)
+    fmt.Fprintf(writer, "<text x=\"%f\" y=\"%f\" text-anchor=\"end\">%.1f</text>\n", px(0)-5, py(t)+4, t)
+  }
+  fmt.Fprintf(writer, "<text x=\"%f\" y=\"%f\" text-anchor=\"middle\">%s</text>\n", size/2, size-margin/2+10, name_x)
+  fmt.Fprintf(writer, "<text x=\"%f\" y=\"%f\" text-anchor=\"middle\" transform=\"rotate(-90 %f %f)\">%s</text>\n", margin/2-5, size/2, margin/2-5, size/2, name_y)
+  fmt.Fprintf(writer, "<text x=\"%f\" y=\"%f\" text-anchor=\"middle\" font-size=\"14\">%s</text>\n", size/2, margin/2, title)
+  for k, curve := range curves {
+    color := svg_palette[k % len(svg_palette)]
+    fmt.Fprintf(writer, "<polyline fill=\"none\" stroke=\"%s\" stroke-width=\"2\" points=\"", color)
+    for i := 0; i < curve.Len(); i++ {
+      fmt.Fprintf(writer, "%.2f,%.2f ", px(curve.X[i]), py(curve.Y[i]))
+    }
+    fmt.Fprintln(writer, "\"/>")
+    if k < len(names) {
+      fmt.Fprintf(writer, "<text x=\"%f\" y=\"%f\" fill=\"%s\">%s</text>\n", px(1)+5, py(1)+12*float64(k+1), color, html.EscapeString(names[k]))
+    }
+  }
+  fmt.Fprintln(writer, "</svg>")
+  return nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// A file of the output directory with its path relative to the directory.
+// The same files are uploaded as artifacts to experiment trackers.
+type output_file struct {
+  Name  string
+  Write func(writer io.Writer) error
+}
+
+// Scalar metrics of the summary target
+func output_metrics(config Config, data *Metrics) ([]string, []float64, error) {
+  names := summary_metrics(config, data.Values != nil)
+  r, err := eval_metrics(config, data, names); if err != nil {
+    return nil, nil, err
+  }
+  return names, r, nil
+}
+
+// Files of the output directory with the following layout:
+//   config.yaml                  options of the run
+//   metrics.json                 scalar metrics
+//   curves/roc.table             ROC curve with thresholds
+//   curves/precision-recall.table
+//   curves/roc-smooth.table      kernel-smoothed ROC curve (with --bandwidth)
+//   plots/roc.svg
+//   plots/precision-recall.svg
+func output_files(config Config, data *Metrics) []output_file {
+  files := []output_file{}
+  files  = append(files, output_file{"config.yaml", func(writer io.Writer) error {
+    return export_config_yaml(config, writer)
+  }})
+  files  = append(files, output_file{"metrics.json", func(writer io.Writer) error {
+    names, r, err := output_metrics(config, data); if err != nil {
+      return err
+    }
+    m := OutputMetrics{Version: output_dir_version, Positives: data.Perf.P, Negatives: data.Perf.N, Metrics: make(map[string]JSONFloat)}
+    for i, name := range names {
+      m.Metrics[name] = JSONFloat(r[i])
+    }
+    encoder := json.NewEncoder(writer)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(m)
+  }})
+  // always print header and thresholds in curve tables
+  c := config
+  c.Format          = "text"
+  c.PrintHeader     = true
+  c.PrintThresholds = true
+  names := []string{"roc", "precision-recall"}
+  if !math.IsNaN(config.Bandwidth) {
+    names = append(names, "roc-smooth")
+  }
+  for _, name := range names {
+    metric, _ := LookupMetric(name)
+    files = append(files, output_file{path.Join("curves", name + ".table"), func(writer io.Writer) error {
+      export_metric(c, writer, metric, data)
+      return nil
+    }})
+  }
+  files = append(files, output_file{path.Join("plots", "roc.svg"), func(writer io.Writer) error {
+    if !math.IsNaN(config.Bandwidth) {
+      roc, err := data.SmoothRoc(); if err != nil {
+        return err
+      }
+      return export_curve_svg(writer, []Curve{data.Roc(), roc}, []string{"empirical", "smoothed"}, "ROC curve", "FPR", "TPR")
+    }
+    return export_curve_svg(writer, []Curve{data.Roc()}, nil, "ROC curve", "FPR", "TPR")
+  }})
+  files = append(files, output_file{path.Join("plots", "precision-recall.svg"), func(writer io.Writer) error {
+    return export_curve_svg(writer, []Curve{data.PrecisionRecall()}, nil, "Precision-recall curve", "recall", "precision")
+  }})
+  return files
+}
+
+// Write results to the output directory (see output_files for the layout)
+func export_output_dir(config Config, data *Metrics) {
+  dir := config.OutputDir
+  for _, d := range []string{dir, filepath.Join(dir, "curves"), filepath.Join(dir, "plots")} {
+    if err := os.MkdirAll(d, 0755); err != nil {
+      log.Fatal(err)
+    }
+  }
+  for _, file := range output_files(config, data) {
+    create_output_file(config, filepath.Join(dir, filepath.FromSlash(file.Name)), file.Write)
+  }
+}
+
+// Write the output directory for special targets, which report their
+// results in other ways. As for standard targets, the output directory
+// describes the pooled predictions of the input, which is read a second
+// time.
+func export_output_dir_pooled(config Config, filename, target string) {
+  switch target {
+  case "rolling", "time-roc", "time-auc", "ndcg", "err", "map", "mrr", "hit-rate", "precision@k", "recall@k":
+    log.Fatalf("--output-dir is not supported by target `%s'", target)
+  }
+  if filename == "" && !has_arrays(config, filename) {
+    log.Fatalf("--output-dir requires an input file with target `%s'", target)
+  }
+  var values []float64
+  var labels []int
+  var perf     Performance
+  if config.StreamBins > 0 {
+    perf = import_performance_binned(config, filename)
+  } else {
+    values, labels = import_predictions_cached(config, filename)
+    p, err := eval_performance(config, values, labels); if err != nil {
+      log.Fatal(err)
+    }
+    perf = p
+  }
+  if perf.P + perf.N == 0 {
+    log.Fatalf("table `%s' is empty", filename)
+  }
+  export_output_dir(config, new_metrics(config, perf, values, labels))
+}
@@ -0,0 +1,100 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Import predictions together with the IDs given by --id-column, which are
+// nil if no ID column is specified
+func import_samples(config Config, filename string) ([]float64, []int, []string) {
+  if config.IdColumn != "" {
+    table := import_table(config, filename, config.IdColumn)
+    return table.Values, table.Labels, table.Columns[config.IdColumn]
+  } else {
+    values, labels := import_predictions_cached(config, filename)
+    return values, labels, nil
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Log-loss and Brier contribution of each prediction, sorted by decreasing
+// log-loss. Rows are identified by their position in the input table
+// (starting at one) and by --id-column if given.
+func classifier_performance_per_sample(config Config, filename string) {
+  if config.StreamBins > 0 {
+    log.Fatal("per-sample is not supported in streaming mode")
+  }
+  values, labels, ids := import_samples(config, filename)
+  losses, err := PerSampleLoss(values, labels); if err != nil {
+    log.Fatal(err)
+  }
+  if config.PrintHeader {
+    if ids != nil {
+      fmt.Println("rank row id prediction label log-loss brier")
+    } else {
+      fmt.Println("rank row prediction label log-loss brier")
+    }
+  }
+  for _, l := range losses {
+    fmt.Printf("%d %d ", l.Rank, l.Index+1)
+    if ids != nil {
+      fmt.Printf("%s ", ids[l.Index])
+    }
+    fmt.Printf("%s %d %s %s\n", format_value(config, l.Value), l.Label, format_value(config, l.LogLoss), format_value(config, l.Brier))
+  }
+}
+
+// Most confident errors, i.e. the --top (default: 10) highest scoring
+// negatives and lowest scoring positives. With --threshold only false
+// positives and false negatives at the given threshold are listed.
+func classifier_performance_hardest_errors(config Config, filename string) {
+  if config.StreamBins > 0 {
+    log.Fatal("hardest-errors is not supported in streaming mode")
+  }
+  values, labels, ids := import_samples(config, filename)
+  n := config.Top
+  if n <= 0 {
+    n = 10
+  }
+  neg, pos, err := HardestErrors(values, labels, n, config.Threshold); if err != nil {
+    log.Fatal(err)
+  }
+  if config.PrintHeader {
+    if ids != nil {
+      fmt.Println("class rank row id prediction label")
+    } else {
+      fmt.Println("class rank row prediction label")
+    }
+  }
+  for _, errors := range []struct{ Name string; Index []int }{{"negative", neg}, {"positive", pos}} {
+    for k, i := range errors.Index {
+      fmt.Printf("%s %d %d ", errors.Name, k+1, i+1)
+      if ids != nil {
+        fmt.Printf("%s ", ids[i])
+      }
+      fmt.Printf("%s %d\n", format_value(config, values[i]), labels[i])
+    }
+  }
+}
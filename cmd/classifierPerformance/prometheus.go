@@ -0,0 +1,144 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "io"
+import   "math"
+import   "net/http"
+import   "strconv"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Prefix of exported metric names
+const prometheus_prefix = "classifier_performance_"
+
+// A metric family in the Prometheus text exposition format
+type prometheus_family struct {
+  Name    string
+  Help    string
+  Type    string
+  Samples []string
+}
+
+func (obj *prometheus_family) Add(labels string, value float64) {
+  obj.Samples = append(obj.Samples, fmt.Sprintf("%s%s{%s} %s", prometheus_prefix, obj.Name, labels, prometheus_float(value)))
+}
+
+func (obj prometheus_family) Write(writer io.Writer) {
+  if len(obj.Samples) == 0 {
+    return
+  }
+  fmt.Fprintf(writer, "# HELP %s%s %s\n", prometheus_prefix, obj.Name, obj.Help)
+  fmt.Fprintf(writer, "# TYPE %s%s %s\n", prometheus_prefix, obj.Name, obj.Type)
+  for _, sample := range obj.Samples {
+    fmt.Fprintln(writer, sample)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+func prometheus_float(v float64) string {
+  switch {
+  case math.IsNaN(v):
+    return "NaN"
+  case math.IsInf(v,  1):
+    return "+Inf"
+  case math.IsInf(v, -1):
+    return "-Inf"
+  default:
+    return strconv.FormatFloat(v, 'g', -1, 64)
+  }
+}
+
+// Metric names may only contain letters, digits and underscores
+func prometheus_name(name string) string {
+  return strings.Map(func(c rune) rune {
+    if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+      return c
+    }
+    return '_'
+  }, name)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Export metrics of all sessions in the Prometheus text format. Scalar
+// metrics are given by --metrics [default: summary metrics], and the
+// precision, recall and false positive rate at the deployed threshold are
+// exported if --threshold is given. With --window-size, metrics are
+// computed on the most recent predictions of each session.
+func serve_prometheus(config Config, sessions *Sessions) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    names := []string{}
+    for _, name := range summary_metrics(config, false) {
+      if m, ok := LookupMetric(name); ok && m.Kind() == ScalarMetric {
+        names = append(names, name)
+      }
+    }
+    total      := prometheus_family{Name: "predictions_total", Type: "counter", Help: "Number of predictions added to the session."}
+    window     := prometheus_family{Name: "predictions"      , Type: "gauge"  , Help: "Number of predictions that are evaluated."}
+    prevalence := prometheus_family{Name: "prevalence"       , Type: "gauge"  , Help: "Fraction of positive predictions that are evaluated."}
+    threshold  := prometheus_family{Name: "threshold"        , Type: "gauge"  , Help: "Deployed threshold."}
+    precision  := prometheus_family{Name: "precision"        , Type: "gauge"  , Help: "Precision at the deployed threshold."}
+    recall     := prometheus_family{Name: "recall"           , Type: "gauge"  , Help: "Recall at the deployed threshold."}
+    fpr        := prometheus_family{Name: "fpr"              , Type: "gauge"  , Help: "False positive rate at the deployed threshold."}
+    metrics    := make([]prometheus_family, len(names))
+    for i, name := range names {
+      metrics[i] = prometheus_family{Name: prometheus_name(name), Type: "gauge", Help: fmt.Sprintf("Metric %s of the classifier.", name)}
+    }
+    for _, session := range sessions.List() {
+      label := fmt.Sprintf("session=%s", strconv.Quote(session.Name))
+      p, n  := session.Counts()
+      total.Add(label+`,class="positive"`, float64(p))
+      total.Add(label+`,class="negative"`, float64(n))
+      perf, err := session.Performance(); if err != nil {
+        PrintStderr(config, 1, "Session `%s': %v\n", session.Name, err)
+        continue
+      }
+      window.Add(label+`,class="positive"`, float64(perf.P))
+      window.Add(label+`,class="negative"`, float64(perf.N))
+      prevalence.Add(label, float64(perf.P)/float64(perf.P + perf.N))
+      if !math.IsNaN(config.Threshold) {
+        c := perf.At(config.Threshold)
+        threshold.Add(label, config.Threshold)
+        precision.Add(label, c.Precision())
+        recall   .Add(label, c.TPR())
+        fpr      .Add(label, c.FPR())
+      }
+      if perf.P == 0 || perf.N == 0 {
+        continue
+      }
+      tables, err := serve_eval(config, perf, nil, nil, names); if err != nil {
+        PrintStderr(config, 1, "Session `%s': %v\n", session.Name, err)
+        continue
+      }
+      for i, table := range tables {
+        metrics[i].Add(label, table.Columns[0][0])
+      }
+    }
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    for _, family := range append([]prometheus_family{total, window, prevalence, threshold, precision, recall, fpr}, metrics...) {
+      family.Write(w)
+    }
+  }
+}
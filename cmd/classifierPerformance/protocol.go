@@ -0,0 +1,135 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "encoding/json"
+import   "fmt"
+import   "io"
+import   "net/url"
+import   "os"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Request of the JSON protocol, where predictions are either given inline or
+// by a file (local file or object store URI). Options are the request
+// options of the evaluate endpoint of the server.
+type ProtocolRequest struct {
+  Targets     []string       `json:"targets"`
+  Options     map[string]any `json:"options"`
+  Predictions []float64      `json:"predictions"`
+  Labels      []int          `json:"labels"`
+  File        string         `json:"file"`
+}
+
+// Options accepted by requests
+var protocol_options = []string{"method", "bins", "top", "fraction", "normalize-precision"}
+
+/* -------------------------------------------------------------------------- */
+
+func protocol_query(request ProtocolRequest) (url.Values, error) {
+  query := url.Values{}
+  for name, value := range request.Options {
+    ok := false
+    for _, option := range protocol_options {
+      ok = ok || name == option
+    }
+    if !ok {
+      return nil, fmt.Errorf("invalid option `%s'", name)
+    }
+    switch v := value.(type) {
+    case string:
+      query.Set(name, v)
+    case float64:
+      query.Set(name, strconv.FormatFloat(v, 'g', -1, 64))
+    case bool:
+      query.Set(name, strconv.FormatBool(v))
+    default:
+      return nil, fmt.Errorf("invalid value of option `%s'", name)
+    }
+  }
+  query["target"] = request.Targets
+  return query, nil
+}
+
+func protocol_read_predictions(request ProtocolRequest) ([]float64, []int, error) {
+  if request.File == "" {
+    return request.Predictions, request.Labels, serve_check_predictions(request.Predictions, request.Labels)
+  }
+  if request.Predictions != nil || request.Labels != nil {
+    return nil, nil, fmt.Errorf("predictions must be given either inline or by a file")
+  }
+  file, err := open_file(request.File); if err != nil {
+    return nil, nil, err
+  }
+  defer file.Close()
+  values, labels, err := ReadPredictions(file); if err != nil {
+    return nil, nil, fmt.Errorf("parsing `%s' failed: %v", request.File, err)
+  }
+  return values, labels, nil
+}
+
+func protocol_evaluate(config Config, reader io.Reader) (ServeResponse, error) {
+  request := ProtocolRequest{}
+  decoder := json.NewDecoder(reader)
+  decoder.DisallowUnknownFields()
+  if err := decoder.Decode(&request); err != nil {
+    return ServeResponse{}, fmt.Errorf("invalid request: %v", err)
+  }
+  query, err := protocol_query(request); if err != nil {
+    return ServeResponse{}, err
+  }
+  config, targets, err := serve_config(config, query); if err != nil {
+    return ServeResponse{}, err
+  }
+  values, labels, err := protocol_read_predictions(request); if err != nil {
+    return ServeResponse{}, err
+  }
+  if len(values) == 0 {
+    return ServeResponse{}, fmt.Errorf("no predictions given")
+  }
+  perf, err := eval_performance(config, append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    return ServeResponse{}, err
+  }
+  tables, err := serve_eval(config, perf, values, labels, targets); if err != nil {
+    return ServeResponse{}, err
+  }
+  return serve_response(perf, targets, tables), nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Read a single JSON request from stdin and write a single JSON response to
+// stdout, which has the same format as responses of the evaluate endpoint of
+// the server. Errors are reported as {"error": "..."} with a non-zero exit
+// status.
+func classifier_performance_protocol_json(config Config) {
+  encoder := json.NewEncoder(os.Stdout)
+  if response, err := protocol_evaluate(config, os.Stdin); err != nil {
+    encoder.Encode(ServeError{Error: err.Error()})
+    os.Exit(1)
+  } else {
+    if err := encoder.Encode(response); err != nil {
+      fmt.Fprintln(os.Stderr, err)
+      os.Exit(1)
+    }
+  }
+}
@@ -0,0 +1,51 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Population stability index of predictions relative to the predictions in a
+// reference file. The last row contains the totals and the overall index.
+func classifier_performance_psi(config Config, filename string) {
+  if config.ReferenceFile == "" {
+    log.Fatal("no reference file specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("psi is not supported in streaming mode")
+  }
+  reference, _ := import_predictions_cached(config, config.ReferenceFile)
+  current  , _ := import_predictions_cached(config, filename)
+  bins, psi, err := PopulationStability(reference, current, config.Bins); if err != nil {
+    log.Fatal(err)
+  }
+  if config.PrintHeader {
+    fmt.Println("min max reference current reference_pct current_pct psi")
+  }
+  for _, bin := range bins {
+    fmt.Printf("%f %f %d %d %f %f %f\n", bin.Min, bin.Max, bin.Reference, bin.Current,
+      100.0*float64(bin.Reference)/float64(len(reference)),
+      100.0*float64(bin.Current  )/float64(len(current  )), bin.PSI)
+  }
+  fmt.Printf("%f %f %d %d %f %f %f\n", bins[len(bins)-1].Min, bins[0].Max, len(reference), len(current), 100.0, 100.0, psi)
+}
@@ -0,0 +1,60 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "os"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Rank correlation between predictions of two models (the reference file
+// and the given file) on the same population, which shows if one model can
+// replace the other without changing the ranking of predictions
+func classifier_performance_rank_correlation(config Config, filename string) {
+  x, y, _ := import_challenger(config, filename)
+  PrintStderr(config, 1, "Comparing %d predictions\n", len(x))
+  if config.PrintHeader {
+    fmt.Printf("measure n estimate lower upper\n")
+  }
+  for _, f := range []func([]float64, []float64, float64) (RankCorrelation, error) {
+    Spearman[float64], Kendall[float64] } {
+    r, err := f(x, y, config.Confidence); if err != nil {
+      log.Fatal(err)
+    }
+    fmt.Printf("%s %d %f %f %f\n", r.Name, r.N, r.Value, r.Lower, r.Upper)
+  }
+}
+
+// Overlap between the top ranked predictions of two models (the reference
+// file and the given file) at the cutoffs given with --k, which shows how
+// many cases both models would flag
+func classifier_performance_top_k_overlap(config Config, filename string) {
+  x, y, _ := import_challenger(config, filename)
+  var cutoffs []int
+  if len(config.K) > 0 {
+    cutoffs = ranking_cutoffs(config, 0)
+  }
+  r, err := TopKOverlap(x, y, cutoffs); if err != nil {
+    log.Fatal(err)
+  }
+  export_table(config, os.Stdout, nil, r)
+}
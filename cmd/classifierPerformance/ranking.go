@@ -0,0 +1,325 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "sort"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Default cutoff of the hit-rate target
+const hit_rate_k = 10
+
+// Predictions of a single query, which are ranked by their values.
+// Relevance values are read from --relevance-column or otherwise given by
+// the labels, which may be graded if --relevance-threshold is given.
+type Query struct {
+  Name      string
+  Values    []float64
+  Labels    []int
+  Relevance []float64
+}
+
+// Column with queries, where --group-column is accepted for compatibility
+func query_column(config Config) string {
+  if config.QueryColumn != "" {
+    return config.QueryColumn
+  }
+  return config.GroupColumn
+}
+
+// Import predictions grouped by queries. Without query column, all
+// predictions form a single query.
+func import_queries(config Config, filename string) []Query {
+  if config.StreamBins > 0 {
+    log.Fatal("ranking measures are not supported in streaming mode")
+  }
+  column  := query_column(config)
+  columns := []string{}
+  if column != "" {
+    columns = append(columns, column)
+  }
+  if config.RelevanceColumn != "" {
+    columns = append(columns, config.RelevanceColumn)
+  }
+  var table PredictionTable
+  if len(columns) == 0 && math.IsNaN(config.RelevanceThreshold) {
+    table.Values, table.Labels = import_predictions_cached(config, filename)
+  } else {
+    table = import_table(config, filename, columns...)
+  }
+  queries := []Query{}
+  index   := map[string]int{}
+  for i := range table.Values {
+    name := "all"
+    if column != "" {
+      name = table.Columns[column][i]
+    }
+    k, ok := index[name]
+    if !ok {
+      k = len(queries)
+      index[name] = k
+      queries = append(queries, Query{Name: name})
+    }
+    relevance := float64(table.Labels[i])
+    if table.Relevance != nil {
+      relevance = table.Relevance[i]
+    }
+    if config.RelevanceColumn != "" {
+      s := table.Columns[config.RelevanceColumn][i]
+      v, err := strconv.ParseFloat(s, 64); if err != nil || v < 0.0 {
+        log.Fatalf("invalid value `%s' in column `%s'", s, config.RelevanceColumn)
+      }
+      relevance = v
+    }
+    q := &queries[k]
+    q.Values    = append(q.Values   , table.Values[i])
+    q.Labels    = append(q.Labels   , table.Labels[i])
+    q.Relevance = append(q.Relevance, relevance)
+  }
+  if column != "" {
+    PrintStderr(config, 1, "Found %d queries\n", len(queries))
+  }
+  return queries
+}
+
+// Cutoffs given with --k in increasing order, or cutoffs 1, 2, ..., n if
+// no cutoff is given
+func ranking_cutoffs(config Config, n int) []int {
+  r := append([]int{}, config.K...)
+  if len(r) == 0 {
+    for k := 1; k <= n; k++ {
+      r = append(r, k)
+    }
+  }
+  sort.Ints(r)
+  return r
+}
+
+// Evaluate a ranking measure on each query at each cutoff and print the mean
+// over queries, where undefined values (e.g. of queries without positives)
+// are skipped. Measures without cutoffs are evaluated with nil cutoffs and
+// return a single value. With --per-query, values of each query are printed
+// before the mean.
+func eval_queries(config Config, queries []Query, name string, cutoffs []int, f func(query Query, cutoffs []int) ([]float64, error)) {
+  m := max(len(cutoffs), 1)
+  s := make([]float64, m)
+  n := make([]int,     m)
+  r := make([][]float64, len(queries))
+  for q, query := range queries {
+    x, err := f(query, cutoffs); if err != nil {
+      log.Fatal(err)
+    }
+    for j := range x {
+      if math.IsNaN(x[j]) {
+        continue
+      }
+      s[j] += x[j]
+      n[j]++
+    }
+    r[q] = x
+  }
+  for j := range s {
+    s[j] /= float64(n[j])
+  }
+  if n[0] < len(queries) {
+    PrintStderr(config, 1, "Skipped %d queries with undefined %s\n", len(queries) - n[0], name)
+  }
+  // print a row for each query and cutoff
+  print_rows := func(query string, x []float64) {
+    for j := range x {
+      if config.PerQuery {
+        fmt.Printf("%s ", query)
+      }
+      if cutoffs != nil {
+        fmt.Printf("%f ", float64(cutoffs[j]))
+      }
+      fmt.Printf("%f\n", x[j])
+    }
+  }
+  if config.PrintHeader {
+    if config.PerQuery {
+      fmt.Print("query ")
+    }
+    if cutoffs != nil {
+      fmt.Print("k ")
+    }
+    fmt.Println(name)
+  }
+  if config.PerQuery {
+    for q, query := range queries {
+      print_rows(query.Name, r[q])
+    }
+    print_rows("mean", s)
+  } else
+  if cutoffs == nil && !config.PrintHeader {
+    fmt.Println(s[0])
+  } else {
+    print_rows("", s)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Normalized discounted cumulative gain at each cutoff, or of complete
+// rankings if no cutoff is given
+func classifier_performance_ndcg(config Config, filename string) {
+  queries := import_queries(config, filename)
+  var cutoffs []int
+  if len(config.K) > 0 {
+    cutoffs = ranking_cutoffs(config, 0)
+  }
+  eval_queries(config, queries, "ndcg", cutoffs, func(query Query, cutoffs []int) ([]float64, error) {
+    if cutoffs == nil {
+      x, err := NDCG(query.Values, query.Relevance, 0)
+      return []float64{x}, err
+    }
+    r := make([]float64, len(cutoffs))
+    for j, k := range cutoffs {
+      x, err := NDCG(query.Values, query.Relevance, k); if err != nil {
+        return nil, err
+      }
+      r[j] = x
+    }
+    return r, nil
+  })
+}
+
+// Expected reciprocal rank at each cutoff, or of complete rankings if no
+// cutoff is given. The maximum relevance of the scale is the largest
+// relevance value found in the input.
+func classifier_performance_err(config Config, filename string) {
+  queries := import_queries(config, filename)
+  var cutoffs []int
+  if len(config.K) > 0 {
+    cutoffs = ranking_cutoffs(config, 0)
+  }
+  max_relevance := 0.0
+  for _, query := range queries {
+    for _, v := range query.Relevance {
+      max_relevance = math.Max(max_relevance, v)
+    }
+  }
+  PrintStderr(config, 1, "Using maximum relevance %f\n", max_relevance)
+  eval_queries(config, queries, "err", cutoffs, func(query Query, cutoffs []int) ([]float64, error) {
+    if cutoffs == nil {
+      x, err := ERR(query.Values, query.Relevance, 0, max_relevance)
+      return []float64{x}, err
+    }
+    r := make([]float64, len(cutoffs))
+    for j, k := range cutoffs {
+      x, err := ERR(query.Values, query.Relevance, k, max_relevance); if err != nil {
+        return nil, err
+      }
+      r[j] = x
+    }
+    return r, nil
+  })
+}
+
+// Mean average precision (map) or mean reciprocal rank (mrr) over queries
+func classifier_performance_map(config Config, filename, target string) {
+  queries := import_queries(config, filename)
+  eval_queries(config, queries, target, nil, func(query Query, _ []int) ([]float64, error) {
+    f := AveragePrecision[float64]
+    if target == "mrr" {
+      f = ReciprocalRank[float64]
+    }
+    x, err := f(query.Values, query.Labels)
+    return []float64{x}, err
+  })
+}
+
+// Precision (precision@k) or recall (recall@k) of the k top ranked
+// predictions as a function of k, or at the cutoffs given with --k
+func classifier_performance_at_k(config Config, filename, target string) {
+  j := 1
+  if target == "recall@k" {
+    j = 2
+  }
+  if query_column(config) == "" {
+    // single ranking of all predictions, which is also supported in
+    // streaming mode
+    var perf Performance
+    if config.StreamBins > 0 {
+      perf = import_performance_binned(config, filename)
+    } else {
+      values, labels := import_predictions_cached(config, filename)
+      if p, err := eval_performance(config, values, labels); err != nil {
+        log.Fatal(err)
+      } else {
+        perf = p
+      }
+    }
+    var cutoffs []int
+    if len(config.K) > 0 {
+      cutoffs = ranking_cutoffs(config, 0)
+    }
+    r := PrecisionRecallAtK(perf, cutoffs)
+    export_table(config, os.Stdout, nil, Table{Names: []string{r.Names[0], r.Names[j]}, Columns: [][]float64{r.Columns[0], r.Columns[j]}})
+    return
+  }
+  queries := import_queries(config, filename)
+  n := 0
+  for _, query := range queries {
+    n = max(n, len(query.Values))
+  }
+  eval_queries(config, queries, target[:len(target)-2], ranking_cutoffs(config, n), func(query Query, cutoffs []int) ([]float64, error) {
+    perf, err := EvalPerformance(append([]float64{}, query.Values...), append([]int{}, query.Labels...)); if err != nil {
+      return nil, err
+    }
+    r := PrecisionRecallAtK(perf, cutoffs).Columns[j]
+    if perf.P == 0 {
+      // skip queries without positives
+      for i := range r {
+        r[i] = math.NaN()
+      }
+    }
+    return r, nil
+  })
+}
+
+// Fraction of groups (e.g. users) with at least one positive prediction among
+// their k top ranked predictions for each cutoff given with --k
+func classifier_performance_hit_rate(config Config, filename string) {
+  if query_column(config) == "" {
+    log.Fatal("hit-rate requires a query or group column")
+  }
+  queries := import_queries(config, filename)
+  cutoffs := []int{hit_rate_k}
+  if len(config.K) > 0 {
+    cutoffs = ranking_cutoffs(config, 0)
+  }
+  eval_queries(config, queries, "hit-rate", cutoffs, func(query Query, cutoffs []int) ([]float64, error) {
+    r := make([]float64, len(cutoffs))
+    for j, k := range cutoffs {
+      x, err := HitAtK(query.Values, query.Labels, k); if err != nil {
+        return nil, err
+      }
+      r[j] = x
+    }
+    return r, nil
+  })
+}
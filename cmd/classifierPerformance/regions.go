@@ -0,0 +1,244 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+import   "regexp"
+import   "strconv"
+import   "strings"
+
+import   "github.com/pbenner/gonetics"
+
+/* -------------------------------------------------------------------------- */
+
+// Read regions from a BED file with at least four columns, where the name
+// column holds the label of each region (1 or positive, 0 or negative)
+func read_labeled_regions(reader io.Reader) (gonetics.GRanges, []int, error) {
+  seqnames := []string{}
+  from     := []int{}
+  to       := []int{}
+  labels   := []int{}
+  scanner  := bufio.NewScanner(reader)
+  for n := 1; scanner.Scan(); n++ {
+    fields := strings.Fields(scanner.Text())
+    if len(fields) == 0 || fields[0] == "track" || fields[0] == "browser" || strings.HasPrefix(fields[0], "#") {
+      continue
+    }
+    if len(fields) < 4 {
+      return gonetics.GRanges{}, nil, fmt.Errorf("line %d: expected at least 4 columns but found %d", n, len(fields))
+    }
+    start, err1 := strconv.Atoi(fields[1])
+    end  , err2 := strconv.Atoi(fields[2])
+    if err1 != nil || err2 != nil || start < 0 || end <= start {
+      return gonetics.GRanges{}, nil, fmt.Errorf("line %d: invalid region `%s:%s-%s'", n, fields[0], fields[1], fields[2])
+    }
+    switch strings.ToLower(fields[3]) {
+    case "1", "positive":
+      labels = append(labels, 1)
+    case "0", "negative":
+      labels = append(labels, 0)
+    default:
+      return gonetics.GRanges{}, nil, fmt.Errorf("line %d: invalid label `%s'", n, fields[3])
+    }
+    seqnames = append(seqnames, fields[0])
+    from     = append(from, start)
+    to       = append(to, end)
+  }
+  if err := scanner.Err(); err != nil {
+    return gonetics.GRanges{}, nil, err
+  }
+  return gonetics.NewGRanges(seqnames, from, to, nil), labels, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Summary of scores within a region, where scores are accumulated as the sum
+// and maximum of values over all covered bases
+type region_score struct {
+  Sum, Max, N float64
+}
+
+func new_region_scores(n int) []region_score {
+  r := make([]region_score, n)
+  for i := range r {
+    r[i].Max = math.Inf(-1)
+  }
+  return r
+}
+
+func (obj *region_score) Add(value float64, n int) {
+  obj.Sum += value*float64(n)
+  obj.N   += float64(n)
+  obj.Max  = math.Max(obj.Max, value)
+}
+
+// Score of a region given by --region-score, which is NaN if the region is
+// not covered by the track
+func (obj region_score) Eval(config Config) float64 {
+  if obj.N == 0 {
+    return math.NaN()
+  }
+  if config.RegionScore == "mean" {
+    return obj.Sum/obj.N
+  }
+  return obj.Max
+}
+
+// Bin size of a bigWig file, which is given by the raw records next to the
+// first region that is covered by the track. The raw data is queried in
+// growing windows around the region, so that only a small part of the file
+// is read. GetBinSize of gonetics is not used, since it stops its query by
+// sending on a channel that may already be closed for small files.
+func bigwig_bin_size(reader *gonetics.BigWigReader, regions gonetics.GRanges) (int, error) {
+  for i := 0; i < regions.Length(); i++ {
+    length, err := reader.Genome.SeqLength(regions.Seqnames[i]); if err != nil {
+      // sequence is not covered by the track
+      continue
+    }
+    for w := 1024;; w *= 4 {
+      from := max(0, regions.Ranges[i].From - w)
+      to   := min(length, regions.Ranges[i].To + w)
+      binSize := 0
+      // a bin size of zero queries raw records
+      for record := range reader.Query(regexp.QuoteMeta(regions.Seqnames[i]), from, to, 0) {
+        if record.Error != nil {
+          return 0, record.Error
+        }
+        if record.DataType == gonetics.BbiTypeBedGraph {
+          return 0, fmt.Errorf("data has type bedGraph, which is not supported")
+        }
+        if binSize == 0 {
+          binSize = record.To - record.From
+        }
+      }
+      if binSize > 0 {
+        return binSize, nil
+      }
+      if from == 0 && to == length {
+        break
+      }
+    }
+  }
+  return 0, fmt.Errorf("no regions are covered by the track")
+}
+
+// Extract scores of regions from a bigWig file, which are resolved at the
+// bin size of the file. The mean is weighted by the number of bases of each
+// bin that overlap the region.
+func bigwig_region_scores(filename string, regions gonetics.GRanges) ([]region_score, error) {
+  f, err := os.Open(filename); if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  reader, err := gonetics.NewBigWigReader(f); if err != nil {
+    return nil, fmt.Errorf("reading bigWig file `%s' failed: %v", filename, err)
+  }
+  binSize, err := bigwig_bin_size(reader, regions); if err != nil {
+    return nil, fmt.Errorf("reading bigWig file `%s' failed: %v", filename, err)
+  }
+  r := new_region_scores(regions.Length())
+  for i := 0; i < regions.Length(); i++ {
+    // query all bins overlapping the region
+    from := (regions.Ranges[i].From/binSize)*binSize
+    to   := ((regions.Ranges[i].To + binSize - 1)/binSize)*binSize
+    for record := range reader.Query(regexp.QuoteMeta(regions.Seqnames[i]), from, to, binSize) {
+      if record.Error != nil {
+        return nil, fmt.Errorf("reading bigWig file `%s' failed: %v", filename, record.Error)
+      }
+      // bins at the boundaries of the region only partially overlap the
+      // region, their statistics are weighted by the fraction of
+      // overlapping bases as for bedGraph files
+      n := min(regions.Ranges[i].To, record.To) - max(regions.Ranges[i].From, record.From)
+      if record.Valid > 0 && n > 0 {
+        w := float64(n)/float64(record.To - record.From)
+        r[i].Sum += w*record.Sum
+        r[i].N   += w*record.Valid
+        r[i].Max  = math.Max(r[i].Max, record.Max)
+      }
+    }
+  }
+  return r, nil
+}
+
+// Extract scores of regions from a bedGraph file, where the mean is weighted
+// by the number of bases of each interval that overlap the region
+func bedgraph_region_scores(config Config, filename string, regions gonetics.GRanges) []region_score {
+  track := gonetics.GRanges{}
+  import_file(config, filename, func(reader io.Reader) error {
+    return track.ReadBedGraph(reader)
+  })
+  values := track.GetMetaFloat("values")
+  r := new_region_scores(regions.Length())
+  queryHits, subjectHits := gonetics.FindOverlaps(regions, track)
+  for k := range queryHits {
+    i, j := queryHits[k], subjectHits[k]
+    n := min(regions.Ranges[i].To, track.Ranges[j].To) - max(regions.Ranges[i].From, track.Ranges[j].From)
+    r[i].Add(values[j], n)
+  }
+  return r
+}
+
+// Import predictions of regions given by --regions, where scores are
+// extracted from a bigWig (.bw, .bigWig) or bedGraph file. Regions that are
+// not covered by the track are skipped.
+func import_region_predictions(config Config, filename string) ([]float64, []int) {
+  PrintStderr(config, 1, "Reading regions from `%s'... ", config.Regions)
+  f, err := open_file(config.Regions); if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatal(err)
+  }
+  regions, labels, err := read_labeled_regions(f)
+  f.Close()
+  if err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatalf("parsing `%s' failed at %v", config.Regions, err)
+  }
+  PrintStderr(config, 1, "done\n")
+  var scores []region_score
+  if ext := strings.ToLower(filename); strings.HasSuffix(ext, ".bw") || strings.HasSuffix(ext, ".bigwig") {
+    if is_object_uri(filename) {
+      log.Fatal("bigWig files in object stores are not supported")
+    }
+    PrintStderr(config, 1, "Reading scores from `%s'... ", filename)
+    scores, err = bigwig_region_scores(filename, regions); if err != nil {
+      PrintStderr(config, 1, "failed\n")
+      log.Fatal(err)
+    }
+    PrintStderr(config, 1, "done\n")
+  } else {
+    scores = bedgraph_region_scores(config, filename, regions)
+  }
+  values := []float64{}
+  l      := []int{}
+  for i := range scores {
+    if v := scores[i].Eval(config); !math.IsNaN(v) {
+      values = append(values, v)
+      l      = append(l, labels[i])
+    }
+  }
+  if n := len(scores) - len(values); n > 0 {
+    fmt.Fprintf(os.Stderr, "notice: skipped %d of %d regions without scores\n", n, len(scores))
+  }
+  return values, l
+}
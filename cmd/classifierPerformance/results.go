@@ -0,0 +1,103 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "io"
+import   "log"
+
+import   "google.golang.org/protobuf/proto"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+import   "github.com/pbenner/classifierPerformance/pkg/evaluationService"
+
+/* -------------------------------------------------------------------------- */
+
+// Conversion of results to the protobuf messages defined in
+// pkg/evaluationService/results.proto, which are shared by the command line
+// tool (--format proto) and the gRPC evaluation service
+
+func proto_table(target string, table Table) *evaluationService.Table {
+  r := &evaluationService.Table{Target: target, Names: table.Names}
+  for _, column := range table.Columns {
+    r.Columns = append(r.Columns, &evaluationService.Column{Values: column})
+  }
+  return r
+}
+
+// Values of scalar metrics, where lower and upper bounds are optional
+func proto_metrics(names []string, values, lower, upper []float64) []*evaluationService.MetricValue {
+  r := make([]*evaluationService.MetricValue, len(names))
+  for i, name := range names {
+    r[i] = &evaluationService.MetricValue{Name: name, Value: values[i]}
+    if lower != nil && upper != nil {
+      r[i].Lower = proto.Float64(lower[i])
+      r[i].Upper = proto.Float64(upper[i])
+    }
+  }
+  return r
+}
+
+// Confusion matrices at all thresholds of a performance object
+func proto_confusion_matrices(perf Performance) []*evaluationService.ConfusionMatrix {
+  r := make([]*evaluationService.ConfusionMatrix, perf.Len())
+  for i := range r {
+    r[i] = &evaluationService.ConfusionMatrix{
+      Threshold     : perf.Tr[i],
+      TruePositives : int64(perf.Tp[i]),
+      FalsePositives: int64(perf.Fp[i]),
+      TrueNegatives : int64(perf.Tn[i]),
+      FalseNegatives: int64(perf.Fn[i]) }
+  }
+  return r
+}
+
+// Empty results with the number of positives and negatives of perf, which
+// are omitted if perf is nil
+func proto_counts(perf *Performance) *evaluationService.Results {
+  if perf == nil {
+    return &evaluationService.Results{}
+  }
+  return &evaluationService.Results{Positives: int64(perf.P), Negatives: int64(perf.N)}
+}
+
+// Results of targets evaluated on perf, where scalar metrics are reported
+// as metric values and all other metrics as tables
+func proto_results(perf Performance, targets []string, tables []Table) *evaluationService.Results {
+  r := proto_counts(&perf)
+  for i, target := range targets {
+    if m, ok := LookupMetric(target); ok && m.Kind() == ScalarMetric {
+      r.Metrics = append(r.Metrics, &evaluationService.MetricValue{Name: target, Value: tables[i].Columns[0][0]})
+    } else {
+      r.Tables = append(r.Tables, proto_table(target, tables[i]))
+    }
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Export results as a binary protobuf message
+func export_proto(writer io.Writer, results *evaluationService.Results) {
+  data, err := proto.Marshal(results); if err != nil {
+    log.Fatal(err)
+  }
+  if _, err := writer.Write(data); err != nil {
+    log.Fatal(err)
+  }
+}
@@ -0,0 +1,96 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "log"
+import   "math"
+import   "os"
+import   "strconv"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Parse a comma separated list of non-negative numbers, e.g. noise levels
+func parse_levels(s, name string) []float64 {
+  r := []float64{}
+  for _, field := range strings.Split(s, ",") {
+    v, err := strconv.ParseFloat(strings.TrimSpace(field), 64); if err != nil || !(v >= 0.0) {
+      log.Fatalf("invalid %s: %s", name, field)
+    }
+    r = append(r, v)
+  }
+  return r
+}
+
+// Robustness profile of predictions, i.e. the mean and standard deviation of
+// the roc-auc and the optimal threshold of the ROC curve over --repetitions
+// noisy replicates, for each combination of noise levels given by
+// --noise-sd and --label-flip
+func classifier_performance_robustness(config Config, filename string) {
+  if config.StreamBins > 0 {
+    log.Fatal("robustness analysis is not supported in streaming mode")
+  }
+  if config.Repetitions <= 0 {
+    log.Fatal("robustness analysis requires a positive number of repetitions")
+  }
+  values, labels := import_predictions_cached(config, filename)
+  f := func(values []float64, labels []int) ([]float64, error) {
+    perf, err := eval_performance(config, values, labels); if err != nil {
+      return nil, err
+    }
+    if perf.P == 0 || perf.N == 0 {
+      // replicate contains only a single class
+      return []float64{math.NaN(), math.NaN()}, nil
+    }
+    metrics := new_metrics(config, perf, values, labels)
+    auc, err := metrics.RocAUC(); if err != nil {
+      return nil, err
+    }
+    roc := metrics.Roc()
+    return []float64{auc, roc.Tr[OptimumRoc(roc.Tr, roc.X, roc.Y)]}, nil
+  }
+  table := Table{Names: []string{"noise-sd", "label-flip", "roc-auc", "roc-auc-sd", "optimal-threshold", "optimal-threshold-sd"}, Columns: make([][]float64, 6)}
+  rng   := new_rng(config)
+  for _, flip := range config.LabelFlip {
+    for _, sd := range config.NoiseSd {
+      PrintStderr(config, 1, "Evaluating replicates with noise sd %v and %v flipped labels... ", sd, flip)
+      r, err := Perturb(values, labels, sd, flip, config.Repetitions, rng, f); if err != nil {
+        PrintStderr(config, 1, "failed\n")
+        log.Fatal(err)
+      }
+      PrintStderr(config, 1, "done\n")
+      auc := []float64{}
+      thr := []float64{}
+      for i := range r {
+        if !math.IsNaN(r[i][0]) {
+          auc = append(auc, r[i][0])
+          thr = append(thr, r[i][1])
+        }
+      }
+      auc_mean, auc_sd := MeanSd(auc)
+      thr_mean, thr_sd := MeanSd(thr)
+      for j, v := range []float64{sd, flip, auc_mean, auc_sd, thr_mean, thr_sd} {
+        table.Columns[j] = append(table.Columns[j], v)
+      }
+    }
+  }
+  export_table(config, os.Stdout, nil, table)
+}
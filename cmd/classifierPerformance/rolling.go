@@ -0,0 +1,129 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "context"
+import   "fmt"
+import   "log"
+import   "math"
+import   "os"
+import   "os/signal"
+import   "strconv"
+import   "sync"
+import   "syscall"
+import   "time"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Parse the report interval of the rolling target, which is either a number
+// of rows or a duration such as 30s
+func parse_report_every(s string) (int, time.Duration, error) {
+  if n, err := strconv.Atoi(s); err == nil && n > 0 {
+    return n, 0, nil
+  }
+  if d, err := time.ParseDuration(s); err == nil && d > 0 {
+    return 0, d, nil
+  }
+  return 0, 0, fmt.Errorf("invalid report interval: %s", s)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Read an unbounded stream of predictions and periodically report metrics of
+// the most recent --window-size predictions. On SIGINT or SIGTERM, reading
+// stops and the remaining predictions are reported.
+func classifier_performance_rolling(config Config, filename string) {
+  if config.WindowSize <= 0 {
+    log.Fatal("rolling target requires a window size")
+  }
+  if !math.IsNaN(config.RelevanceThreshold) || config.StreamBins > 0 {
+    log.Fatal("rolling target does not support graded relevance labels or binning")
+  }
+  // report once per window by default
+  rows, interval := config.WindowSize, time.Duration(0)
+  if config.ReportEvery != "" {
+    if n, d, err := parse_report_every(config.ReportEvery); err != nil {
+      log.Fatal(err)
+    } else {
+      rows, interval = n, d
+    }
+  }
+  evaluator, err := NewWindowEvaluator(config.WindowSize); if err != nil {
+    log.Fatal(err)
+  }
+  metrics := summary_metrics(config, true)
+  if config.PrintHeader {
+    fmt.Print("time rows n prevalence")
+    for _, name := range metrics {
+      fmt.Printf(" %s", name)
+    }
+    fmt.Println()
+  }
+  // total number of rows read and at the last report
+  var mutex sync.Mutex
+  total, last := 0, 0
+  report := func() {
+    if total == last {
+      return
+    }
+    last = total
+    values, labels := evaluator.Predictions()
+    perf, err := eval_performance(config, values, labels); if err != nil {
+      fmt.Fprintf(os.Stderr, "notice: skipping report at row %d: %v\n", total, err)
+      return
+    }
+    r, err := new_metrics(config, perf, values, labels).Eval(metrics...); if err != nil {
+      fmt.Fprintf(os.Stderr, "notice: skipping report at row %d: %v\n", total, err)
+      return
+    }
+    fmt.Printf("%s %d %d %f", time.Now().Format(time.RFC3339), total, len(values), float64(perf.P)/float64(perf.P + perf.N))
+    for i := range metrics {
+      fmt.Printf(" %f", r[i])
+    }
+    fmt.Println()
+  }
+  if interval > 0 {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    go func() {
+      for range ticker.C {
+        mutex.Lock()
+        report()
+        mutex.Unlock()
+      }
+    }()
+  }
+  ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+  defer stop()
+  scan_predictions_context(ctx, config, filename, func(value float64, label int) error {
+    mutex.Lock()
+    defer mutex.Unlock()
+    evaluator.Add(value, label)
+    if total++; interval == 0 && total % rows == 0 {
+      report()
+    }
+    return nil
+  })
+  // report remaining predictions at the end of the stream or on interrupt
+  mutex.Lock()
+  report()
+  mutex.Unlock()
+}
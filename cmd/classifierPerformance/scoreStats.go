@@ -0,0 +1,59 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Distribution of scores of positives and negatives, and effect sizes that
+// quantify their separation. The overlap coefficient is computed from
+// histograms with --bins bins.
+func classifier_performance_score_stats(config Config, filename string) {
+  if config.StreamBins > 0 {
+    log.Fatal("score-stats is not supported in streaming mode")
+  }
+  values, labels := import_predictions_cached(config, filename)
+  pos, neg, err := SplitScores(values, labels); if err != nil {
+    log.Fatal(err)
+  }
+  if len(pos) == 0 || len(neg) == 0 {
+    log.Fatal("score-stats requires positive and negative predictions")
+  }
+  bins, err := ClassHistogram(values, labels, config.Bins); if err != nil {
+    log.Fatal(err)
+  }
+  if config.PrintHeader {
+    fmt.Println("statistic value")
+  }
+  for _, class := range []struct{ Name string; Scores []float64 }{{"positives", pos}, {"negatives", neg}} {
+    s := ScoreStats(class.Scores)
+    fmt.Printf("%s-n %d\n", class.Name, s.N)
+    for _, stat := range []struct{ Name string; Value float64 }{
+      {"mean", s.Mean}, {"sd", s.Sd}, {"min", s.Min}, {"q05", s.Q05}, {"q25", s.Q25},
+      {"median", s.Median}, {"q75", s.Q75}, {"q95", s.Q95}, {"max", s.Max}} {
+      fmt.Printf("%s-%s %f\n", class.Name, stat.Name, stat.Value)
+    }
+  }
+  fmt.Printf("cohens-d %f\n", CohensD(pos, neg))
+  fmt.Printf("overlap %f\n", OverlapCoefficient(bins))
+}
@@ -0,0 +1,386 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "encoding/csv"
+import   "encoding/json"
+import   "errors"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "net/http"
+import   "net/url"
+import   "strconv"
+import   "strings"
+import   "time"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Default address of the server
+const serve_address = ":8080"
+
+// Request bodies are read into memory and therefore limited in size
+const serve_max_body = 64 << 20
+
+// Timeouts of the HTTP server
+const (
+  serve_read_timeout  = 1*time.Minute
+  serve_write_timeout = 5*time.Minute
+  serve_idle_timeout  = 2*time.Minute
+)
+
+// Body of JSON requests
+type ServeRequest struct {
+  Predictions []float64 `json:"predictions"`
+  Labels      []int     `json:"labels"`
+}
+
+// Results of an evaluation, where each target maps column names to values.
+// Scalar metrics have a single column with a single value.
+type ServeResponse struct {
+  N         int                               `json:"n"`
+  Positives int                               `json:"positives"`
+  Negatives int                               `json:"negatives"`
+  Results   map[string]map[string][]JSONFloat `json:"results"`
+}
+
+// Numbers of predictions added to a session
+type ServeSession struct {
+  Session   string `json:"session"`
+  Positives int    `json:"positives"`
+  Negatives int    `json:"negatives"`
+}
+
+type ServeError struct {
+  Error string `json:"error"`
+}
+
+/* -------------------------------------------------------------------------- */
+
+func serve_json(w http.ResponseWriter, status int, v any) {
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(status)
+  if err := json.NewEncoder(w).Encode(v); err != nil {
+    log.Printf("writing response failed: %v", err)
+  }
+}
+
+func serve_error(w http.ResponseWriter, status int, err error) {
+  serve_json(w, status, ServeError{Error: err.Error()})
+}
+
+// Status of errors caused by a request
+func serve_status(err error) int {
+  var e *http.MaxBytesError
+  switch {
+  case errors.As(err, &e):
+    return http.StatusRequestEntityTooLarge
+  case errors.Is(err, errTooManySessions):
+    return http.StatusServiceUnavailable
+  }
+  return http.StatusBadRequest
+}
+
+// Limit the size of request bodies
+func serve_limit(handler http.HandlerFunc) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    r.Body = http.MaxBytesReader(w, r.Body, serve_max_body)
+    handler(w, r)
+  }
+}
+
+// Read predictions from a CSV table with a header, which contains columns
+// called predictions and labels
+func serve_read_csv(reader io.Reader) ([]float64, []int, error) {
+  records, err := csv.NewReader(reader).ReadAll(); if err != nil {
+    return nil, nil, err
+  }
+  if len(records) == 0 {
+    return nil, nil, nil
+  }
+  i_predictions, i_labels := -1, -1
+  for i, name := range records[0] {
+    switch strings.TrimSpace(name) {
+    case "predictions", "prediction": i_predictions = i
+    case "labels"     , "label"     : i_labels      = i
+    }
+  }
+  if i_predictions == -1 {
+    return nil, nil, fmt.Errorf("no column called `predictions' found")
+  }
+  if i_labels == -1 {
+    return nil, nil, fmt.Errorf("no column called `labels' found")
+  }
+  values := make([]float64, len(records)-1)
+  labels := make([]int,     len(records)-1)
+  for i, record := range records[1:] {
+    v, err := strconv.ParseFloat(strings.TrimSpace(record[i_predictions]), 64); if err != nil {
+      return nil, nil, fmt.Errorf("line %d: invalid prediction `%s'", i+2, record[i_predictions])
+    }
+    if math.IsNaN(v) {
+      return nil, nil, fmt.Errorf("line %d: prediction is not a number", i+2)
+    }
+    l, err := strconv.Atoi(strings.TrimSpace(record[i_labels])); if err != nil || (l != 0 && l != 1) {
+      return nil, nil, fmt.Errorf("line %d: invalid label `%s'", i+2, record[i_labels])
+    }
+    values[i] = v
+    labels[i] = l
+  }
+  return values, labels, nil
+}
+
+// Read predictions from the request body, which is either JSON, CSV, or a
+// whitespace separated table as read from files
+func serve_read_predictions(r *http.Request) ([]float64, []int, error) {
+  content_type := strings.ToLower(strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0]))
+  switch content_type {
+  case "application/json":
+    request := ServeRequest{}
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+      return nil, nil, err
+    }
+    if err := serve_check_predictions(request.Predictions, request.Labels); err != nil {
+      return nil, nil, err
+    }
+    return request.Predictions, request.Labels, nil
+  case "text/csv":
+    return serve_read_csv(r.Body)
+  default:
+    // stop reading as soon as the client disconnects
+    return ReadPredictionsContext(r.Context(), r.Body)
+  }
+}
+
+// Options of a request given as query parameters, which override the
+// options of the server
+func serve_config(config Config, query url.Values) (Config, []string, error) {
+  targets := []string{}
+  for _, s := range query["target"] {
+    for _, t := range strings.Split(s, ",") {
+      if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+        targets = append(targets, t)
+      }
+    }
+  }
+  if s := query.Get("method"); s != "" {
+    config.Method = s
+  }
+  for _, name := range []string{"bins", "top"} {
+    if s := query.Get(name); s != "" {
+      v, err := strconv.Atoi(s); if err != nil || v < 0 {
+        return config, nil, fmt.Errorf("invalid value `%s' of parameter `%s'", s, name)
+      }
+      if name == "bins" {
+        config.Bins = v
+      } else {
+        config.Top  = v
+      }
+    }
+  }
+  if s := query.Get("fraction"); s != "" {
+    v, err := strconv.ParseFloat(s, 64); if err != nil || v <= 0.0 || v > 1.0 {
+      return config, nil, fmt.Errorf("invalid value `%s' of parameter `fraction'", s)
+    }
+    config.Fraction = v
+  }
+  if s := query.Get("normalize-precision"); s != "" {
+    v, err := strconv.ParseBool(s); if err != nil {
+      return config, nil, fmt.Errorf("invalid value `%s' of parameter `normalize-precision'", s)
+    }
+    config.NormalizePrecision = v
+  }
+  return config, serve_expand_targets(config, targets, true), nil
+}
+
+// Expand the summary target into its metrics, which is also the default if
+// no targets are given. Without raw predictions, metrics that require them
+// are skipped.
+func serve_expand_targets(config Config, targets []string, raw bool) []string {
+  r := []string{}
+  for _, t := range targets {
+    if t == "summary" {
+      r = append(r, summary_metrics(config, raw)...)
+    } else {
+      r = append(r, t)
+    }
+  }
+  if len(r) == 0 {
+    r = summary_metrics(config, raw)
+  }
+  return r
+}
+
+func serve_check_predictions(values []float64, labels []int) error {
+  if len(values) != len(labels) {
+    return fmt.Errorf("number of predictions and labels do not match")
+  }
+  for _, l := range labels {
+    if l != 0 && l != 1 {
+      return fmt.Errorf("invalid label: %d", l)
+    }
+  }
+  for _, v := range values {
+    if math.IsNaN(v) {
+      return fmt.Errorf("invalid prediction: NaN")
+    }
+  }
+  return nil
+}
+
+// Evaluate targets on the given performance, where raw predictions are nil
+// for predictions accumulated in sessions. Invalid targets are reported
+// before any metric is evaluated.
+func serve_eval(config Config, perf Performance, values []float64, labels []int, targets []string) ([]Table, error) {
+  metrics := make([]Metric, len(targets))
+  for i, target := range targets {
+    if m, ok := LookupMetric(target); !ok {
+      return nil, fmt.Errorf("invalid target: %s", target)
+    } else {
+      metrics[i] = m
+    }
+  }
+  data := new_metrics(config, perf, values, labels)
+  r    := make([]Table, len(targets))
+  for i, m := range metrics {
+    table, err := m.Eval(data); if err != nil {
+      return nil, fmt.Errorf("target `%s': %v", targets[i], err)
+    }
+    r[i] = table
+  }
+  return r, nil
+}
+
+func serve_response(perf Performance, targets []string, tables []Table) ServeResponse {
+  response := ServeResponse{N: perf.P + perf.N, Positives: perf.P, Negatives: perf.N, Results: map[string]map[string][]JSONFloat{}}
+  for i, table := range tables {
+    result := map[string][]JSONFloat{}
+    for j, name := range table.Names {
+      column := make([]JSONFloat, len(table.Columns[j]))
+      for k, v := range table.Columns[j] {
+        column[k] = JSONFloat(v)
+      }
+      result[name] = column
+    }
+    response.Results[targets[i]] = result
+  }
+  return response
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Evaluate registered metrics on predictions given in the request body. The
+// targets are given by one or more target parameters [default: summary].
+func serve_evaluate(config Config) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+      serve_error(w, http.StatusMethodNotAllowed, fmt.Errorf("method `%s' not allowed", r.Method))
+      return
+    }
+    config, targets, err := serve_config(config, r.URL.Query()); if err != nil {
+      serve_error(w, http.StatusBadRequest, err)
+      return
+    }
+    values, labels, err := serve_read_predictions(r); if err != nil {
+      serve_error(w, serve_status(err), err)
+      return
+    }
+    if len(values) == 0 {
+      serve_error(w, http.StatusBadRequest, fmt.Errorf("no predictions given"))
+      return
+    }
+    perf, err := eval_performance(config, append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+      serve_error(w, http.StatusBadRequest, err)
+      return
+    }
+    tables, err := serve_eval(config, perf, values, labels, targets); if err != nil {
+      serve_error(w, http.StatusBadRequest, err)
+      return
+    }
+    PrintStderr(config, 1, "Evaluated %d predictions from %s\n", len(values), r.RemoteAddr)
+    serve_json(w, http.StatusOK, serve_response(perf, targets, tables))
+  }
+}
+
+// Add predictions given in the request body to the session given by the
+// session parameter, which is created if it does not exist
+func serve_add(config Config, sessions *Sessions) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+      serve_error(w, http.StatusMethodNotAllowed, fmt.Errorf("method `%s' not allowed", r.Method))
+      return
+    }
+    values, labels, err := serve_read_predictions(r); if err != nil {
+      serve_error(w, serve_status(err), err)
+      return
+    }
+    session, err := sessions.Get(r.URL.Query().Get("session"), true); if err != nil {
+      serve_error(w, serve_status(err), err)
+      return
+    }
+    session.Add(values, labels)
+    p, n := session.Counts()
+    serve_json(w, http.StatusOK, ServeSession{Session: session.Name, Positives: p, Negatives: n})
+  }
+}
+
+// List of targets accepted by the evaluate endpoint
+func serve_targets(w http.ResponseWriter, r *http.Request) {
+  serve_json(w, http.StatusOK, append(RegisteredMetrics(), "summary"))
+}
+
+// Serve the evaluation of predictions over HTTP. Endpoints are
+//   POST /evaluate  evaluate predictions given as JSON ({"predictions": [...],
+//                   "labels": [...]}), CSV (Content-Type text/csv), or as a
+//                   whitespace separated table
+//   POST /add       add predictions to the session given by the session
+//                   parameter
+//   GET  /targets   list of targets
+//   GET  /metrics   metrics of all sessions in the Prometheus text format
+// With --grpc, the gRPC evaluation service is started as well, which shares
+// its sessions with the HTTP server. Request bodies are limited to
+// serve_max_body bytes and the number of sessions to serve_max_sessions.
+func classifier_performance_serve(config Config, address string) {
+  if address == "" {
+    address = serve_address
+  }
+  if config.WindowSize > 0 && config.StreamBins > 0 {
+    log.Fatal("options --window-size and --stream-bins cannot be combined")
+  }
+  sessions := new_sessions(config)
+  if config.Grpc != "" {
+    classifier_performance_grpc(config, config.Grpc, sessions)
+  }
+  mux := http.NewServeMux()
+  mux.HandleFunc("/evaluate", serve_limit(serve_evaluate(config)))
+  mux.HandleFunc("/add"     , serve_limit(serve_add(config, sessions)))
+  mux.HandleFunc("/targets" , serve_targets)
+  mux.HandleFunc("/metrics" , serve_prometheus(config, sessions))
+  server := &http.Server{
+    Addr             : address,
+    Handler          : mux,
+    ReadHeaderTimeout: serve_read_timeout,
+    ReadTimeout      : serve_read_timeout,
+    WriteTimeout     : serve_write_timeout,
+    IdleTimeout      : serve_idle_timeout }
+  PrintStderr(config, 1, "Listening on %s\n", address)
+  log.Fatal(server.ListenAndServe())
+}
@@ -0,0 +1,149 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "encoding/json"
+import   "math"
+import   "net/http"
+import   "net/http/httptest"
+import   "slices"
+import   "strconv"
+import   "strings"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func test_serve(handler http.HandlerFunc, method, target, content_type, body string) *httptest.ResponseRecorder {
+  r := httptest.NewRequest(method, target, strings.NewReader(body))
+  if content_type != "" {
+    r.Header.Set("Content-Type", content_type)
+  }
+  w := httptest.NewRecorder()
+  serve_limit(handler)(w, r)
+  return w
+}
+
+func test_serve_response(t *testing.T, w *httptest.ResponseRecorder) ServeResponse {
+  r := ServeResponse{}
+  if w.Code != http.StatusOK {
+    t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+  }
+  if err := json.NewDecoder(w.Body).Decode(&r); err != nil {
+    t.Fatal(err)
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Predictions given as JSON, CSV, or table must give the same results
+func TestServeEvaluate(t *testing.T) {
+  handler := serve_evaluate(test_config())
+  body_json, _ := json.Marshal(ServeRequest{Predictions: test_values, Labels: test_labels})
+  body_csv     := "predictions,labels\n"
+  body_table   := "predictions labels\n"
+  for i := range test_values {
+    value := strconv.FormatFloat(test_values[i], 'g', -1, 64)
+    label := strconv.Itoa(test_labels[i])
+    body_csv   += value + "," + label + "\n"
+    body_table += value + " " + label + "\n"
+  }
+  r1 := test_serve_response(t, test_serve(handler, http.MethodPost, "/evaluate?target=roc-auc,ks", "application/json", string(body_json)))
+  r2 := test_serve_response(t, test_serve(handler, http.MethodPost, "/evaluate?target=roc-auc&target=ks", "text/csv", body_csv))
+  r3 := test_serve_response(t, test_serve(handler, http.MethodPost, "/evaluate?target=roc-auc,ks", "", body_table))
+  if r1.N != 12 || r1.Positives != 6 || r1.Negatives != 6 || len(r1.Results) != 2 {
+    t.Errorf("unexpected response: %v", r1)
+  }
+  for _, r := range []ServeResponse{r2, r3} {
+    for _, target := range []string{"roc-auc", "ks"} {
+      v1 := r1.Results[target][target]
+      v2 := r .Results[target][target]
+      if len(v1) != 1 || len(v2) != 1 || math.Abs(float64(v1[0]) - float64(v2[0])) > 1e-12 {
+        t.Errorf("target `%s': unexpected results %v and %v", target, v1, v2)
+      }
+    }
+  }
+  // the default target is the summary
+  if r := test_serve_response(t, test_serve(handler, http.MethodPost, "/evaluate", "application/json", string(body_json))); len(r.Results) != len(summary_metrics(test_config(), true)) {
+    t.Errorf("unexpected summary: %v", r.Results)
+  }
+}
+
+func TestServeEvaluateInvalid(t *testing.T) {
+  handler := serve_evaluate(test_config())
+  for _, test := range []struct {
+    method, target, content_type, body string
+    status int
+  }{
+    {http.MethodGet , "/evaluate"              , "application/json", `{"predictions": [0.5], "labels": [1]}`        , http.StatusMethodNotAllowed},
+    {http.MethodPost, "/evaluate?target=foo"   , "application/json", `{"predictions": [0.5, 0.4], "labels": [1, 0]}`, http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate?bins=-1"      , "application/json", `{"predictions": [0.5, 0.4], "labels": [1, 0]}`, http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate?fraction=2"   , "application/json", `{"predictions": [0.5, 0.4], "labels": [1, 0]}`, http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate"              , "application/json", `{"predictions": [0.5, 0.4], "labels": [1]}`   , http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate"              , "application/json", `{"predictions": [0.5, 0.4], "labels": [1, 2]}`, http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate"              , "application/json", `{"predictions": [], "labels": []}`            , http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate"              , "application/json", `{"predictions": [0.5`                         , http.StatusBadRequest      },
+    {http.MethodPost, "/evaluate"              , "text/csv"        , "predictions,labels\nNaN,1\n0.4,0\n"           , http.StatusBadRequest      },
+  } {
+    if w := test_serve(handler, test.method, test.target, test.content_type, test.body); w.Code != test.status {
+      t.Errorf("request `%s %s' with body `%s': status %d instead of %d", test.method, test.target, test.body, w.Code, test.status)
+    }
+  }
+}
+
+func TestServeAdd(t *testing.T) {
+  sessions := new_sessions(test_config())
+  handler  := serve_add(test_config(), sessions)
+  for i := 0; i < 2; i++ {
+    w := test_serve(handler, http.MethodPost, "/add?session=a", "application/json", `{"predictions": [0.9, 0.2, 0.1], "labels": [1, 0, 0]}`)
+    if w.Code != http.StatusOK {
+      t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+    }
+    r := ServeSession{}
+    if err := json.NewDecoder(w.Body).Decode(&r); err != nil {
+      t.Fatal(err)
+    }
+    if r.Session != "a" || r.Positives != i+1 || r.Negatives != 2*(i+1) {
+      t.Errorf("unexpected session: %v", r)
+    }
+  }
+  if w := test_serve(handler, http.MethodPost, "/add", "application/json", `{"predictions": [0.9], "labels": [1]}`); w.Code != http.StatusBadRequest {
+    t.Errorf("predictions without session accepted")
+  }
+  if w := test_serve(handler, http.MethodPost, "/add?session=a", "application/json", `{"predictions": [0.9], "labels": [3]}`); w.Code != http.StatusBadRequest {
+    t.Errorf("invalid label accepted")
+  }
+  if p, n := sessions.sessions["a"].Counts(); p != 2 || n != 4 {
+    t.Errorf("unexpected counts: %d %d", p, n)
+  }
+}
+
+func TestServeTargets(t *testing.T) {
+  w := test_serve(serve_targets, http.MethodGet, "/targets", "", "")
+  r := []string{}
+  if err := json.NewDecoder(w.Body).Decode(&r); err != nil {
+    t.Fatal(err)
+  }
+  for _, target := range []string{"roc-auc", "summary"} {
+    if !slices.Contains(r, target) {
+      t.Errorf("target `%s' missing", target)
+    }
+  }
+}
@@ -0,0 +1,166 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "errors"
+import   "fmt"
+import   "sort"
+import   "sync"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Maximal number of sessions of the server
+const serve_max_sessions = 256
+
+// Number of most recent predictions evaluated by sessions if neither
+// --window-size nor --stream-bins is given, which bounds the memory of a
+// session
+const serve_window_size = 100000
+
+var errTooManySessions = errors.New("too many sessions")
+
+/* -------------------------------------------------------------------------- */
+
+type session_evaluator interface {
+  AddBatch(values []float64, labels []int)
+  Performance() (Performance, error)
+}
+
+// Session accumulates predictions that are streamed to the server. In
+// streaming mode (--stream-bins), predictions are binned. Otherwise, only
+// the most recent predictions are evaluated (--window-size, by default
+// serve_window_size), so that the memory of a session is bounded. Positives
+// and negatives count all predictions added since the session was created or
+// reset.
+type Session struct {
+  sync.Mutex
+  Name      string
+  Positives int
+  Negatives int
+  evaluator session_evaluator
+}
+
+// Sessions of the server indexed by name
+type Sessions struct {
+  sync.Mutex
+  config   Config
+  sessions map[string]*Session
+}
+
+/* -------------------------------------------------------------------------- */
+
+func new_session_evaluator(config Config) (session_evaluator, error) {
+  if config.StreamBins > 0 {
+    return NewBinnedEvaluator(config.StreamRange[0], config.StreamRange[1], config.StreamBins)
+  }
+  if config.WindowSize > 0 {
+    return NewWindowEvaluator(config.WindowSize)
+  }
+  return NewWindowEvaluator(serve_window_size)
+}
+
+func new_sessions(config Config) *Sessions {
+  return &Sessions{config: config, sessions: map[string]*Session{}}
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Get the session with the given name, which is created if it does not exist
+// and create is true. The result is nil if the session does not exist.
+func (obj *Sessions) Get(name string, create bool) (*Session, error) {
+  if name == "" {
+    return nil, fmt.Errorf("no session name given")
+  }
+  obj.Lock()
+  defer obj.Unlock()
+  if s, ok := obj.sessions[name]; ok || !create {
+    return s, nil
+  }
+  if len(obj.sessions) >= serve_max_sessions {
+    return nil, fmt.Errorf("%w (at most %d)", errTooManySessions, serve_max_sessions)
+  }
+  evaluator, err := new_session_evaluator(obj.config); if err != nil {
+    return nil, err
+  }
+  s := &Session{Name: name, evaluator: evaluator}
+  obj.sessions[name] = s
+  return s, nil
+}
+
+// All sessions sorted by name
+func (obj *Sessions) List() []*Session {
+  obj.Lock()
+  defer obj.Unlock()
+  r := make([]*Session, 0, len(obj.sessions))
+  for _, s := range obj.sessions {
+    r = append(r, s)
+  }
+  sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+func (obj *Session) Add(values []float64, labels []int) {
+  obj.Lock()
+  defer obj.Unlock()
+  obj.evaluator.AddBatch(values, labels)
+  for _, l := range labels {
+    if l == 1 {
+      obj.Positives++
+    } else {
+      obj.Negatives++
+    }
+  }
+}
+
+// Numbers of positive and negative predictions added to the session
+func (obj *Session) Counts() (int, int) {
+  obj.Lock()
+  defer obj.Unlock()
+  return obj.Positives, obj.Negatives
+}
+
+// Performance of the session. Predictions of a window are copied under the
+// lock and evaluated without blocking other requests to the session, while
+// binned predictions are evaluated in time linear in the number of bins.
+func (obj *Session) Performance() (Performance, error) {
+  obj.Lock()
+  if evaluator, ok := obj.evaluator.(*WindowEvaluator); ok {
+    values, labels := evaluator.Predictions()
+    obj.Unlock()
+    return EvalPerformance(values, labels)
+  }
+  defer obj.Unlock()
+  return obj.evaluator.Performance()
+}
+
+func (obj *Session) Reset(config Config) error {
+  evaluator, err := new_session_evaluator(config); if err != nil {
+    return err
+  }
+  obj.Lock()
+  defer obj.Unlock()
+  obj.evaluator = evaluator
+  obj.Positives = 0
+  obj.Negatives = 0
+  return nil
+}
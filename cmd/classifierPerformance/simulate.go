@@ -0,0 +1,51 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Print a table of n synthetic predictions and labels drawn from the model
+// selected with --model, with AUC --auc and prevalence --prevalence
+// (default: 0.5).
+func classifier_performance_simulate(config Config, size string) {
+  n, err := strconv.Atoi(size); if err != nil || n < 0 {
+    log.Fatalf("invalid sample size: %s", size)
+  }
+  prevalence := 0.5
+  switch len(config.Prevalence) {
+  case 0:
+  case 1:
+    prevalence = config.Prevalence[0]
+  default:
+    log.Fatal("simulate requires a single prevalence")
+  }
+  values, labels, err := Simulate(config.Model, n, config.Auc, prevalence, new_rng(config)); if err != nil {
+    log.Fatal(err)
+  }
+  fmt.Println("prediction label")
+  for i := range values {
+    fmt.Printf("%v %d\n", values[i], labels[i])
+  }
+}
@@ -0,0 +1,88 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Indicators of the k lowest scored predictions, which are approved since
+// scores predict the positive (bad) outcome as for the challenger target.
+// Ties are broken by the order of predictions.
+func approved(values []float64, k int) []bool {
+  index := make([]int, len(values))
+  for i := range index {
+    index[i] = i
+  }
+  sort.SliceStable(index, func(i, j int) bool { return values[index[i]] < values[index[j]] })
+  r := make([]bool, len(values))
+  for _, i := range index[:k] {
+    r[i] = true
+  }
+  return r
+}
+
+// Number of accepted predictions at the approval rate given by --top or
+// --fraction
+func approval_count(config Config, n int) int {
+  switch {
+  case config.Top > 0:
+    return min(config.Top, n)
+  case config.Fraction > 0.0:
+    return int(math.Round(config.Fraction*float64(n)))
+  default:
+    return int(math.Round(DefaultTopFraction*float64(n)))
+  }
+}
+
+// Swap-in and swap-out sets when the predictions approved by the champion
+// (reference file) are replaced by the predictions approved by the
+// challenger at the same approval rate, where each model approves its
+// lowest scored predictions. The bad rate is the fraction of positive labels
+// within each set.
+func classifier_performance_swap_set(config Config, filename string) {
+  champion, challenger, labels := import_challenger(config, filename)
+  k  := approval_count(config, len(labels))
+  a1 := approved(champion  , k)
+  a2 := approved(challenger, k)
+  names  := []string{"accepted", "swap-in", "swap-out", "rejected"}
+  counts := make([][2]int, len(names))
+  for i := range labels {
+    j := 0
+    switch {
+    case  a1[i] &&  a2[i]: j = 0
+    case !a1[i] &&  a2[i]: j = 1
+    case  a1[i] && !a2[i]: j = 2
+    default:               j = 3
+    }
+    counts[j][0]++
+    counts[j][1] += labels[i]
+  }
+  PrintStderr(config, 1, "Accepting %d of %d predictions\n", k, len(labels))
+  if config.PrintHeader {
+    fmt.Println("set n bad bad-rate")
+  }
+  for j, name := range names {
+    fmt.Printf("%s %d %d %f\n", name, counts[j][0], counts[j][1], float64(counts[j][1])/float64(counts[j][0]))
+  }
+}
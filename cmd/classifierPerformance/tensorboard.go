@@ -0,0 +1,192 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "encoding/binary"
+import   "fmt"
+import   "hash/crc32"
+import   "io"
+import   "log"
+import   "math"
+import   "os"
+import   "path/filepath"
+import   "time"
+
+import   "google.golang.org/protobuf/encoding/protowire"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Number of thresholds of precision-recall curves, which is the default of
+// the TensorBoard PR curve plugin
+const tensorboard_pr_thresholds = 127
+
+var tensorboard_crc_table = crc32.MakeTable(crc32.Castagnoli)
+
+/* -------------------------------------------------------------------------- */
+
+// Write a record in the TFRecord format, i.e. the length of the data and the
+// data, each followed by its masked CRC32-C checksum
+func tensorboard_write_record(writer io.Writer, data []byte) error {
+  mask := func(b []byte) uint32 {
+    c := crc32.Checksum(b, tensorboard_crc_table)
+    return ((c >> 15) | (c << 17)) + 0xa282ead8
+  }
+  header := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+  header  = binary.LittleEndian.AppendUint32(header, mask(header))
+  footer := binary.LittleEndian.AppendUint32(nil, mask(data))
+  for _, b := range [][]byte{header, data, footer} {
+    if _, err := writer.Write(b); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Encode an Event message (tensorflow/core/util/event.proto) with either a
+// file version or a summary
+func tensorboard_event(step int64, file_version string, summary []byte) []byte {
+  var b []byte
+  b = protowire.AppendTag    (b, 1, protowire.Fixed64Type)
+  b = protowire.AppendFixed64(b, math.Float64bits(float64(time.Now().UnixNano())/1e9))
+  b = protowire.AppendTag    (b, 2, protowire.VarintType)
+  b = protowire.AppendVarint (b, uint64(step))
+  if file_version != "" {
+    b = protowire.AppendTag   (b, 3, protowire.BytesType)
+    b = protowire.AppendString(b, file_version)
+  }
+  if summary != nil {
+    b = protowire.AppendTag  (b, 5, protowire.BytesType)
+    b = protowire.AppendBytes(b, summary)
+  }
+  return b
+}
+
+// Encode a Summary message with a single value, which is either given by a
+// scalar or by a tensor with plugin metadata
+func tensorboard_summary(tag string, value []byte) []byte {
+  var v []byte
+  v = protowire.AppendTag   (v, 1, protowire.BytesType)
+  v = protowire.AppendString(v, tag)
+  v = append(v, value...)
+  var b []byte
+  b = protowire.AppendTag  (b, 1, protowire.BytesType)
+  b = protowire.AppendBytes(b, v)
+  return b
+}
+
+func tensorboard_scalar(tag string, x float64) []byte {
+  var v []byte
+  v = protowire.AppendTag    (v, 2, protowire.Fixed32Type)
+  v = protowire.AppendFixed32(v, math.Float32bits(float32(x)))
+  return tensorboard_summary(tag, v)
+}
+
+// Summary of a precision-recall curve in the format of the TensorBoard PR
+// curve plugin, i.e. a float tensor of shape [6, n] with rows TP, FP, TN,
+// FN, precision and recall at n equally spaced thresholds in [0, 1].
+// Predictions are therefore assumed to be probabilities. As in the plugin,
+// predictions are positive if they are at least as large as the threshold.
+func tensorboard_pr_curve(tag string, perf Performance, n int) []byte {
+  rows := make([][]float64, 6)
+  for i := 0; i < n; i++ {
+    // At classifies predictions larger than its argument as positive
+    c := perf.At(math.Nextafter(float64(i)/float64(n-1), math.Inf(-1)))
+    tp, fp, tn, fn := float64(c.Tp), float64(c.Fp), float64(c.Tn), float64(c.Fn)
+    rows[0] = append(rows[0], tp)
+    rows[1] = append(rows[1], fp)
+    rows[2] = append(rows[2], tn)
+    rows[3] = append(rows[3], fn)
+    rows[4] = append(rows[4], tp/math.Max(tp + fp, 1e-7))
+    rows[5] = append(rows[5], tp/math.Max(tp + fn, 1e-7))
+  }
+  // TensorProto with dtype DT_FLOAT, shape and values
+  var shape []byte
+  for _, size := range []int{6, n} {
+    var dim []byte
+    dim   = protowire.AppendTag   (dim, 1, protowire.VarintType)
+    dim   = protowire.AppendVarint(dim, uint64(size))
+    shape = protowire.AppendTag   (shape, 2, protowire.BytesType)
+    shape = protowire.AppendBytes (shape, dim)
+  }
+  var values []byte
+  for _, row := range rows {
+    for _, x := range row {
+      values = protowire.AppendFixed32(values, math.Float32bits(float32(x)))
+    }
+  }
+  var tensor []byte
+  tensor = protowire.AppendTag   (tensor, 1, protowire.VarintType)
+  tensor = protowire.AppendVarint(tensor, 1)
+  tensor = protowire.AppendTag   (tensor, 2, protowire.BytesType)
+  tensor = protowire.AppendBytes (tensor, shape)
+  tensor = protowire.AppendTag   (tensor, 5, protowire.BytesType)
+  tensor = protowire.AppendBytes (tensor, values)
+  // SummaryMetadata with PrCurvePluginData as content
+  var content []byte
+  content = protowire.AppendTag   (content, 2, protowire.VarintType)
+  content = protowire.AppendVarint(content, uint64(n))
+  var plugin []byte
+  plugin = protowire.AppendTag   (plugin, 1, protowire.BytesType)
+  plugin = protowire.AppendString(plugin, "pr_curves")
+  plugin = protowire.AppendTag   (plugin, 2, protowire.BytesType)
+  plugin = protowire.AppendBytes (plugin, content)
+  var metadata []byte
+  metadata = protowire.AppendTag  (metadata, 1, protowire.BytesType)
+  metadata = protowire.AppendBytes(metadata, plugin)
+  var v []byte
+  v = protowire.AppendTag  (v, 8, protowire.BytesType)
+  v = protowire.AppendBytes(v, tensor)
+  v = protowire.AppendTag  (v, 9, protowire.BytesType)
+  v = protowire.AppendBytes(v, metadata)
+  return tensorboard_summary(tag, v)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Write scalar metrics of the summary target and the precision-recall curve
+// to a new TensorBoard event file in the directory given by --tensorboard
+func export_tensorboard(config Config, data *Metrics) {
+  names, values, err := output_metrics(config, data); if err != nil {
+    log.Fatal(err)
+  }
+  if err := os.MkdirAll(config.Tensorboard, 0755); err != nil {
+    log.Fatal(err)
+  }
+  // the process ID distinguishes event files of runs started within the
+  // same second, e.g. by parallel jobs
+  hostname, _ := os.Hostname()
+  filename    := filepath.Join(config.Tensorboard, fmt.Sprintf("events.out.tfevents.%d.%s.%d", time.Now().Unix(), hostname, os.Getpid()))
+  create_output_file(config, filename, func(writer io.Writer) error {
+    events := [][]byte{tensorboard_event(config.Step, "brain.Event:2", nil)}
+    for i, name := range names {
+      events = append(events, tensorboard_event(config.Step, "", tensorboard_scalar(name, values[i])))
+    }
+    events = append(events, tensorboard_event(config.Step, "", tensorboard_pr_curve("precision-recall", data.Perf, tensorboard_pr_thresholds)))
+    w := bufio.NewWriter(writer)
+    for _, event := range events {
+      if err := tensorboard_write_record(w, event); err != nil {
+        return err
+      }
+    }
+    return w.Flush()
+  })
+}
@@ -0,0 +1,106 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "log"
+import   "os"
+import   "strconv"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Import predictions together with event times and event indicators. If no
+// event column is given, labels are used as event indicators.
+func import_survival(config Config, filename string) ([]float64, []float64, []int) {
+  if config.EventTimeColumn == "" {
+    log.Fatal("no event time column specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("time-dependent ROC curves are not supported in streaming mode")
+  }
+  columns := []string{config.EventTimeColumn}
+  if config.EventColumn != "" {
+    columns = append(columns, config.EventColumn)
+  }
+  table  := import_table(config, filename, columns...)
+  times  := make([]float64, len(table.Values))
+  events := table.Labels
+  for i, s := range table.Columns[config.EventTimeColumn] {
+    v, err := strconv.ParseFloat(s, 64); if err != nil {
+      log.Fatalf("invalid value `%s' in column `%s'", s, config.EventTimeColumn)
+    }
+    times[i] = v
+  }
+  if config.EventColumn != "" {
+    events = make([]int, len(table.Values))
+    for i, s := range table.Columns[config.EventColumn] {
+      v, err := strconv.Atoi(s); if err != nil || (v != 0 && v != 1) {
+        log.Fatalf("invalid value `%s' in column `%s'", s, config.EventColumn)
+      }
+      events[i] = v
+    }
+  }
+  return table.Values, times, events
+}
+
+// Time-dependent ROC curves (time-roc) or areas under the curves (time-auc)
+// at the horizons given with --horizons
+func classifier_performance_time_roc(config Config, filename, target string) {
+  if len(config.Horizons) == 0 {
+    log.Fatal("no time horizons specified")
+  }
+  values, times, events := import_survival(config, filename)
+  table := Table{Names: []string{"horizon", "auc"}, Columns: make([][]float64, 2)}
+  if target == "time-roc" {
+    table = Table{Names: []string{"horizon", "FPR", "TPR", "threshold"}, Columns: make([][]float64, 4)}
+  }
+  for _, t := range config.Horizons {
+    roc, err := TimeDependentRoc(values, times, events, t); if err != nil {
+      log.Fatal(err)
+    }
+    if target == "time-roc" {
+      for i := 0; i < roc.Len(); i++ {
+        for j, v := range []float64{t, roc.X[i], roc.Y[i], roc.Tr[i]} {
+          table.Columns[j] = append(table.Columns[j], v)
+        }
+      }
+    } else {
+      auc, err := roc.AUC(); if err != nil {
+        log.Fatal(err)
+      }
+      table.Columns[0] = append(table.Columns[0], t)
+      table.Columns[1] = append(table.Columns[1], auc)
+    }
+  }
+  export_table(config, os.Stdout, nil, table)
+}
+
+// Parse a comma separated list of time horizons
+func parse_horizons(s string) []float64 {
+  r := []float64{}
+  for _, field := range strings.Split(s, ",") {
+    v, err := strconv.ParseFloat(strings.TrimSpace(field), 64); if err != nil {
+      log.Fatalf("invalid time horizon: %s", field)
+    }
+    r = append(r, v)
+  }
+  return r
+}
@@ -0,0 +1,125 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "math"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Sensitivity, specificity and roc-auc of weighted predictions at threshold t
+func verification_bias_row(values []float64, labels []int, weights []float64, t float64) []float64 {
+  tpr, fpr, err := WeightedRates(values, labels, weights, t); if err != nil {
+    log.Fatal(err)
+  }
+  roc, err := WeightedRoc(values, labels, weights); if err != nil {
+    log.Fatal(err)
+  }
+  auc, err := roc.AUC(); if err != nil {
+    log.Fatal(err)
+  }
+  return []float64{tpr, 1.0 - fpr, auc}
+}
+
+// Sensitivity, specificity and roc-auc of partially verified data, where
+// labels are only known for verified predictions (--verified-column). The
+// naive estimates use only verified predictions, the corrected estimates
+// weight verified predictions either by inverse probabilities of
+// verification (--weight-column) or by the inverse fraction of verified
+// predictions within strata of prediction values (Begg and Greenes).
+func classifier_performance_verification_bias(config Config, filename string) {
+  if config.VerifiedColumn == "" {
+    log.Fatal("no verification column specified")
+  }
+  if config.StreamBins > 0 {
+    log.Fatal("verification bias correction is not supported in streaming mode")
+  }
+  columns := []string{config.VerifiedColumn}
+  if config.WeightColumn != "" {
+    columns = append(columns, config.WeightColumn)
+  }
+  // labels of unverified predictions are ignored and may be NA
+  table    := import_table_labels(config, filename, true, columns...)
+  verified := make([]bool, len(table.Values))
+  naive    := make([]float64, len(table.Values))
+  for i, s := range table.Columns[config.VerifiedColumn] {
+    v, err := strconv.ParseBool(s); if err != nil {
+      log.Fatalf("invalid value `%s' in column `%s'", s, config.VerifiedColumn)
+    }
+    verified[i] = v
+    if v {
+      naive[i] = 1.0
+    }
+    if table.Labels[i] == MissingLabel {
+      if v {
+        log.Fatalf("label of verified prediction %d is missing", i+1)
+      }
+      table.Labels[i] = 0
+    }
+  }
+  estimator := "begg-greenes"
+  var weights []float64
+  if config.WeightColumn != "" {
+    estimator = "ipw"
+    weights   = make([]float64, len(table.Values))
+    for i, s := range table.Columns[config.WeightColumn] {
+      if !verified[i] {
+        continue
+      }
+      v, err := strconv.ParseFloat(s, 64); if err != nil || !(v > 0.0) || math.IsInf(v, 1) {
+        log.Fatalf("invalid value `%s' in column `%s'", s, config.WeightColumn)
+      }
+      weights[i] = v
+    }
+  } else {
+    w, err := VerificationWeights(table.Values, verified, config.Bins); if err != nil {
+      log.Fatal(err)
+    }
+    weights = w
+  }
+  // threshold given by the user or optimal threshold of verified predictions
+  t := config.Threshold
+  if math.IsNaN(t) {
+    values := []float64{}
+    labels := []int{}
+    for i := range verified {
+      if verified[i] {
+        values = append(values, table.Values[i])
+        labels = append(labels, table.Labels[i])
+      }
+    }
+    perf, err := EvalPerformance(values, labels); if err != nil {
+      log.Fatal(err)
+    }
+    roc := Roc(perf)
+    t = roc.Tr[OptimumRoc(roc.Tr, roc.X, roc.Y)]
+    PrintStderr(config, 1, "Using optimal threshold %f of verified predictions\n", t)
+  }
+  if config.PrintHeader {
+    fmt.Println("estimator sensitivity specificity roc-auc")
+  }
+  for _, row := range []struct{ name string; weights []float64 }{{"naive", naive}, {estimator, weights}} {
+    r := verification_bias_row(table.Values, table.Labels, row.weights, t)
+    fmt.Printf("%s %f %f %f\n", row.name, r[0], r[1], r[2])
+  }
+}
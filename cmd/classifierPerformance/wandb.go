@@ -0,0 +1,320 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "encoding/base64"
+import   "encoding/json"
+import   "fmt"
+import   "io"
+import   "log"
+import   "math"
+import   "net/http"
+import   "os"
+import   "path"
+import   "sort"
+import   "strconv"
+import   "strings"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Directory of uploaded files within a run, which prevents collisions with
+// files of the W&B client such as config.yaml
+const wandb_files_dir = "classifierPerformance"
+
+// Number of attempts to merge metrics into the summary of a run, which may
+// be replaced concurrently by other clients
+const wandb_summary_attempts = 5
+
+type wandb_graphql_request struct {
+  Query     string         `json:"query"`
+  Variables map[string]any `json:"variables"`
+}
+
+type wandb_graphql_response struct {
+  Data   json.RawMessage `json:"data"`
+  Errors []struct {
+    Message string `json:"message"`
+  } `json:"errors"`
+}
+
+type wandb_run_summary struct {
+  Project struct {
+    Run *struct {
+      SummaryMetrics string `json:"summaryMetrics"`
+    } `json:"run"`
+  } `json:"project"`
+}
+
+type wandb_upload_urls struct {
+  Model struct {
+    Bucket struct {
+      Files struct {
+        UploadHeaders []string `json:"uploadHeaders"`
+        Edges []struct {
+          Node struct {
+            Name string `json:"name"`
+            Url  string `json:"url"`
+          } `json:"node"`
+        } `json:"edges"`
+      } `json:"files"`
+    } `json:"bucket"`
+  } `json:"model"`
+}
+
+const wandb_query_summary = `
+query RunSummary($entity: String!, $project: String!, $run: String!) {
+  project(name: $project, entityName: $entity) {
+    run(name: $run) { summaryMetrics }
+  }
+}`
+
+const wandb_mutation_summary = `
+mutation UpsertSummary($entity: String!, $project: String!, $run: String!, $summaryMetrics: JSONString) {
+  upsertBucket(input: {entityName: $entity, modelName: $project, name: $run, summaryMetrics: $summaryMetrics}) {
+    bucket { id }
+  }
+}`
+
+const wandb_query_upload_urls = `
+query RunUploadUrls($entity: String!, $project: String!, $run: String!, $files: [String]!) {
+  model(name: $project, entityName: $entity) {
+    bucket(name: $run) {
+      files(names: $files) {
+        uploadHeaders
+        edges { node { name url(upload: true) } }
+      }
+    }
+  }
+}`
+
+/* -------------------------------------------------------------------------- */
+
+// Split a run path of the form ENTITY/PROJECT/RUN_ID, where missing
+// components are taken from the environment variables of the W&B client
+func wandb_run_path(config Config) (string, string, string, error) {
+  fields := strings.Split(config.Wandb, "/")
+  env    := []string{"WANDB_ENTITY", "WANDB_PROJECT", "WANDB_RUN_ID"}
+  if len(fields) > len(env) {
+    return "", "", "", fmt.Errorf("invalid W&B run path `%s'", config.Wandb)
+  }
+  r := make([]string, len(env))
+  for i := range env {
+    if j := i - len(env) + len(fields); j >= 0 && fields[j] != "" {
+      r[i] = fields[j]
+    } else
+    if r[i] = os.Getenv(env[i]); r[i] == "" {
+      return "", "", "", fmt.Errorf("invalid W&B run path `%s': %s not set", config.Wandb, env[i])
+    }
+  }
+  return r[0], r[1], r[2], nil
+}
+
+func wandb_request(method, url string, body io.Reader, header http.Header) ([]byte, error) {
+  request, err := http.NewRequest(method, url, body); if err != nil {
+    return nil, err
+  }
+  for key, values := range header {
+    request.Header[key] = values
+  }
+  response, err := tracking_client.Do(request); if err != nil {
+    return nil, err
+  }
+  defer response.Body.Close()
+  r, err := io.ReadAll(response.Body); if err != nil {
+    return nil, err
+  }
+  if response.StatusCode/100 != 2 {
+    return nil, fmt.Errorf("%s request failed with status %s: %s", method, response.Status, strings.TrimSpace(string(r)))
+  }
+  return r, nil
+}
+
+// Send a query to the GraphQL endpoint of the W&B server given by
+// WANDB_BASE_URL, authenticated with the key in WANDB_API_KEY
+func wandb_graphql(query string, variables map[string]any, result any) error {
+  base := os.Getenv("WANDB_BASE_URL")
+  if base == "" {
+    base = "https://api.wandb.ai"
+  }
+  key := os.Getenv("WANDB_API_KEY")
+  if key == "" {
+    return fmt.Errorf("WANDB_API_KEY not set")
+  }
+  body, err := json.Marshal(wandb_graphql_request{Query: query, Variables: variables}); if err != nil {
+    return err
+  }
+  header := http.Header{}
+  header.Set("Content-Type", "application/json")
+  header.Set("Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte("api:" + key)))
+  b, err := wandb_request(http.MethodPost, strings.TrimRight(base, "/") + "/graphql", bytes.NewReader(body), header); if err != nil {
+    return err
+  }
+  response := wandb_graphql_response{}
+  if err := json.Unmarshal(b, &response); err != nil {
+    return err
+  }
+  if len(response.Errors) > 0 {
+    return fmt.Errorf("W&B query failed: %s", response.Errors[0].Message)
+  }
+  return json.Unmarshal(response.Data, result)
+}
+
+// Encode the summary of a run, where non-finite values are written as by
+// the Python client of W&B (NaN, Infinity, -Infinity)
+func wandb_summary(summary map[string]json.RawMessage, names []string, values []float64) string {
+  for i, name := range names {
+    switch v := values[i]; {
+    case math.IsNaN(v):
+      summary[name] = json.RawMessage("NaN")
+    case math.IsInf(v,  1):
+      summary[name] = json.RawMessage("Infinity")
+    case math.IsInf(v, -1):
+      summary[name] = json.RawMessage("-Infinity")
+    default:
+      summary[name] = json.RawMessage(strconv.FormatFloat(v, 'g', -1, 64))
+    }
+  }
+  keys := make([]string, 0, len(summary))
+  for key := range summary {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+  var b strings.Builder
+  b.WriteString("{")
+  for i, key := range keys {
+    if i > 0 {
+      b.WriteString(", ")
+    }
+    k, _ := json.Marshal(key)
+    fmt.Fprintf(&b, "%s: %s", k, summary[key])
+  }
+  b.WriteString("}")
+  return b.String()
+}
+
+// Summary of a run as map from keys to encoded values
+func wandb_read_summary(variables map[string]any) (map[string]json.RawMessage, error) {
+  r := wandb_run_summary{}
+  if err := wandb_graphql(wandb_query_summary, variables, &r); err != nil {
+    return nil, err
+  }
+  if r.Project.Run == nil {
+    return nil, fmt.Errorf("run does not exist")
+  }
+  summary := map[string]json.RawMessage{}
+  if s := r.Project.Run.SummaryMetrics; s != "" {
+    if err := json.Unmarshal([]byte(s), &summary); err != nil {
+      return nil, fmt.Errorf("invalid summary: %v", err)
+    }
+  }
+  return summary, nil
+}
+
+// Merge metrics into the summary of a run. W&B only supports replacing the
+// summary as a whole, hence the summary is read and merged right before it
+// is written. Afterwards, the summary is read again to verify that the
+// metrics were not dropped by a concurrent update of another client, in
+// which case the merge is repeated on the new summary.
+func wandb_merge_summary(variables map[string]any, names []string, values []float64) error {
+  for i := 0; i < wandb_summary_attempts; i++ {
+    summary, err := wandb_read_summary(variables); if err != nil {
+      return err
+    }
+    v := map[string]any{"summaryMetrics": wandb_summary(summary, names, values)}
+    for key, value := range variables {
+      v[key] = value
+    }
+    if err := wandb_graphql(wandb_mutation_summary, v, &map[string]any{}); err != nil {
+      return err
+    }
+    summary, err = wandb_read_summary(variables); if err != nil {
+      return err
+    }
+    merged := true
+    for _, name := range names {
+      if _, ok := summary[name]; !ok {
+        merged = false
+      }
+    }
+    if merged {
+      return nil
+    }
+  }
+  return fmt.Errorf("summary was replaced concurrently %d times", wandb_summary_attempts)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Add scalar metrics of the summary target to the summary of an existing
+// W&B run and upload curve tables and plots to the files of the run
+func export_wandb(config Config, data *Metrics) {
+  entity, project, run, err := wandb_run_path(config); if err != nil {
+    log.Fatal(err)
+  }
+  names, values, err := output_metrics(config, data); if err != nil {
+    log.Fatal(err)
+  }
+  variables := map[string]any{"entity": entity, "project": project, "run": run}
+  PrintStderr(config, 1, "Logging %d metrics to W&B run `%s/%s/%s'... ", len(names), entity, project, run)
+  if err := wandb_merge_summary(variables, names, values); err != nil {
+    PrintStderr(config, 1, "failed\n")
+    log.Fatalf("updating summary of W&B run `%s/%s/%s' failed: %v", entity, project, run, err)
+  }
+  PrintStderr(config, 1, "done\n")
+  // upload files to the signed URLs of the run
+  files     := output_files(config, data)
+  filenames := make([]string, len(files))
+  for i, file := range files {
+    filenames[i] = path.Join(wandb_files_dir, file.Name)
+  }
+  variables["files"] = filenames
+  u := wandb_upload_urls{}
+  if err := wandb_graphql(wandb_query_upload_urls, variables, &u); err != nil {
+    log.Fatal(err)
+  }
+  header := http.Header{}
+  for _, h := range u.Model.Bucket.Files.UploadHeaders {
+    if key, value, ok := strings.Cut(h, ":"); ok {
+      header.Add(key, value)
+    }
+  }
+  urls := make(map[string]string)
+  for _, edge := range u.Model.Bucket.Files.Edges {
+    urls[edge.Node.Name] = edge.Node.Url
+  }
+  for i, file := range files {
+    url, ok := urls[filenames[i]]
+    if !ok {
+      log.Fatalf("no upload URL for file `%s'", filenames[i])
+    }
+    var b bytes.Buffer
+    if err := file.Write(&b); err != nil {
+      log.Fatal(err)
+    }
+    PrintStderr(config, 1, "Uploading file `%s'... ", filenames[i])
+    if _, err := wandb_request(http.MethodPut, url, &b, header); err != nil {
+      PrintStderr(config, 1, "failed\n")
+      log.Fatal(err)
+    }
+    PrintStderr(config, 1, "done\n")
+  }
+}
@@ -0,0 +1,65 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "log"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Weight of evidence and information value of quantile bins of predictions
+// or of a feature column. The last row contains the totals and the overall
+// information value.
+func classifier_performance_woe(config Config, filename string) {
+  if config.StreamBins > 0 {
+    log.Fatal("woe is not supported in streaming mode")
+  }
+  var values []float64
+  var labels []int
+  if config.FeatureColumn == "" {
+    values, labels = import_predictions_cached(config, filename)
+  } else {
+    table := import_table(config, filename, config.FeatureColumn)
+    values = make([]float64, len(table.Values))
+    labels = table.Labels
+    for i, s := range table.Columns[config.FeatureColumn] {
+      v, err := strconv.ParseFloat(s, 64); if err != nil {
+        log.Fatalf("invalid value `%s' in column `%s'", s, config.FeatureColumn)
+      }
+      values[i] = v
+    }
+  }
+  bins, err := QuantileBins(values, labels, config.Bins); if err != nil {
+    log.Fatal(err)
+  }
+  woe, iv, total := WeightOfEvidence(bins)
+  if config.PrintHeader {
+    fmt.Println("min max n positives negatives woe iv")
+  }
+  n_pos, n_neg := 0, 0
+  for k, bin := range bins {
+    fmt.Printf("%f %f %d %d %d %f %f\n", bin.Min, bin.Max, bin.Len(), bin.Positives, bin.Negatives, woe[k], iv[k])
+    n_pos += bin.Positives
+    n_neg += bin.Negatives
+  }
+  fmt.Printf("%f %f %d %d %d %f %f\n", bins[len(bins)-1].Min, bins[0].Max, n_pos + n_neg, n_pos, n_neg, 0.0, total)
+}
@@ -0,0 +1,96 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "io"
+import   "math"
+import   "regexp"
+import   "strconv"
+
+import . "github.com/pbenner/classifierPerformance/pkg/classifierPerformance"
+
+/* -------------------------------------------------------------------------- */
+
+// Results of scalar metrics and operating points in YAML (--format yaml),
+// which are meant to be committed and reviewed alongside model cards. As
+// config.yaml of output directories, YAML is written without a library.
+
+var yaml_plain_key = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.@()/-]*$`)
+
+// Keys that are not plain scalars, e.g. names of metrics with several
+// arguments, are quoted
+func yaml_key(key string) string {
+  if yaml_plain_key.MatchString(key) {
+    return key
+  }
+  return strconv.Quote(key)
+}
+
+// Undefined and infinite values are written as YAML floats, other values
+// with full precision, so that they are neither truncated nor change in
+// diffs because of rounding
+func yaml_value(v float64) string {
+  switch {
+  case math.IsNaN(v):
+    return ".nan"
+  case math.IsInf(v, 1):
+    return ".inf"
+  case math.IsInf(v, -1):
+    return "-.inf"
+  }
+  return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func export_yaml_counts(writer io.Writer, perf *Performance) {
+  if perf != nil {
+    fmt.Fprintf(writer, "positives: %d\n", perf.P)
+    fmt.Fprintf(writer, "negatives: %d\n", perf.N)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Export values of scalar metrics, where lower and upper bounds are optional.
+// The number of positives and negatives is omitted if perf is nil.
+func export_yaml_metrics(writer io.Writer, perf *Performance, names []string, values, lower, upper []float64) {
+  export_yaml_counts(writer, perf)
+  fmt.Fprintln(writer, "metrics:")
+  for i, name := range names {
+    if lower != nil && upper != nil {
+      fmt.Fprintf(writer, "  %s:\n", yaml_key(name))
+      fmt.Fprintf(writer, "    value: %s\n", yaml_value(values[i]))
+      fmt.Fprintf(writer, "    lower: %s\n", yaml_value(lower[i]))
+      fmt.Fprintf(writer, "    upper: %s\n", yaml_value(upper[i]))
+    } else {
+      fmt.Fprintf(writer, "  %s: %s\n", yaml_key(name), yaml_value(values[i]))
+    }
+  }
+}
+
+// Export an operating point, i.e. a table with a single row, as mapping from
+// column names to values
+func export_yaml_point(writer io.Writer, perf *Performance, name string, table Table) {
+  export_yaml_counts(writer, perf)
+  fmt.Fprintln(writer, "metrics:")
+  fmt.Fprintf(writer, "  %s:\n", yaml_key(name))
+  for j, column := range table.Columns {
+    fmt.Fprintf(writer, "    %s: %s\n", yaml_key(table.Names[j]), yaml_value(column[0]))
+  }
+}
@@ -0,0 +1,202 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "fmt"
+import   "math"
+
+import   "github.com/apache/arrow-go/v18/arrow"
+import   "github.com/apache/arrow-go/v18/arrow/array"
+import   "github.com/apache/arrow-go/v18/arrow/ipc"
+
+/* -------------------------------------------------------------------------- */
+
+// Numeric value of an Arrow array at position i
+func arrowFloat(a arrow.Array, i int) (float64, error) {
+  if a.IsNull(i) {
+    return 0.0, fmt.Errorf("missing value")
+  }
+  switch a := a.(type) {
+  case *array.Float64: return a.Value(i), nil
+  case *array.Float32: return float64(a.Value(i)), nil
+  case *array.Int64  : return float64(a.Value(i)), nil
+  case *array.Int32  : return float64(a.Value(i)), nil
+  case *array.Int16  : return float64(a.Value(i)), nil
+  case *array.Int8   : return float64(a.Value(i)), nil
+  case *array.Uint64 : return float64(a.Value(i)), nil
+  case *array.Uint32 : return float64(a.Value(i)), nil
+  case *array.Uint16 : return float64(a.Value(i)), nil
+  case *array.Uint8  : return float64(a.Value(i)), nil
+  case *array.Boolean:
+    if a.Value(i) {
+      return 1.0, nil
+    }
+    return 0.0, nil
+  default:
+    return 0.0, fmt.Errorf("unsupported type %s", a.DataType())
+  }
+}
+
+// Scan all rows of a record batch, where n is the number of rows scanned
+// before. Fields of rows are only passed to f if rows is true.
+func scanArrowRecord(record arrow.RecordBatch, n int, i_predictions, i_labels int, i_extra []int, threshold float64, rows bool, f predictionRowFunc) error {
+  names  := record.Schema().Fields()
+  fields := make([][]byte, len(i_extra))
+  row    := [][]byte(nil)
+  if rows {
+    row = make([][]byte, record.NumCols())
+  }
+  for i := 0; i < int(record.NumRows()); i++ {
+    fail := func(j int, err error) error {
+      return fmt.Errorf("row %d, column `%s': %v", n+i+1, names[j].Name, err)
+    }
+    value, err := arrowFloat(record.Column(i_predictions), i); if err != nil {
+      return fail(i_predictions, err)
+    }
+    if math.IsNaN(value) {
+      return fail(i_predictions, fmt.Errorf("prediction is not a number"))
+    }
+    relevance, err := arrowFloat(record.Column(i_labels), i); if err != nil {
+      return fail(i_labels, err)
+    }
+    label := 0
+    if math.IsNaN(threshold) {
+      if relevance != 0.0 && relevance != 1.0 {
+        return fail(i_labels, fmt.Errorf("labels must be 0 or 1"))
+      }
+      label = int(relevance)
+    } else {
+      if relevance < 0.0 || math.IsNaN(relevance) || math.IsInf(relevance, 1) {
+        return fail(i_labels, fmt.Errorf("relevance must be a non-negative number"))
+      }
+      if relevance >= threshold {
+        label = 1
+      }
+    }
+    for k, j := range i_extra {
+      fields[k] = []byte(record.Column(j).ValueStr(i))
+    }
+    for j := range row {
+      row[j] = []byte(record.Column(j).ValueStr(i))
+    }
+    if err := f(value, label, relevance, fields, row); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// Scan an Arrow IPC file (e.g. Feather version 2) or stream given as a byte
+// slice, which may be memory-mapped. Columns are identified by name as in
+// text tables, and labels may be integers, floating point numbers or
+// booleans.
+func scanArrowPredictionTable(data []byte, extra []string, threshold float64, rows bool, f predictionRowFunc) error {
+  var schema  *arrow.Schema
+  var records func(func(arrow.RecordBatch) error) error
+  if bytes.HasPrefix(data, ipc.Magic) {
+    reader, err := ipc.NewMappedFileReader(data); if err != nil {
+      return err
+    }
+    defer reader.Close()
+    schema  = reader.Schema()
+    records = func(g func(arrow.RecordBatch) error) error {
+      for i := 0; i < reader.NumRecords(); i++ {
+        record, err := reader.RecordBatch(i); if err != nil {
+          return err
+        }
+        if err := g(record); err != nil {
+          return err
+        }
+      }
+      return nil
+    }
+  } else {
+    reader, err := ipc.NewReader(bytes.NewReader(data)); if err != nil {
+      return err
+    }
+    defer reader.Release()
+    schema  = reader.Schema()
+    records = func(g func(arrow.RecordBatch) error) error {
+      for reader.Next() {
+        if err := g(reader.RecordBatch()); err != nil {
+          return err
+        }
+      }
+      return reader.Err()
+    }
+  }
+  column := func(names ...string) int {
+    for _, name := range names {
+      if i := schema.FieldIndices(name); len(i) > 0 {
+        return i[0]
+      }
+    }
+    return -1
+  }
+  i_predictions := column("predictions", "prediction")
+  i_labels      := column("labels", "label")
+  i_extra       := make([]int, len(extra))
+  if i_predictions == -1 {
+    return fmt.Errorf("no column called `predictions' found")
+  }
+  if i_labels == -1 {
+    return fmt.Errorf("no column called `labels' found")
+  }
+  for k, name := range extra {
+    if i_extra[k] = column(name); i_extra[k] == -1 {
+      return fmt.Errorf("no column called `%s' found", name)
+    }
+  }
+  n := 0
+  return records(func(record arrow.RecordBatch) error {
+    if err := scanArrowRecord(record, n, i_predictions, i_labels, i_extra, threshold, rows, f); err != nil {
+      return err
+    }
+    n += int(record.NumRows())
+    return nil
+  })
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Same as ScanPredictions, but reads predictions from an Arrow IPC file or
+// stream.
+func ScanArrowPredictions(data []byte, f func(value float64, label int) error) error {
+  return scanArrowPredictionTable(data, nil, math.NaN(), false, func(value float64, label int, relevance float64, fields, row [][]byte) error {
+    return f(value, label)
+  })
+}
+
+// Read a prediction table from an Arrow IPC file or stream and keep the
+// given additional columns. If threshold is not NaN, labels are graded
+// relevance values as in ReadGradedPredictionTable.
+func ReadArrowPredictionTable(data []byte, threshold float64, columns ...string) (PredictionTable, error) {
+  return readPredictionTable(threshold, columns, false, func(f predictionRowFunc) error {
+    return scanArrowPredictionTable(data, columns, threshold, false, f)
+  })
+}
+
+// Same as ReadArrowPredictionTable, but also keeps all rows as in
+// ReadPredictionTableRows.
+func ReadArrowPredictionTableRows(data []byte, threshold float64, columns ...string) (PredictionTable, error) {
+  return readPredictionTable(threshold, columns, true, func(f predictionRowFunc) error {
+    return scanArrowPredictionTable(data, columns, threshold, true, f)
+  })
+}
@@ -0,0 +1,222 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// AveragedCurve is the pointwise mean of several curves together with the
+// standard deviations of both coordinates.
+type AveragedCurve struct {
+  Curve
+  XSd []float64
+  YSd []float64
+  // number of averaged curves
+  N   int
+}
+
+// Pointwise confidence band of the mean y values, i.e. mean +/- z standard
+// errors.
+func (obj AveragedCurve) Band(z float64) ([]float64, []float64) {
+  lower := make([]float64, len(obj.Y))
+  upper := make([]float64, len(obj.Y))
+  for i := range obj.Y {
+    d := z*obj.YSd[i]/math.Sqrt(float64(obj.N))
+    lower[i] = obj.Y[i] - d
+    upper[i] = obj.Y[i] + d
+  }
+  return lower, upper
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Evaluate performance at the given thresholds.
+func (obj Performance) Resample(grid []float64) Performance {
+  r := Performance{P: obj.P, N: obj.N}
+  r.Tr = make([]float64, len(grid))
+  r.Tp = make([]int, len(grid))
+  r.Fp = make([]int, len(grid))
+  r.Tn = make([]int, len(grid))
+  r.Fn = make([]int, len(grid))
+  for i, t := range grid {
+    c := obj.At(t)
+    r.Tr[i] = t
+    r.Tp[i] = c.Tp
+    r.Fp[i] = c.Fp
+    r.Tn[i] = c.Tn
+    r.Fn[i] = c.Fn
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Mean and sample standard deviation.
+func MeanSd(x []float64) (float64, float64) {
+  if len(x) == 0 {
+    return math.NaN(), math.NaN()
+  }
+  m := 0.0
+  for _, v := range x {
+    m += v
+  }
+  m /= float64(len(x))
+  if len(x) == 1 {
+    return m, 0.0
+  }
+  s := 0.0
+  for _, v := range x {
+    s += (v-m)*(v-m)
+  }
+  return m, math.Sqrt(s/float64(len(x)-1))
+}
+
+func averageCurves(curves []Curve, n int) AveragedCurve {
+  r := AveragedCurve{}
+  r.X   = make([]float64, n)
+  r.Y   = make([]float64, n)
+  r.XSd = make([]float64, n)
+  r.YSd = make([]float64, n)
+  r.N   = len(curves)
+  x := make([]float64, len(curves))
+  y := make([]float64, len(curves))
+  for i := 0; i < n; i++ {
+    for k, c := range curves {
+      x[k] = c.X[i]
+      y[k] = c.Y[i]
+    }
+    r.X[i], r.XSd[i] = MeanSd(x)
+    r.Y[i], r.YSd[i] = MeanSd(y)
+  }
+  return r
+}
+
+// Vertical averaging of curves, i.e. all curves are interpolated on a common
+// grid of x values and the y values are averaged.
+func VerticalAverage(curves []Curve, grid []float64) AveragedCurve {
+  c := make([]Curve, len(curves))
+  for k := range curves {
+    c[k] = curves[k].Interpolate(grid)
+  }
+  return averageCurves(c, len(grid))
+}
+
+// Vertical averaging of precision-recall curves. Precision is interpolated
+// at the given recall values following Davis and Goadrich (2006), since
+// linear interpolation in precision-recall space is overly optimistic.
+func VerticalAveragePrecisionRecall(perfs []Performance, grid []float64, normalize bool) AveragedCurve {
+  c := make([]Curve, len(perfs))
+  for k := range perfs {
+    c[k] = InterpolatePrecisionRecall(perfs[k], grid, normalize)
+  }
+  return averageCurves(c, len(grid))
+}
+
+// Threshold averaging of curves, i.e. curves are evaluated at a common grid
+// of thresholds and both x and y values are averaged. The function f
+// computes the curve from a Performance object, e.g. Roc.
+func ThresholdAverage(perfs []Performance, grid []float64, f func(Performance) Curve) AveragedCurve {
+  c := make([]Curve, len(perfs))
+  for k := range perfs {
+    c[k] = f(perfs[k].Resample(grid))
+  }
+  r := averageCurves(c, len(grid))
+  r.Tr = make([]float64, len(grid))
+  copy(r.Tr, grid)
+  return r
+}
+
+// Grid of n equally spaced points in the interval [0,1].
+func UnitGrid(n int) []float64 {
+  if n < 2 {
+    n = 2
+  }
+  grid := make([]float64, n)
+  for i := 0; i < n; i++ {
+    grid[i] = float64(i)/float64(n-1)
+  }
+  return grid
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Evaluate the precision at the given recall values. Between two points A
+// and B of the curve, the number of false positives increases linearly with
+// the number of true positives, i.e. FP = FP_A + (TP - TP_A)(FP_B - FP_A)/(TP_B
+// - TP_A) (Davis and Goadrich, 2006). At recall zero the precision of the
+// point with the smallest positive number of true positives is used.
+func InterpolatePrecisionRecall(perf Performance, grid []float64, normalize bool) Curve {
+  // collect points ordered by increasing number of true positives, the
+  // last point is the one where all predictions are classified as positive
+  tp := []float64{}
+  fp := []float64{}
+  for i := perf.Len()-1; i >= 0; i-- {
+    if n := len(tp); n > 0 && tp[n-1] == float64(perf.Tp[i]) {
+      // keep the point with the smallest number of false positives
+      continue
+    }
+    tp = append(tp, float64(perf.Tp[i]))
+    fp = append(fp, float64(perf.Fp[i]))
+  }
+  if n := len(tp); n == 0 || tp[n-1] != float64(perf.P) {
+    tp = append(tp, float64(perf.P))
+    fp = append(fp, float64(perf.N))
+  }
+  // precision at recall zero
+  p0 := math.NaN()
+  for i := range tp {
+    if tp[i] > 0 {
+      p0 = tp[i]/(tp[i] + fp[i]); break
+    }
+  }
+  r := Curve{}
+  r.X = make([]float64, len(grid))
+  r.Y = make([]float64, len(grid))
+  for j, recall := range grid {
+    r.X[j] = recall
+    t := recall*float64(perf.P)
+    switch {
+    case perf.P == 0:
+      r.Y[j] = math.NaN()
+    case t <= 0:
+      r.Y[j] = p0
+    default:
+      // first point with at least t true positives
+      b := sort.SearchFloat64s(tp, t)
+      if b == len(tp) {
+        b = len(tp)-1
+      }
+      f := fp[b]
+      if b > 0 && tp[b] != t {
+        a := b-1
+        f  = fp[a] + (t - tp[a])*(fp[b] - fp[a])/(tp[b] - tp[a])
+      }
+      r.Y[j] = t/(t + f)
+    }
+  }
+  if normalize {
+    c := float64(perf.P)/float64(perf.P+perf.N)
+    for i := 0; i < len(r.Y); i++ {
+      r.Y[i] = (r.Y[i] - c)/(1.0 - c)
+    }
+  }
+  return r
+}
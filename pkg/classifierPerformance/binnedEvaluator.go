@@ -0,0 +1,126 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// BinnedEvaluator accumulates predictions in a fixed number of equally sized
+// bins over the interval [Min, Max]. Memory is bounded by the number of bins
+// and does not depend on the number of predictions. Predictions outside the
+// interval are assigned to the first or last bin.
+type BinnedEvaluator struct {
+  Min, Max float64
+  Pos      []int
+  Neg      []int
+  err      error
+}
+
+/* -------------------------------------------------------------------------- */
+
+func NewBinnedEvaluator(min, max float64, n int) (*BinnedEvaluator, error) {
+  if n <= 0 {
+    return nil, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  if !(min < max) {
+    return nil, fmt.Errorf("invalid interval [%f, %f]", min, max)
+  }
+  r := BinnedEvaluator{}
+  r.Min = min
+  r.Max = max
+  r.Pos = make([]int, n)
+  r.Neg = make([]int, n)
+  return &r, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+func (obj *BinnedEvaluator) bin(value float64) int {
+  n := len(obj.Pos)
+  k := int(math.Floor(float64(n)*(value - obj.Min)/(obj.Max - obj.Min)))
+  if k < 0 {
+    k = 0
+  }
+  if k >= n {
+    k = n-1
+  }
+  return k
+}
+
+// Upper boundary of the k-th bin, which is used as threshold.
+func (obj *BinnedEvaluator) threshold(k int) float64 {
+  return obj.Min + float64(k+1)*(obj.Max - obj.Min)/float64(len(obj.Pos))
+}
+
+func (obj *BinnedEvaluator) Add(value float64, label int) {
+  if obj.err != nil {
+    return
+  }
+  if math.IsNaN(value) {
+    obj.err = fmt.Errorf("invalid prediction: %f", value); return
+  }
+  switch label {
+  case 1: obj.Pos[obj.bin(value)] += 1
+  case 0: obj.Neg[obj.bin(value)] += 1
+  default:
+    obj.err = fmt.Errorf("invalid label: %d", label)
+  }
+}
+
+func (obj *BinnedEvaluator) AddBatch(values []float64, labels []int) {
+  for i := 0; i < len(values); i++ {
+    obj.Add(values[i], labels[i])
+  }
+}
+
+// Compute performance using the upper boundaries of all non-empty bins as
+// thresholds.
+func (obj *BinnedEvaluator) Performance() (Performance, error) {
+  if obj.err != nil {
+    return Performance{}, obj.err
+  }
+  n_pos := 0
+  n_neg := 0
+  for k := 0; k < len(obj.Pos); k++ {
+    n_pos += obj.Pos[k]
+    n_neg += obj.Neg[k]
+  }
+  tr := []float64{}
+  tp := []int{}
+  fp := []int{}
+  tn := []int{}
+  fn := []int{}
+  c_pos := 0
+  c_neg := 0
+  for k := 0; k < len(obj.Pos); k++ {
+    if obj.Pos[k] == 0 && obj.Neg[k] == 0 {
+      continue
+    }
+    c_pos += obj.Pos[k]
+    c_neg += obj.Neg[k]
+    tr = append(tr, obj.threshold(k))
+    tp = append(tp, n_pos - c_pos)
+    fp = append(fp, n_neg - c_neg)
+    tn = append(tn, c_neg)
+    fn = append(fn, c_pos)
+  }
+  return Performance{Tr: tr, Tp: tp, Fp: fp, Tn: tn, Fn: fn, P: n_pos, N: n_neg}, nil
+}
@@ -0,0 +1,378 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Number of points of fitted ROC curves evaluated by the roc-binormal metric
+const binormalPoints = 101
+
+// Binormal is a binormal ROC model, where predictions of negatives and
+// positives are monotone transformations of latent normal variables with
+// distributions N(0,1) and N(A/B,1/B^2). The ROC curve of the model is given
+// by TPR = Phi(A + B*Phi^-1(FPR)). Thresholds are the latent boundaries
+// between rating categories and Covariance is the estimated covariance
+// matrix of A and B.
+type Binormal struct {
+  A, B          float64
+  Thresholds    []float64
+  Covariance    [2][2]float64
+  LogLikelihood float64
+}
+
+/* -------------------------------------------------------------------------- */
+
+func normalCdf(x float64) float64 {
+  return 0.5*math.Erfc(-x/math.Sqrt2)
+}
+
+func normalPdf(x float64) float64 {
+  return math.Exp(-0.5*x*x)/math.Sqrt(2.0*math.Pi)
+}
+
+func normalQuantile(p float64) float64 {
+  return math.Sqrt2*math.Erfinv(2.0*p - 1.0)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Area under the fitted ROC curve.
+func (obj Binormal) AUC() float64 {
+  return normalCdf(obj.A/math.Sqrt(1.0 + obj.B*obj.B))
+}
+
+// Standard error of the area under the fitted ROC curve computed with the
+// delta method.
+func (obj Binormal) AUCStdErr() float64 {
+  s  := 1.0 + obj.B*obj.B
+  d  := normalPdf(obj.A/math.Sqrt(s))
+  ga := d/math.Sqrt(s)
+  gb := -d*obj.A*obj.B/math.Pow(s, 1.5)
+  v  := ga*ga*obj.Covariance[0][0] + 2.0*ga*gb*obj.Covariance[0][1] + gb*gb*obj.Covariance[1][1]
+  return math.Sqrt(v)
+}
+
+// Fitted ROC curve evaluated at n equally spaced false positive rates. As
+// for empirical ROC curves, points are ordered by increasing thresholds,
+// which are given on the scale of the latent variable.
+func (obj Binormal) Curve(n int) Curve {
+  r := Curve{}
+  for i := 0; i < n; i++ {
+    fpr := 1.0 - float64(i)/float64(n-1)
+    z   := normalQuantile(fpr)
+    r.X  = append(r.X , fpr)
+    r.Y  = append(r.Y , normalCdf(obj.A + obj.B*z))
+    r.Tr = append(r.Tr, -z)
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Log-likelihood of the binormal model with parameters theta = (a, b,
+// c_1, ..., c_{K-1}) for counts of negatives and positives in K ordered
+// rating categories
+func binormalLogLikelihood(theta []float64, neg, pos []int) float64 {
+  a, b, c := theta[0], theta[1], theta[2:]
+  if b <= 0.0 {
+    return math.Inf(-1)
+  }
+  for k := 1; k < len(c); k++ {
+    if c[k] <= c[k-1] {
+      return math.Inf(-1)
+    }
+  }
+  r := 0.0
+  for k := range neg {
+    f0_lo, f0_hi := 0.0, 1.0
+    f1_lo, f1_hi := 0.0, 1.0
+    if k > 0 {
+      f0_lo = normalCdf(c[k-1])
+      f1_lo = normalCdf(b*c[k-1] - a)
+    }
+    if k < len(c) {
+      f0_hi = normalCdf(c[k])
+      f1_hi = normalCdf(b*c[k] - a)
+    }
+    if neg[k] > 0 {
+      r += float64(neg[k])*math.Log(f0_hi - f0_lo)
+    }
+    if pos[k] > 0 {
+      r += float64(pos[k])*math.Log(f1_hi - f1_lo)
+    }
+  }
+  if math.IsNaN(r) {
+    return math.Inf(-1)
+  }
+  return r
+}
+
+// Gradient and Hessian of f at x computed with central differences
+func numericalDerivatives(f func([]float64) float64, x []float64) ([]float64, [][]float64) {
+  n := len(x)
+  h := make([]float64, n)
+  for i := range x {
+    h[i] = 1e-4*math.Max(1.0, math.Abs(x[i]))
+  }
+  eval := func(i, j int, si, sj float64) float64 {
+    y := append([]float64{}, x...)
+    y[i] += si*h[i]
+    y[j] += sj*h[j]
+    return f(y)
+  }
+  f0 := f(x)
+  g  := make([]float64, n)
+  H  := make([][]float64, n)
+  for i := range H {
+    H[i] = make([]float64, n)
+  }
+  for i := 0; i < n; i++ {
+    fp := eval(i, i, 0.5, 0.5)
+    fm := eval(i, i, -0.5, -0.5)
+    g[i]    = (fp - fm)/(2.0*h[i])
+    H[i][i] = (fp - 2.0*f0 + fm)/(h[i]*h[i])
+    for j := 0; j < i; j++ {
+      H[i][j] = (eval(i, j, 1, 1) - eval(i, j, 1, -1) - eval(i, j, -1, 1) + eval(i, j, -1, -1))/(4.0*h[i]*h[j])
+      H[j][i] = H[i][j]
+    }
+  }
+  return g, H
+}
+
+// Inverse of a square matrix computed with Gauss-Jordan elimination
+func invertMatrix(a [][]float64) ([][]float64, error) {
+  n := len(a)
+  m := make([][]float64, n)
+  for i := range a {
+    m[i] = make([]float64, 2*n)
+    copy(m[i], a[i])
+    m[i][n+i] = 1.0
+  }
+  for j := 0; j < n; j++ {
+    p := j
+    for i := j+1; i < n; i++ {
+      if math.Abs(m[i][j]) > math.Abs(m[p][j]) {
+        p = i
+      }
+    }
+    if m[p][j] == 0.0 || math.IsNaN(m[p][j]) {
+      return nil, fmt.Errorf("matrix is singular")
+    }
+    m[j], m[p] = m[p], m[j]
+    for k := range m[j] {
+      if k != j {
+        m[j][k] /= m[j][j]
+      }
+    }
+    m[j][j] = 1.0
+    for i := 0; i < n; i++ {
+      if i != j && m[i][j] != 0.0 {
+        s := m[i][j]
+        for k := range m[i] {
+          m[i][k] -= s*m[j][k]
+        }
+      }
+    }
+  }
+  r := make([][]float64, n)
+  for i := range m {
+    r[i] = m[i][n:]
+  }
+  return r, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Assign predictions to ordered rating categories, which are the distinct
+// prediction values if there are at most n of them and quantile bins
+// otherwise
+func ratingCategories[T Float](values []T, n int) ([]int, int, error) {
+  distinct := make([]float64, len(values))
+  for i := range values {
+    distinct[i] = float64(values[i])
+  }
+  sort.Float64s(distinct)
+  k := 0
+  for i := range distinct {
+    if i == 0 || distinct[i] != distinct[k-1] {
+      distinct[k] = distinct[i]
+      k++
+    }
+  }
+  distinct = distinct[0:k]
+  r := make([]int, len(values))
+  if len(distinct) <= n {
+    for i := range values {
+      r[i] = sort.SearchFloat64s(distinct, float64(values[i]))
+    }
+    return r, len(distinct), nil
+  }
+  index, err := QuantileIndex(values, n); if err != nil {
+    return nil, 0, err
+  }
+  m := 0
+  for _, j := range index {
+    m = max(m, j+1)
+  }
+  for i, j := range index {
+    r[i] = m-1-j
+  }
+  return r, m, nil
+}
+
+// Fit a binormal ROC model by maximum likelihood (Dorfman and Alf, 1969).
+// Predictions are treated as ordinal ratings, where continuous predictions
+// are grouped into at most n quantile bins.
+func FitBinormal[T Float](values []T, labels []int, n int) (Binormal, error) {
+  if len(values) != len(labels) {
+    return Binormal{}, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if n < 2 {
+    return Binormal{}, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  category, m, err := ratingCategories(values, n); if err != nil {
+    return Binormal{}, err
+  }
+  neg := make([]int, m)
+  pos := make([]int, m)
+  for i, k := range category {
+    switch labels[i] {
+    case 1: pos[k]++
+    case 0: neg[k]++
+    default:
+      return Binormal{}, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  n_pos, n_neg := 0, 0
+  min_pos, max_neg := m, -1
+  for k := 0; k < m; k++ {
+    n_pos += pos[k]
+    n_neg += neg[k]
+    if pos[k] > 0 && k < min_pos {
+      min_pos = k
+    }
+    if neg[k] > 0 {
+      max_neg = k
+    }
+  }
+  if n_pos == 0 || n_neg == 0 {
+    return Binormal{}, fmt.Errorf("binormal model requires positive and negative predictions")
+  }
+  if m < 2 {
+    return Binormal{}, fmt.Errorf("binormal model requires at least two distinct prediction values")
+  }
+  if max_neg < min_pos {
+    return Binormal{}, fmt.Errorf("binormal model cannot be fitted to perfectly separated classes")
+  }
+  // initial values from the empirical ROC curve in normal deviate space
+  theta := make([]float64, m+1)
+  x, y  := []float64{}, []float64{}
+  c_neg, c_pos := n_neg, n_pos
+  for k := 1; k < m; k++ {
+    c_neg -= neg[k-1]
+    c_pos -= pos[k-1]
+    fpr := math.Min(math.Max(float64(c_neg)/float64(n_neg), 0.5/float64(n_neg)), 1.0 - 0.5/float64(n_neg))
+    tpr := math.Min(math.Max(float64(c_pos)/float64(n_pos), 0.5/float64(n_pos)), 1.0 - 0.5/float64(n_pos))
+    theta[k+1] = -normalQuantile(fpr)
+    if k > 1 && theta[k+1] <= theta[k] {
+      theta[k+1] = theta[k] + 1e-2
+    }
+    x = append(x, normalQuantile(fpr))
+    y = append(y, normalQuantile(tpr))
+  }
+  theta[0], theta[1] = 1.0, 1.0
+  if len(x) > 1 {
+    mx, my := 0.0, 0.0
+    for i := range x {
+      mx += x[i]/float64(len(x))
+      my += y[i]/float64(len(x))
+    }
+    sxy, sxx := 0.0, 0.0
+    for i := range x {
+      sxy += (x[i] - mx)*(y[i] - my)
+      sxx += (x[i] - mx)*(x[i] - mx)
+    }
+    if sxx > 0.0 && sxy > 0.0 {
+      theta[1] = sxy/sxx
+      theta[0] = my - theta[1]*mx
+    }
+  }
+  // maximize the likelihood with damped Newton steps
+  f  := func(theta []float64) float64 { return binormalLogLikelihood(theta, neg, pos) }
+  ll := f(theta)
+  if math.IsInf(ll, -1) {
+    return Binormal{}, fmt.Errorf("binormal fit failed: invalid initial values")
+  }
+  converged := false
+  for iter := 0; iter < 500 && !converged; iter++ {
+    g, H := numericalDerivatives(f, theta)
+    d := make([]float64, len(theta))
+    // Newton direction, or gradient direction if the Hessian is not
+    // negative definite
+    if Hinv, err := invertMatrix(H); err == nil {
+      for i := range d {
+        for j := range d {
+          d[i] -= Hinv[i][j]*g[j]
+        }
+      }
+    }
+    s := 0.0
+    for i := range d {
+      s += d[i]*g[i]
+    }
+    if !(s > 0.0) {
+      copy(d, g)
+    }
+    improved := false
+    for step := 1.0; step > 1e-10; step /= 2.0 {
+      t := make([]float64, len(theta))
+      for i := range t {
+        t[i] = theta[i] + step*d[i]
+      }
+      if l := f(t); l >= ll {
+        converged = l - ll < 1e-10
+        theta, ll, improved = t, l, true
+        break
+      }
+    }
+    if !improved {
+      converged = true
+    }
+  }
+  if !converged {
+    return Binormal{}, fmt.Errorf("binormal fit did not converge")
+  }
+  _, H := numericalDerivatives(f, theta)
+  for i := range H {
+    for j := range H[i] {
+      H[i][j] = -H[i][j]
+    }
+  }
+  cov, err := invertMatrix(H); if err != nil {
+    return Binormal{}, fmt.Errorf("binormal fit failed: %v", err)
+  }
+  r := Binormal{A: theta[0], B: theta[1], Thresholds: theta[2:], LogLikelihood: ll}
+  r.Covariance = [2][2]float64{{cov[0][0], cov[0][1]}, {cov[1][0], cov[1][1]}}
+  return r, nil
+}
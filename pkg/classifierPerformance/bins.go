@@ -0,0 +1,264 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// ScoreBin summarizes predictions with values in the interval [Min, Max].
+type ScoreBin struct {
+  Min, Max  float64
+  Positives int
+  Negatives int
+}
+
+func (obj ScoreBin) Len() int {
+  return obj.Positives + obj.Negatives
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Partition predictions into n bins of approximately equal size, ordered by
+// decreasing value (i.e. the first bin contains the highest scores). Tied
+// predictions always fall into the same bin, which may result in less than n
+// bins.
+func QuantileBins[T Float](values []T, labels []int, n int) ([]ScoreBin, error) {
+  if len(values) != len(labels) {
+    return nil, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if n < 1 {
+    return nil, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  v := make([]T,   len(values))
+  l := make([]int, len(labels))
+  copy(v, values)
+  copy(l, labels)
+  sort.Sort(sort.Reverse(predictions[T]{v, l}))
+  r := []ScoreBin{}
+  for _, b := range quantileBlocks(v, n) {
+    bin := ScoreBin{Min: float64(v[b[1]-1]), Max: float64(v[b[0]])}
+    for i := b[0]; i < b[1]; i++ {
+      switch l[i] {
+      case 1: bin.Positives++
+      case 0: bin.Negatives++
+      default:
+        return nil, fmt.Errorf("invalid label: %d", l[i])
+      }
+    }
+    r = append(r, bin)
+  }
+  return r, nil
+}
+
+// Index of the quantile bin of each prediction, where bins are ordered by
+// decreasing value as in QuantileBins (i.e. bin 0 contains the highest
+// scores). The result is aligned with the input.
+func QuantileIndex[T Float](values []T, n int) ([]int, error) {
+  if n < 1 {
+    return nil, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  index := make([]int, len(values))
+  for i := range index {
+    index[i] = i
+  }
+  sort.SliceStable(index, func(i, j int) bool { return values[index[i]] > values[index[j]] })
+  v := make([]T, len(values))
+  for i, j := range index {
+    v[i] = values[j]
+  }
+  r := make([]int, len(values))
+  for k, b := range quantileBlocks(v, n) {
+    for i := b[0]; i < b[1]; i++ {
+      r[index[i]] = k
+    }
+  }
+  return r, nil
+}
+
+// Split sorted values into at most n blocks [i,j) of approximately equal
+// size without separating tied values
+func quantileBlocks[T Float](v []T, n int) [][2]int {
+  r := [][2]int{}
+  for i, k := 0, 1; i < len(v); k++ {
+    // end of block k, moved to the end of a block of tied values
+    j := int(math.Round(float64(k)*float64(len(v))/float64(n)))
+    if j <= i {
+      j = i+1
+    }
+    for j < len(v) && v[j] == v[j-1] {
+      j++
+    }
+    r = append(r, [2]int{i, j})
+    i = j
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Kolmogorov-Smirnov table of score bins as used for validating scorecards.
+// For each bin, the table contains the score range, the number of positives
+// (bads) and negatives (goods), their cumulative counts and percentages, and
+// the difference between cumulative percentages (KS).
+func KSTable(bins []ScoreBin) Table {
+  names := []string{"min", "max", "n", "positives", "negatives", "cum_positives", "cum_negatives", "cum_positives_pct", "cum_negatives_pct", "ks"}
+  r := Table{Names: names, Columns: make([][]float64, len(names))}
+  n_pos, n_neg := 0, 0
+  for _, bin := range bins {
+    n_pos += bin.Positives
+    n_neg += bin.Negatives
+  }
+  c_pos, c_neg := 0, 0
+  for _, bin := range bins {
+    c_pos += bin.Positives
+    c_neg += bin.Negatives
+    p_pos := 100.0*float64(c_pos)/float64(n_pos)
+    p_neg := 100.0*float64(c_neg)/float64(n_neg)
+    for j, x := range []float64{bin.Min, bin.Max, float64(bin.Len()), float64(bin.Positives), float64(bin.Negatives),
+      float64(c_pos), float64(c_neg), p_pos, p_neg, math.Abs(p_pos - p_neg)} {
+      r.Columns[j] = append(r.Columns[j], x)
+    }
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Proportions of empty bins are replaced by this value when computing the
+// population stability index or weights of evidence
+const binEpsilon = 1e-4
+
+// StabilityBin compares the number of reference and current predictions
+// within a score range [Min, Max) and reports the contribution of the bin to
+// the population stability index.
+type StabilityBin struct {
+  Min, Max  float64
+  Reference int
+  Current   int
+  PSI       float64
+}
+
+// Population stability index between reference and current predictions.
+// Bins are quantiles of the reference predictions ordered by decreasing
+// value. The upper bin is unbounded from above and the lower bin from below,
+// so that all current predictions are counted. Returns the bins and the
+// overall index.
+func PopulationStability[T Float](reference, current []T, n int) ([]StabilityBin, float64, error) {
+  if n < 1 {
+    return nil, 0.0, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  if len(reference) == 0 || len(current) == 0 {
+    return nil, 0.0, fmt.Errorf("no predictions given")
+  }
+  // NaN values cannot be assigned to bins
+  for _, x := range current {
+    if math.IsNaN(float64(x)) {
+      return nil, 0.0, fmt.Errorf("invalid current prediction: NaN")
+    }
+  }
+  v := make([]float64, len(reference))
+  for i := range reference {
+    if v[i] = float64(reference[i]); math.IsNaN(v[i]) {
+      return nil, 0.0, fmt.Errorf("invalid reference prediction: NaN")
+    }
+  }
+  sort.Sort(sort.Reverse(sort.Float64Slice(v)))
+  blocks := quantileBlocks(v, n)
+  bins   := make([]StabilityBin, len(blocks))
+  for k, b := range blocks {
+    bins[k].Min       = v[b[1]-1]
+    bins[k].Max       = math.Inf(1)
+    bins[k].Reference = b[1]-b[0]
+    if k > 0 {
+      bins[k].Max = bins[k-1].Min
+    }
+  }
+  bins[len(bins)-1].Min = math.Inf(-1)
+  for _, x := range current {
+    // first bin with a lower bound not larger than x
+    k := sort.Search(len(bins), func(k int) bool { return bins[k].Min <= float64(x) })
+    bins[k].Current++
+  }
+  r := 0.0
+  for k := range bins {
+    p := math.Max(float64(bins[k].Reference)/float64(len(reference)), binEpsilon)
+    q := math.Max(float64(bins[k].Current  )/float64(len(current  )), binEpsilon)
+    bins[k].PSI = (q - p)*math.Log(q/p)
+    r += bins[k].PSI
+  }
+  return bins, r, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Decile summary of a gains chart. For each bin of decreasing scores, the
+// table contains the number of predictions and positives, the fraction of
+// all positives captured by the bin and cumulatively, the response rate
+// (fraction of positives within the bin), and the lift and cumulative lift
+// relative to the overall response rate.
+func GainsTable(bins []ScoreBin) Table {
+  names := []string{"min", "max", "n", "positives", "captured_pct", "cum_captured_pct", "response_pct", "lift", "cum_lift"}
+  r := Table{Names: names, Columns: make([][]float64, len(names))}
+  n, n_pos := 0, 0
+  for _, bin := range bins {
+    n     += bin.Len()
+    n_pos += bin.Positives
+  }
+  rate := float64(n_pos)/float64(n)
+  c, c_pos := 0, 0
+  for _, bin := range bins {
+    c     += bin.Len()
+    c_pos += bin.Positives
+    response := float64(bin.Positives)/float64(bin.Len())
+    for j, x := range []float64{bin.Min, bin.Max, float64(bin.Len()), float64(bin.Positives),
+      100.0*float64(bin.Positives)/float64(n_pos), 100.0*float64(c_pos)/float64(n_pos), 100.0*response,
+      response/rate, float64(c_pos)/float64(c)/rate} {
+      r.Columns[j] = append(r.Columns[j], x)
+    }
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Weight of evidence ln(g/b) of each bin, where g and b are the fractions of
+// all negatives (goods) and positives (bads) within the bin, and the
+// contribution (g-b)*ln(g/b) of each bin to the information value. Returns
+// weights of evidence, contributions and the total information value.
+func WeightOfEvidence(bins []ScoreBin) ([]float64, []float64, float64) {
+  n_pos, n_neg := 0, 0
+  for _, bin := range bins {
+    n_pos += bin.Positives
+    n_neg += bin.Negatives
+  }
+  woe := make([]float64, len(bins))
+  iv  := make([]float64, len(bins))
+  r   := 0.0
+  for k, bin := range bins {
+    g := math.Max(float64(bin.Negatives)/float64(n_neg), binEpsilon)
+    b := math.Max(float64(bin.Positives)/float64(n_pos), binEpsilon)
+    woe[k] = math.Log(g/b)
+    iv [k] = (g - b)*woe[k]
+    r += iv[k]
+  }
+  return woe, iv, r
+}
@@ -0,0 +1,57 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestPopulationStability(t *testing.T) {
+  reference := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8}
+  bins, r, err := PopulationStability(reference, reference, 4); if err != nil {
+    t.Fatal(err)
+  }
+  if r != 0.0 {
+    t.Errorf("identical distributions have index %f", r)
+  }
+  n := 0
+  for _, bin := range bins {
+    n += bin.Current
+  }
+  if n != len(reference) {
+    t.Errorf("%d of %d predictions assigned to bins", n, len(reference))
+  }
+  // predictions outside the reference range are counted in the outer bins
+  if _, r, err := PopulationStability(reference, []float64{-1.0, 2.0}, 4); err != nil {
+    t.Fatal(err)
+  } else if !(r > 0.0) {
+    t.Errorf("shifted distribution has index %f", r)
+  }
+}
+
+func TestPopulationStabilityNaN(t *testing.T) {
+  if _, _, err := PopulationStability([]float64{0.1, 0.2}, []float64{0.1, math.NaN()}, 2); err == nil {
+    t.Error("NaN current prediction not rejected")
+  }
+  if _, _, err := PopulationStability([]float64{math.NaN(), 0.2}, []float64{0.1, 0.2}, 2); err == nil {
+    t.Error("NaN reference prediction not rejected")
+  }
+}
@@ -0,0 +1,203 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "context"
+import   "fmt"
+import   "math"
+import   "math/rand"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Draw n bootstrap replicates of the predictions and evaluate f on each of
+// them. The result of replicate i is stored in the i-th row. Arguments passed
+// to f are reused between replicates and may be modified by f.
+func Bootstrap(values []float64, labels []int, n int, rng *rand.Rand, f func(values []float64, labels []int) ([]float64, error)) ([][]float64, error) {
+  return BootstrapContext(context.Background(), values, labels, n, rng, f)
+}
+
+func BootstrapContext(ctx context.Context, values []float64, labels []int, n int, rng *rand.Rand, f func(values []float64, labels []int) ([]float64, error)) ([][]float64, error) {
+  r := make([][]float64, n)
+  v := make([]float64, len(values))
+  l := make([]int,     len(labels))
+  for i := 0; i < n; i++ {
+    if err := ctx.Err(); err != nil {
+      return nil, err
+    }
+    for j := range v {
+      k   := rng.Intn(len(values))
+      v[j] = values[k]
+      l[j] = labels[k]
+    }
+    if x, err := f(v, l); err != nil {
+      return nil, err
+    } else {
+      r[i] = x
+    }
+  }
+  return r, nil
+}
+
+// Cluster bootstrap for correlated predictions (e.g. repeated measurements
+// of the same patient), where clusters are resampled with replacement and
+// all predictions of a sampled cluster are included in the replicate. The
+// i-th element of clusters identifies the cluster of the i-th prediction.
+// Replicates may differ in size. The result of replicate i is stored in the
+// i-th row. Arguments passed to f are reused between replicates and may be
+// modified by f.
+func BootstrapClusters(values []float64, labels []int, clusters []int, n int, rng *rand.Rand, f func(values []float64, labels []int) ([]float64, error)) ([][]float64, error) {
+  return BootstrapClustersContext(context.Background(), values, labels, clusters, n, rng, f)
+}
+
+func BootstrapClustersContext(ctx context.Context, values []float64, labels []int, clusters []int, n int, rng *rand.Rand, f func(values []float64, labels []int) ([]float64, error)) ([][]float64, error) {
+  if len(values) != len(labels) || len(values) != len(clusters) {
+    return nil, fmt.Errorf("number of predictions, labels and clusters do not match")
+  }
+  // indices of predictions in each cluster
+  index   := map[int]int{}
+  members := [][]int{}
+  for i, c := range clusters {
+    k, ok := index[c]
+    if !ok {
+      k = len(members)
+      index[c] = k
+      members  = append(members, nil)
+    }
+    members[k] = append(members[k], i)
+  }
+  r := make([][]float64, n)
+  v := make([]float64, 0, len(values))
+  l := make([]int,     0, len(labels))
+  for i := 0; i < n; i++ {
+    if err := ctx.Err(); err != nil {
+      return nil, err
+    }
+    v = v[:0]
+    l = l[:0]
+    for range members {
+      for _, j := range members[rng.Intn(len(members))] {
+        v = append(v, values[j])
+        l = append(l, labels[j])
+      }
+    }
+    if x, err := f(v, l); err != nil {
+      return nil, err
+    } else {
+      r[i] = x
+    }
+  }
+  return r, nil
+}
+
+// Draw n bootstrap replicates of indices 0, 1, ..., m-1 and evaluate f on
+// each of them. This allows to resample several aligned data sets jointly,
+// e.g. predictions of two classifiers on the same observations. The result
+// of replicate i is stored in the i-th row. The index slice passed to f is
+// reused between replicates.
+func BootstrapIndices(m, n int, rng *rand.Rand, f func(index []int) ([]float64, error)) ([][]float64, error) {
+  return BootstrapIndicesContext(context.Background(), m, n, rng, f)
+}
+
+func BootstrapIndicesContext(ctx context.Context, m, n int, rng *rand.Rand, f func(index []int) ([]float64, error)) ([][]float64, error) {
+  r     := make([][]float64, n)
+  index := make([]int, m)
+  for i := 0; i < n; i++ {
+    if err := ctx.Err(); err != nil {
+      return nil, err
+    }
+    for j := range index {
+      index[j] = rng.Intn(m)
+    }
+    if x, err := f(index); err != nil {
+      return nil, err
+    } else {
+      r[i] = x
+    }
+  }
+  return r, nil
+}
+
+// Stratified bootstrap, where predictions of each group are resampled
+// independently so that group sizes are preserved. The result of replicate
+// i is stored in the i-th row. Arguments passed to f are reused between
+// replicates and may be modified by f.
+func BootstrapGroups(values [][]float64, labels [][]int, n int, rng *rand.Rand, f func(values [][]float64, labels [][]int) ([]float64, error)) ([][]float64, error) {
+  return BootstrapGroupsContext(context.Background(), values, labels, n, rng, f)
+}
+
+func BootstrapGroupsContext(ctx context.Context, values [][]float64, labels [][]int, n int, rng *rand.Rand, f func(values [][]float64, labels [][]int) ([]float64, error)) ([][]float64, error) {
+  r := make([][]float64, n)
+  v := make([][]float64, len(values))
+  l := make([][]int,     len(labels))
+  for k := range values {
+    v[k] = make([]float64, len(values[k]))
+    l[k] = make([]int,     len(labels[k]))
+  }
+  for i := 0; i < n; i++ {
+    if err := ctx.Err(); err != nil {
+      return nil, err
+    }
+    for k := range v {
+      for j := range v[k] {
+        m      := rng.Intn(len(values[k]))
+        v[k][j] = values[k][m]
+        l[k][j] = labels[k][m]
+      }
+    }
+    if x, err := f(v, l); err != nil {
+      return nil, err
+    } else {
+      r[i] = x
+    }
+  }
+  return r, nil
+}
+
+// Percentile confidence interval at the given level (e.g. 0.95). Undefined
+// values are ignored.
+func PercentileInterval(x []float64, level float64) (float64, float64) {
+  y := make([]float64, 0, len(x))
+  for _, v := range x {
+    if !math.IsNaN(v) {
+      y = append(y, v)
+    }
+  }
+  if len(y) == 0 {
+    return math.NaN(), math.NaN()
+  }
+  sort.Float64s(y)
+  return Quantile(y, (1.0-level)/2.0), Quantile(y, (1.0+level)/2.0)
+}
+
+// Empirical quantile of sorted values using linear interpolation.
+func Quantile(x []float64, p float64) float64 {
+  if len(x) == 0 {
+    return math.NaN()
+  }
+  h := p*float64(len(x)-1)
+  i := int(math.Floor(h))
+  if i >= len(x)-1 {
+    return x[len(x)-1]
+  }
+  if i < 0 {
+    return x[0]
+  }
+  return x[i] + (h - float64(i))*(x[i+1] - x[i])
+}
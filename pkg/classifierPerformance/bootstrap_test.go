@@ -0,0 +1,70 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math/rand"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestBootstrapClustersAlignment(t *testing.T) {
+  // the value of a prediction identifies its cluster and position within the
+  // cluster, and the label is a function of the value
+  clusters := []int{2, 0, 1, 2, 0, 2}
+  values   := []float64{2.0, 0.0, 1.0, 2.1, 0.1, 2.2}
+  labels   := []int{1, 0, 1, 0, 0, 1}
+  label    := map[float64]int{}
+  size     := map[int]int{}
+  for i := range values {
+    label[values[i]] = labels[i]
+    size[clusters[i]]++
+  }
+  _, err := BootstrapClusters(values, labels, clusters, 100, rand.New(rand.NewSource(1)), func(v []float64, l []int) ([]float64, error) {
+    if len(v) != len(l) {
+      t.Fatalf("number of predictions and labels do not match")
+    }
+    count := map[float64]int{}
+    for i := range v {
+      if l[i] != label[v[i]] {
+        t.Fatalf("prediction %f has label %d", v[i], l[i])
+      }
+      count[v[i]]++
+    }
+    // sampled clusters are included as a whole
+    for i := range values {
+      for j := range values {
+        if clusters[i] == clusters[j] && count[values[i]] != count[values[j]] {
+          t.Fatalf("cluster %d is not sampled as a whole", clusters[i])
+        }
+      }
+    }
+    return []float64{float64(len(v))}, nil
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+}
+
+func TestBootstrapClustersLength(t *testing.T) {
+  f := func(v []float64, l []int) ([]float64, error) { return nil, nil }
+  if _, err := BootstrapClusters([]float64{0.1, 0.2}, []int{0, 1}, []int{0}, 1, rand.New(rand.NewSource(1)), f); err == nil {
+    t.Error("invalid number of clusters not rejected")
+  }
+}
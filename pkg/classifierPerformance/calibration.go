@@ -0,0 +1,89 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// Reliability holds a reliability diagram, i.e. the mean predicted
+// probability and the observed fraction of positives within bins of
+// predicted probabilities. Empty bins are omitted.
+type Reliability struct {
+  Predicted []float64
+  Observed  []float64
+  Count     []int
+}
+
+// Compute the reliability diagram of predicted probabilities using n bins of
+// equal width on [0,1].
+func EvalReliability[T Float](values []T, labels []int, n int) (Reliability, error) {
+  if len(values) != len(labels) {
+    return Reliability{}, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if n < 1 {
+    return Reliability{}, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  sum   := make([]float64, n)
+  pos   := make([]int,     n)
+  count := make([]int,     n)
+  for i := range values {
+    v := float64(values[i])
+    if v < 0.0 || v > 1.0 || math.IsNaN(v) {
+      return Reliability{}, fmt.Errorf("prediction is not a probability: %f", v)
+    }
+    if labels[i] != 0 && labels[i] != 1 {
+      return Reliability{}, fmt.Errorf("invalid label: %d", labels[i])
+    }
+    // the last bin includes 1
+    k := int(v*float64(n))
+    if k == n {
+      k--
+    }
+    sum  [k] += v
+    pos  [k] += labels[i]
+    count[k] += 1
+  }
+  r := Reliability{}
+  for k := 0; k < n; k++ {
+    if count[k] > 0 {
+      r.Predicted = append(r.Predicted, sum[k]/float64(count[k]))
+      r.Observed  = append(r.Observed , float64(pos[k])/float64(count[k]))
+      r.Count     = append(r.Count    , count[k])
+    }
+  }
+  return r, nil
+}
+
+func (obj Reliability) Len() int {
+  return len(obj.Count)
+}
+
+// Expected calibration error, i.e. the weighted mean absolute difference
+// between predicted probabilities and observed fractions of positives.
+func (obj Reliability) ECE() float64 {
+  r := 0.0
+  n := 0
+  for k := 0; k < obj.Len(); k++ {
+    r += float64(obj.Count[k])*math.Abs(obj.Predicted[k] - obj.Observed[k])
+    n += obj.Count[k]
+  }
+  return r/float64(n)
+}
@@ -22,65 +22,324 @@ import   "fmt"
 import   "math"
 import   "sort"
 
-import   "bufio"
+import   "bytes"
+import   "context"
 import   "io"
 import   "strconv"
-import   "strings"
 
 /* -------------------------------------------------------------------------- */
 
-func ReadPredictions(reader io.Reader) ([]float64, []int, error) {
-  scanner := bufio.NewScanner(reader)
+// Read predictions row by row and call f on each prediction. This allows to
+// process prediction tables that do not fit into memory.
+func ScanPredictions(reader io.Reader, f func(value float64, label int) error) error {
+  return ScanPredictionsContext(context.Background(), reader, f)
+}
+
+// Same as ScanPredictions, but stops reading with the error of ctx as soon as
+// ctx is cancelled. If reader is an io.Closer, it is closed on cancellation,
+// which also interrupts a blocking read, e.g. from a pipe.
+func ScanPredictionsContext(ctx context.Context, reader io.Reader, f func(value float64, label int) error) error {
+  return scanPredictionTable(ctx, reader, nil, math.NaN(), false, func(value float64, label int, relevance float64, fields, row [][]byte) error {
+    return f(value, label)
+  })
+}
+
+// Close reader as soon as ctx is cancelled, if reader is an io.Closer. The
+// returned function stops waiting for ctx and must be called once reading
+// is finished.
+func closeOnDone(ctx context.Context, reader io.Reader) func() bool {
+  if closer, ok := reader.(io.Closer); ok {
+    return context.AfterFunc(ctx, func() { closer.Close() })
+  }
+  return func() bool { return false }
+}
+
+// Reading from a reader closed by closeOnDone fails with an arbitrary error,
+// which is replaced by the error of ctx
+func contextError(ctx context.Context, err error) error {
+  if ctx.Err() != nil {
+    return ctx.Err()
+  }
+  return err
+}
+
+// Function called on each row of a prediction table with the values of
+// requested extra columns (fields) and all fields of the row
+type predictionRowFunc func(value float64, label int, relevance float64, fields, row [][]byte) error
+
+// Scan a prediction table, which may contain additional columns. The values of
+// all requested extra columns are passed to f in the given order, followed by
+// all fields of the row. The slices are only valid until f returns. If
+// threshold is NaN, labels must be 0 or 1. Otherwise, labels are graded
+// relevance values, which are passed to f as relevance, and predictions with
+// relevance of at least threshold are labeled as positive. If missing is
+// true, labels may also be `NA', which are passed to f as MissingLabel with
+// relevance NaN.
+func scanPredictionTable(ctx context.Context, reader io.Reader, extra []string, threshold float64, missing bool, f predictionRowFunc) error {
+  lr     := newLineReader(reader)
+  fields := make([][]byte, 0, 2)
+  values := make([][]byte, len(extra))
 
   i_predictions := -1
   i_labels      := -1
+  i_extra       := make([]int, len(extra))
 
-  values := []float64{}
-  labels := []int{}
+  columns := []string{}
+
+  defer closeOnDone(ctx, reader)()
 
-  if scanner.Scan() {
-    fields := strings.Fields(scanner.Text())
-    if len(fields) != 2 {
-      return nil, nil, fmt.Errorf("invalid predictions table")
+  if line, err := lr.ReadLine(); err == io.EOF {
+    return nil
+  } else
+  if err != nil {
+    return contextError(ctx, err)
+  } else {
+    fields = splitFields(line, fields)
+    for i := 0; i < len(fields); i++ {
+      columns = append(columns, string(fields[i]))
     }
-    for i := 0; i < 2; i++ {
-      if fields[i] == "predictions" || fields[i] == "prediction" {
+    for i := 0; i < len(columns); i++ {
+      if columns[i] == "predictions" || columns[i] == "prediction" {
         i_predictions = i
       }
     }
-    for i := 0; i < 2; i++ {
-      if fields[i] == "labels" || fields[i] == "label" {
+    for i := 0; i < len(columns); i++ {
+      if columns[i] == "labels" || columns[i] == "label" {
         i_labels = i
       }
     }
     if i_predictions == -1 {
-      return nil, nil, fmt.Errorf("no column called `predictions' found")
+      return newParseError(1, "", nil, line, fmt.Errorf("no column called `predictions' found"))
     }
     if i_labels == -1 {
-      return nil, nil, fmt.Errorf("no column called `labels' found")
+      return newParseError(1, "", nil, line, fmt.Errorf("no column called `labels' found"))
+    }
+    for k, name := range extra {
+      i_extra[k] = -1
+      for i := 0; i < len(columns); i++ {
+        if columns[i] == name {
+          i_extra[k] = i
+        }
+      }
+      if i_extra[k] == -1 {
+        return newParseError(1, "", nil, line, fmt.Errorf("no column called `%s' found", name))
+      }
     }
   }
 
   // read header
-  for scanner.Scan() {
-    fields := strings.Fields(scanner.Text())
-    label, err := strconv.ParseInt(fields[i_labels], 10, 64); if err != nil {
-      return nil, nil, err
+  for n := 2;; n++ {
+    // checking the context is relatively expensive, do it only every
+    // few thousand lines
+    if n % 4096 == 0 {
+      if err := ctx.Err(); err != nil {
+        return err
+      }
+    }
+    line, err := lr.ReadLine()
+    if err == io.EOF {
+      break
     }
-    value, err := strconv.ParseFloat(fields[i_predictions], 64); if err != nil {
-      return nil, nil, err
+    if err != nil {
+      return contextError(ctx, err)
     }
-    if label != 0 && label != 1 {
-      return nil, nil, fmt.Errorf("invalid label `%d' observed", label)
+    fields = splitFields(line, fields)
+    if len(fields) == 0 {
+      continue
     }
+    if len(fields) != len(columns) {
+      return newParseError(n, "", nil, line, fmt.Errorf("expected %d columns but found %d", len(columns), len(fields)))
+    }
+    var label     int64
+    var relevance float64
+    if missing && string(fields[i_labels]) == "NA" {
+      label     = MissingLabel
+      relevance = math.NaN()
+    } else
+    if math.IsNaN(threshold) {
+      label, err = strconv.ParseInt(string(fields[i_labels]), 10, 64); if err != nil {
+        return newParseError(n, columns[i_labels], fields[i_labels], line, err.(*strconv.NumError).Err)
+      }
+      relevance = float64(label)
+    } else {
+      relevance, err = strconv.ParseFloat(string(fields[i_labels]), 64); if err != nil {
+        return newParseError(n, columns[i_labels], fields[i_labels], line, err.(*strconv.NumError).Err)
+      }
+      if relevance < 0.0 || math.IsNaN(relevance) || math.IsInf(relevance, 1) {
+        return newParseError(n, columns[i_labels], fields[i_labels], line, fmt.Errorf("relevance must be a non-negative number"))
+      }
+      if relevance >= threshold {
+        label = 1
+      }
+    }
+    value, err := strconv.ParseFloat(string(fields[i_predictions]), 64); if err != nil {
+      return newParseError(n, columns[i_predictions], fields[i_predictions], line, err.(*strconv.NumError).Err)
+    }
+    if math.IsNaN(value) {
+      return newParseError(n, columns[i_predictions], fields[i_predictions], line, fmt.Errorf("prediction is not a number"))
+    }
+    if label != 0 && label != 1 && !(missing && label == MissingLabel && math.IsNaN(relevance)) {
+      return newParseError(n, columns[i_labels], fields[i_labels], line, fmt.Errorf("labels must be 0 or 1"))
+    }
+    for k, i := range i_extra {
+      values[k] = fields[i]
+    }
+    if err := f(value, int(label), relevance, values, fields); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func ReadPredictions(reader io.Reader) ([]float64, []int, error) {
+  return ReadPredictionsContext(context.Background(), reader)
+}
+
+func ReadPredictionsContext(ctx context.Context, reader io.Reader) ([]float64, []int, error) {
+  values := []float64{}
+  labels := []int{}
+  if err := ScanPredictionsContext(ctx, reader, func(value float64, label int) error {
     values = append(values, value)
-    labels = append(labels, int(label))
+    labels = append(labels, label)
+    return nil
+  }); err != nil {
+    return nil, nil, err
   }
   return values, labels, nil
 }
 
 /* -------------------------------------------------------------------------- */
 
+// Label of predictions with unknown labels in tables read with
+// ReadPartialPredictionTable
+const MissingLabel = -1
+
+// PredictionTable holds predictions and labels together with additional
+// columns of the input table, e.g. cross-validation folds or groups.
+// Relevance holds graded relevance values of tables read with
+// ReadGradedPredictionTable and is nil otherwise. Rows holds the raw rows
+// (with fields separated by single spaces) of tables read with
+// ReadPredictionTableRows and is nil otherwise.
+type PredictionTable struct {
+  Values    []float64
+  Labels    []int
+  Relevance []float64
+  Columns   map[string][]string
+  Rows      []string
+}
+
+// Read a prediction table and keep the given additional columns.
+func ReadPredictionTable(reader io.Reader, columns ...string) (PredictionTable, error) {
+  return ReadPredictionTableContext(context.Background(), reader, columns...)
+}
+
+func ReadPredictionTableContext(ctx context.Context, reader io.Reader, columns ...string) (PredictionTable, error) {
+  return readPredictionTable(math.NaN(), columns, false, func(f predictionRowFunc) error {
+    return scanPredictionTable(ctx, reader, columns, math.NaN(), false, f)
+  })
+}
+
+// Read a prediction table with graded relevance values (e.g. on a scale from
+// 0 to 4) instead of binary labels. Predictions with relevance of at least
+// threshold are labeled as positive.
+func ReadGradedPredictionTable(reader io.Reader, threshold float64, columns ...string) (PredictionTable, error) {
+  return ReadGradedPredictionTableContext(context.Background(), reader, threshold, columns...)
+}
+
+func ReadGradedPredictionTableContext(ctx context.Context, reader io.Reader, threshold float64, columns ...string) (PredictionTable, error) {
+  if math.IsNaN(threshold) {
+    return PredictionTable{}, fmt.Errorf("invalid relevance threshold: %f", threshold)
+  }
+  return readPredictionTable(threshold, columns, false, func(f predictionRowFunc) error {
+    return scanPredictionTable(ctx, reader, columns, threshold, false, f)
+  })
+}
+
+// Read a prediction table and keep the given additional columns as well as
+// all rows, e.g. to detect duplicate rows. If threshold is not NaN, labels
+// are graded relevance values as in ReadGradedPredictionTable.
+func ReadPredictionTableRows(reader io.Reader, threshold float64, columns ...string) (PredictionTable, error) {
+  return ReadPredictionTableRowsContext(context.Background(), reader, threshold, columns...)
+}
+
+func ReadPredictionTableRowsContext(ctx context.Context, reader io.Reader, threshold float64, columns ...string) (PredictionTable, error) {
+  return readPredictionTable(threshold, columns, true, func(f predictionRowFunc) error {
+    return scanPredictionTable(ctx, reader, columns, threshold, false, f)
+  })
+}
+
+// Read a prediction table where labels of some predictions are unknown and
+// given as `NA', e.g. labels of unverified predictions in screening studies.
+// Unknown labels are stored as MissingLabel. If rows is true, all rows are
+// kept as in ReadPredictionTableRows.
+func ReadPartialPredictionTable(reader io.Reader, rows bool, columns ...string) (PredictionTable, error) {
+  return ReadPartialPredictionTableContext(context.Background(), reader, rows, columns...)
+}
+
+func ReadPartialPredictionTableContext(ctx context.Context, reader io.Reader, rows bool, columns ...string) (PredictionTable, error) {
+  return readPredictionTable(math.NaN(), columns, rows, func(f predictionRowFunc) error {
+    return scanPredictionTable(ctx, reader, columns, math.NaN(), true, f)
+  })
+}
+
+// Collect the rows of a prediction table passed by scan to its argument
+func readPredictionTable(threshold float64, columns []string, rows bool, scan func(predictionRowFunc) error) (PredictionTable, error) {
+  r := PredictionTable{}
+  r.Values  = []float64{}
+  r.Labels  = []int{}
+  r.Columns = make(map[string][]string)
+  if !math.IsNaN(threshold) {
+    r.Relevance = []float64{}
+  }
+  if rows {
+    r.Rows = []string{}
+  }
+  for _, name := range columns {
+    r.Columns[name] = []string{}
+  }
+  if err := scan(func(value float64, label int, relevance float64, fields, row [][]byte) error {
+    r.Values = append(r.Values, value)
+    r.Labels = append(r.Labels, label)
+    if r.Relevance != nil {
+      r.Relevance = append(r.Relevance, relevance)
+    }
+    for k, name := range columns {
+      r.Columns[name] = append(r.Columns[name], string(fields[k]))
+    }
+    if r.Rows != nil {
+      r.Rows = append(r.Rows, string(bytes.Join(row, []byte(" "))))
+    }
+    return nil
+  }); err != nil {
+    return PredictionTable{}, err
+  }
+  return r, nil
+}
+
+// Group predictions by the values of a categorical column. Groups are
+// returned in order of first appearance.
+func GroupPredictions(values []float64, labels []int, groups []string) ([]string, [][]float64, [][]int) {
+  names := []string{}
+  index := make(map[string]int)
+  r_v   := [][]float64{}
+  r_l   := [][]int{}
+  for i, g := range groups {
+    k, ok := index[g]
+    if !ok {
+      k = len(names)
+      index[g] = k
+      names = append(names, g)
+      r_v   = append(r_v, []float64{})
+      r_l   = append(r_l, []int{})
+    }
+    r_v[k] = append(r_v[k], values[i])
+    r_l[k] = append(r_l[k], labels[i])
+  }
+  return names, r_v, r_l
+}
+
+/* -------------------------------------------------------------------------- */
+
 type Predictions struct {
   Values []float64
   Labels []int
@@ -101,6 +360,31 @@ func (obj Predictions) Less(i, j int) bool {
 
 /* -------------------------------------------------------------------------- */
 
+// Float is the set of types that can be used for prediction values.
+type Float interface {
+  ~float32 | ~float64
+}
+
+type predictions[T Float] struct {
+  values []T
+  labels []int
+}
+
+func (obj predictions[T]) Len() int {
+  return len(obj.values)
+}
+
+func (obj predictions[T]) Swap(i, j int) {
+  obj.values[i], obj.values[j] = obj.values[j], obj.values[i]
+  obj.labels[i], obj.labels[j] = obj.labels[j], obj.labels[i]
+}
+
+func (obj predictions[T]) Less(i, j int) bool {
+  return obj.values[i] < obj.values[j]
+}
+
+/* -------------------------------------------------------------------------- */
+
 type Performance struct {
   Tr []float64
   Tp []int
@@ -114,15 +398,51 @@ func (obj Performance) Len() int {
   return len(obj.Tr)
 }
 
+// Merge two performance objects computed on disjoint sets of predictions. The
+// result is evaluated on the union of both threshold grids.
+func Merge(a, b Performance) Performance {
+  tr := []float64{}
+  tp := []int{}
+  fp := []int{}
+  tn := []int{}
+  fn := []int{}
+  // number of negative and positive predictions below or equal to the
+  // current threshold in a and b
+  tn_a, fn_a := 0, 0
+  tn_b, fn_b := 0, 0
+  for i, j := 0, 0; i < a.Len() || j < b.Len(); {
+    var t float64
+    switch {
+    case j >= b.Len() || (i < a.Len() && a.Tr[i] < b.Tr[j]):
+      t = a.Tr[i]
+    default:
+      t = b.Tr[j]
+    }
+    if i < a.Len() && a.Tr[i] == t {
+      tn_a, fn_a = a.Tn[i], a.Fn[i]; i++
+    }
+    if j < b.Len() && b.Tr[j] == t {
+      tn_b, fn_b = b.Tn[j], b.Fn[j]; j++
+    }
+    tr = append(tr, t)
+    tp = append(tp, a.P - fn_a + b.P - fn_b)
+    fp = append(fp, a.N - tn_a + b.N - tn_b)
+    tn = append(tn, tn_a + tn_b)
+    fn = append(fn, fn_a + fn_b)
+  }
+  return Performance{Tr: tr, Tp: tp, Fp: fp, Tn: tn, Fn: fn, P: a.P + b.P, N: a.N + b.N}
+}
+
 /* -------------------------------------------------------------------------- */
 
-func EvalPerformance(values []float64, labels []int) (Performance, error) {
-  sort.Sort(Predictions{values, labels})
+func EvalPerformance[T Float](values []T, labels []int) (Performance, error) {
+  sort.Sort(predictions[T]{values, labels})
   n_pos := 0
   n_neg := 0
-  n_pos_map := make(map[float64]int)
-  n_neg_map := make(map[float64]int)
-  for i, _ := range values {
+  for i, _ := range labels {
+    if math.IsNaN(float64(values[i])) {
+      return Performance{}, fmt.Errorf("invalid prediction: NaN")
+    }
     if labels[i] == 1 {
       n_pos += 1
     } else
@@ -131,49 +451,176 @@ func EvalPerformance(values []float64, labels []int) (Performance, error) {
     } else {
       return Performance{}, fmt.Errorf("invalid label: %d", labels[i])
     }
-    n_pos_map[values[i]] = n_pos
-    n_neg_map[values[i]] = n_neg
   }
-  // create a list of unique thresholds
   tr := []float64{}
-  for v, _ := range n_pos_map {
-    tr = append(tr, v)
+  tp := []int{}
+  fp := []int{}
+  tn := []int{}
+  fn := []int{}
+  // number of positive and negative predictions with values below or
+  // equal to the current threshold
+  c_pos := 0
+  c_neg := 0
+  // sweep over blocks of tied predictions, each block defines a unique
+  // threshold
+  for i := 0; i < len(values); {
+    j := i+1
+    for j < len(values) && values[j] == values[i] {
+      j++
+    }
+    for k := i; k < j; k++ {
+      if labels[k] == 1 {
+        c_pos += 1
+      } else {
+        c_neg += 1
+      }
+    }
+    tr = append(tr, float64(values[i]))
+    tp = append(tp, n_pos - c_pos)
+    fp = append(fp, n_neg - c_neg)
+    tn = append(tn, c_neg)
+    fn = append(fn, c_pos)
+    i  = j
   }
+  return Performance{Tr: tr, Tp: tp, Fp: fp, Tn: tn, Fn: fn, P: n_pos, N: n_neg}, nil
+}
+
+// Evaluate performance on a given grid of thresholds instead of all unique
+// prediction values. A prediction is classified as positive if its value
+// is strictly larger than the threshold.
+func EvalPerformanceGrid[T Float](values []T, labels []int, grid []float64) (Performance, error) {
+  sort.Sort(predictions[T]{values, labels})
+  tr := make([]float64, len(grid))
+  copy(tr, grid)
   sort.Float64s(tr)
+  // remove duplicate thresholds
+  if len(tr) > 0 {
+    k := 1
+    for i := 1; i < len(tr); i++ {
+      if tr[i] != tr[k-1] {
+        tr[k] = tr[i]; k++
+      }
+    }
+    tr = tr[0:k]
+  }
+  n_pos := 0
+  n_neg := 0
+  for i, _ := range labels {
+    if math.IsNaN(float64(values[i])) {
+      return Performance{}, fmt.Errorf("invalid prediction: NaN")
+    }
+    if labels[i] == 1 {
+      n_pos += 1
+    } else
+    if labels[i] == 0 {
+      n_neg += 1
+    } else {
+      return Performance{}, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
   tp := make([]int, len(tr))
   fp := make([]int, len(tr))
   tn := make([]int, len(tr))
   fn := make([]int, len(tr))
-  for i, t := range tr {
-    tp[i] = n_pos - n_pos_map[t]
-    fp[i] = n_neg - n_neg_map[t]
-    tn[i] = n_neg_map[t]
-    fn[i] = n_pos_map[t]
+  // number of positive and negative predictions with values below or
+  // equal to the current threshold
+  c_pos := 0
+  c_neg := 0
+  for i, j := 0, 0; i < len(tr); i++ {
+    for ; j < len(values) && float64(values[j]) <= tr[i]; j++ {
+      if labels[j] == 1 {
+        c_pos += 1
+      } else {
+        c_neg += 1
+      }
+    }
+    tp[i] = n_pos - c_pos
+    fp[i] = n_neg - c_neg
+    tn[i] = c_neg
+    fn[i] = c_pos
   }
   return Performance{Tr: tr, Tp: tp, Fp: fp, Tn: tn, Fn: fn, P: n_pos, N: n_neg}, nil
 }
 
-func EvalPrecisionRecall(values []float64, labels []int, normalize bool) ([]float64, []float64, error) {
+func EvalPrecisionRecall[T Float](values []T, labels []int, normalize bool) (Curve, error) {
   if perf, err := EvalPerformance(values, labels); err != nil {
-    return nil, nil, err
+    return Curve{}, err
   } else {
-    r, p := PrecisionRecall(perf, normalize)
-    return r, p, nil
+    return PrecisionRecall(perf, normalize), nil
   }
 }
 
-func EvalRoc(values []float64, labels []int) ([]float64, []float64, error) {
+func EvalRoc[T Float](values []T, labels []int) (Curve, error) {
   if perf, err := EvalPerformance(values, labels); err != nil {
-    return nil, nil, err
+    return Curve{}, err
   } else {
-    r, p := Roc(perf)
-    return r, p, nil
+    return Roc(perf), nil
   }
 }
 
 /* -------------------------------------------------------------------------- */
 
-func PrecisionRecall(perf Performance, normalize bool) ([]float64, []float64) {
+// Maximal number of thresholds of a grid computed by QuantileGrid
+const MaxQuantileGridSize = 10000000
+
+// Compute a grid of n thresholds at the empirical quantiles of the
+// predictions. Values must be sorted in ascending order. Since a grid cannot
+// have more thresholds than predictions, n is reduced to the number of
+// predictions, which yields the same grid. At most MaxQuantileGridSize
+// thresholds may be requested.
+func QuantileGrid[T Float](values []T, n int) ([]float64, error) {
+  if n > MaxQuantileGridSize {
+    return nil, fmt.Errorf("number of thresholds %d exceeds %d", n, MaxQuantileGridSize)
+  }
+  grid := []float64{}
+  if len(values) == 0 || n <= 0 {
+    return grid, nil
+  }
+  if n > len(values) {
+    n = len(values)
+  }
+  if n == 1 {
+    return append(grid, float64(values[len(values)-1])), nil
+  }
+  for k := 0; k < n; k++ {
+    t := float64(values[k*(len(values)-1)/(n-1)])
+    if len(grid) == 0 || grid[len(grid)-1] != t {
+      grid = append(grid, t)
+    }
+  }
+  return grid, nil
+}
+
+// Maximal number of thresholds of a grid computed by StepGrid
+const MaxStepGridSize = 10000000
+
+// Compute a grid of equally spaced thresholds between the smallest and
+// largest prediction. Values must be sorted in ascending order. The step
+// width must be positive and yield at most MaxStepGridSize thresholds.
+func StepGrid[T Float](values []T, step float64) ([]float64, error) {
+  if !(step > 0.0) || math.IsInf(step, 1) {
+    return nil, fmt.Errorf("invalid step width: %v", step)
+  }
+  grid := []float64{}
+  if len(values) == 0 {
+    return grid, nil
+  }
+  min := float64(values[0])
+  max := float64(values[len(values)-1])
+  if n := (max - min)/step; !(n < MaxStepGridSize) {
+    return nil, fmt.Errorf("step width %v yields more than %d thresholds", step, MaxStepGridSize)
+  }
+  for k := 0; min + float64(k)*step < max; k++ {
+    grid = append(grid, min + float64(k)*step)
+  }
+  return append(grid, max), nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Compute the precision-recall curve with recall on the x-axis and precision
+// on the y-axis.
+func PrecisionRecall(perf Performance, normalize bool) Curve {
   precision := make([]float64, perf.Len())
   recall    := make([]float64, perf.Len())
   for i := 0; i < len(precision); i++ {
@@ -191,35 +638,169 @@ func PrecisionRecall(perf Performance, normalize bool) ([]float64, []float64) {
       precision[i] = (precision[i] - c)/(1.0 - c)
     }
   }
-  return recall, precision
+  return Curve{X: recall, Y: precision, Tr: perf.Tr}
 }
 
-func Roc(perf Performance) ([]float64, []float64) {
+// Compute the ROC curve with the false positive rate on the x-axis and the
+// true positive rate on the y-axis.
+func Roc(perf Performance) Curve {
   tpr := make([]float64, perf.Len())
   fpr := make([]float64, perf.Len())
   for i := 0; i < len(tpr); i++ {
     tpr[i] = float64(perf.Tp[i])/float64(perf.P)
     fpr[i] = float64(perf.Fp[i])/float64(perf.N)
   }
-  return fpr, tpr
+  return Curve{X: fpr, Y: tpr, Tr: perf.Tr}
+}
+
+// Cumulative gains curve, i.e. the fraction of all positives captured as a
+// function of the fraction of predictions classified as positive.
+func Gains(perf Performance) Curve {
+  x := make([]float64, perf.Len())
+  y := make([]float64, perf.Len())
+  for i := 0; i < len(x); i++ {
+    x[i] = float64(perf.Tp[i] + perf.Fp[i])/float64(perf.P + perf.N)
+    y[i] = float64(perf.Tp[i])/float64(perf.P)
+  }
+  return Curve{X: x, Y: y, Tr: perf.Tr}
+}
+
+// Lift curve, i.e. the ratio of the fraction of captured positives and the
+// fraction of predictions classified as positive. Thresholds at which no
+// prediction is classified as positive are omitted.
+func Lift(perf Performance) Curve {
+  gains := Gains(perf)
+  r := Curve{}
+  for i := 0; i < gains.Len(); i++ {
+    if gains.X[i] > 0.0 {
+      r.X  = append(r.X , gains.X[i])
+      r.Y  = append(r.Y , gains.Y[i]/gains.X[i])
+      r.Tr = append(r.Tr, gains.Tr[i])
+    }
+  }
+  return r
+}
+
+// Free-response ROC (FROC) curve, i.e. the lesion localization fraction as a
+// function of the number of false positives per image. Performance must be
+// computed on candidate detections, where a positive label marks a candidate
+// that correctly localizes a lesion. Images and lesions are the total
+// numbers of images and lesions, including images and lesions without any
+// candidate. The curve ends at the point where all candidates are classified
+// as positive, which has threshold -Inf if it is not already contained in
+// the performance.
+func Froc(perf Performance, images, lesions int) Curve {
+  r := Curve{}
+  if perf.Len() == 0 || perf.Tp[0] + perf.Fp[0] < perf.P + perf.N {
+    r.X  = append(r.X , float64(perf.N)/float64(images))
+    r.Y  = append(r.Y , float64(perf.P)/float64(lesions))
+    r.Tr = append(r.Tr, math.Inf(-1))
+  }
+  for i := 0; i < perf.Len(); i++ {
+    r.X  = append(r.X , float64(perf.Fp[i])/float64(images))
+    r.Y  = append(r.Y , float64(perf.Tp[i])/float64(lesions))
+    r.Tr = append(r.Tr, perf.Tr[i])
+  }
+  return r
+}
+
+// Precision and recall as a function of the number of predictions classified
+// as positive (flagged), which allows to select a threshold matching a given
+// review capacity. Thresholds at which no prediction is flagged are omitted.
+func AlertVolume(perf Performance) Table {
+  r := Table{Names: []string{"flagged", "precision", "recall", "threshold"}, Columns: make([][]float64, 4)}
+  for i := 0; i < perf.Len(); i++ {
+    if n := perf.Tp[i] + perf.Fp[i]; n > 0 {
+      r.Columns[0] = append(r.Columns[0], float64(n))
+      r.Columns[1] = append(r.Columns[1], float64(perf.Tp[i])/float64(n))
+      r.Columns[2] = append(r.Columns[2], float64(perf.Tp[i])/float64(perf.P))
+      r.Columns[3] = append(r.Columns[3], perf.Tr[i])
+    }
+  }
+  return r
 }
 
 /* -------------------------------------------------------------------------- */
 
-func AUC(x, y []float64) float64 {
+func AUC(x, y []float64) (float64, error) {
   n1 := len(x)
   n2 := len(y)
   if n1 != n2 {
-    panic("internal error")
+    return 0.0, fmt.Errorf("curve has invalid number of points: len(x)=%d, len(y)=%d", n1, n2)
+  }
+  if n1 == 0 {
+    return 0.0, fmt.Errorf("curve is empty")
   }
   result := 0.0
 
+  for i := 0; i < n1; i++ {
+    if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+      return 0.0, fmt.Errorf("curve has undefined value at point %d", i)
+    }
+  }
   for i := 1; i < n1; i++ {
     dx := math.Abs(x[i] - x[i-1])
     dy := (y[i] + y[i-1])/2.0
     result += dx*dy
   }
-  return result
+  return result, nil
+}
+
+// Same as AUC, but panics on error.
+func MustAUC(x, y []float64) float64 {
+  if r, err := AUC(x, y); err != nil {
+    panic(err)
+  } else {
+    return r
+  }
+}
+
+// Compute the area under the ROC curve from the Mann-Whitney U statistic.
+// Tied predictions receive midranks, so that ties contribute 1/2 to the
+// statistic. NaN predictions cannot be ranked and are rejected.
+func AUCRankSum[T Float](values []T, labels []int) (float64, error) {
+  if len(values) != len(labels) {
+    return 0.0, fmt.Errorf("number of predictions and labels do not match")
+  }
+  for _, x := range values {
+    if math.IsNaN(float64(x)) {
+      return 0.0, fmt.Errorf("invalid prediction: NaN")
+    }
+  }
+  v := make([]T,   len(values))
+  l := make([]int, len(labels))
+  copy(v, values)
+  copy(l, labels)
+  sort.Sort(predictions[T]{v, l})
+  n_pos := 0
+  n_neg := 0
+  r_pos := 0.0
+  for i := 0; i < len(v); {
+    // find all predictions tied with v[i]
+    j := i+1
+    for j < len(v) && v[j] == v[i] {
+      j++
+    }
+    // midrank of the tied block (ranks start at 1)
+    r := float64(i+j+1)/2.0
+    for k := i; k < j; k++ {
+      if l[k] == 1 {
+        n_pos += 1
+        r_pos += r
+      } else
+      if l[k] == 0 {
+        n_neg += 1
+      } else {
+        return 0.0, fmt.Errorf("invalid label: %d", l[k])
+      }
+    }
+    i = j
+  }
+  if n_pos == 0 || n_neg == 0 {
+    return math.NaN(), nil
+  }
+  u := r_pos - float64(n_pos)*float64(n_pos+1)/2.0
+  return u/(float64(n_pos)*float64(n_neg)), nil
 }
 
 func Optimum(tr, x, y []float64) int {
@@ -233,3 +814,13 @@ func Optimum(tr, x, y []float64) int {
   }
   return k
 }
+
+// Find the threshold that maximizes the product of specificity (1-FPR) and
+// sensitivity (TPR).
+func OptimumRoc(tr, fpr, tpr []float64) int {
+  fpr_inv := make([]float64, len(fpr))
+  for i := 0; i < len(fpr); i++ {
+    fpr_inv[i] = 1.0 - fpr[i]
+  }
+  return Optimum(tr, fpr_inv, tpr)
+}
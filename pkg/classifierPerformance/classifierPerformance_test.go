@@ -0,0 +1,203 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "reflect"
+import   "sort"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestEvalPerformanceNaN(t *testing.T) {
+  values := []float64{0.1, math.NaN(), 0.3}
+  labels := []int{0, 1, 1}
+  if _, err := EvalPerformance(values, labels); err == nil {
+    t.Error("NaN prediction not rejected")
+  }
+}
+
+func TestEvalPerformanceTies(t *testing.T) {
+  values := []float64{0.5, 0.1, 0.5, 0.9, 0.5}
+  labels := []int{1, 0, 0, 1, 0}
+  perf, err := EvalPerformance(values, labels); if err != nil {
+    t.Fatal(err)
+  }
+  // each block of tied predictions defines a single threshold
+  r := Performance{
+    Tr: []float64{0.1, 0.5, 0.9},
+    Tp: []int{2, 1, 0},
+    Fp: []int{2, 0, 0},
+    Tn: []int{1, 3, 3},
+    Fn: []int{0, 1, 2},
+    P : 2,
+    N : 3 }
+  if !reflect.DeepEqual(perf, r) {
+    t.Errorf("invalid performance: %+v", perf)
+  }
+}
+
+func TestEvalPerformanceLabels(t *testing.T) {
+  if _, err := EvalPerformance([]float64{0.1, 0.2}, []int{0, 2}); err == nil {
+    t.Error("invalid label not rejected")
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+// ROC curve computed by brute force, where predictions with values greater
+// than or equal to the threshold are classified as positive. The curve
+// starts at (0,0) with threshold +Inf and ends at (1,1).
+func bruteForceRoc(values []float64, labels []int) ([]float64, []float64) {
+  tr := append([]float64{math.Inf(1)}, values...)
+  sort.Sort(sort.Reverse(sort.Float64Slice(tr)))
+  x  := []float64{}
+  y  := []float64{}
+  for i, t := range tr {
+    if i > 0 && t == tr[i-1] {
+      continue
+    }
+    tp, fp, p, n := 0, 0, 0, 0
+    for j := range values {
+      if labels[j] == 1 {
+        p++
+      } else {
+        n++
+      }
+      if values[j] >= t {
+        tp += labels[j]
+        fp += 1 - labels[j]
+      }
+    }
+    x = append(x, float64(fp)/float64(n))
+    y = append(y, float64(tp)/float64(p))
+  }
+  return x, y
+}
+
+func TestAUCRankSum(t *testing.T) {
+  for _, test := range []struct {
+    values []float64
+    labels []int
+  }{
+    // unsorted predictions without ties
+    {[]float64{0.3, 0.9, 0.1, 0.7, 0.4, 0.8, 0.2, 0.6}, []int{1, 1, 0, 0, 0, 1, 0, 1}},
+    // ties within and between classes
+    {[]float64{0.5, 0.1, 0.5, 0.9, 0.5, 0.1, 0.9}, []int{1, 0, 0, 1, 0, 1, 0}},
+    // all predictions tied
+    {[]float64{0.5, 0.5, 0.5, 0.5}, []int{1, 0, 0, 1}},
+  } {
+    values := append([]float64{}, test.values...)
+    r, err := AUCRankSum(values, test.labels); if err != nil {
+      t.Fatal(err)
+    }
+    if !reflect.DeepEqual(values, test.values) {
+      t.Errorf("predictions modified: %v", values)
+    }
+    x, y := bruteForceRoc(test.values, test.labels)
+    if auc, err := AUC(x, y); err != nil {
+      t.Fatal(err)
+    } else if math.Abs(auc - r) > 1e-12 {
+      t.Errorf("AUC %f does not match rank sum %f", auc, r)
+    }
+    // ranksum method of the roc-auc target
+    metrics := NewMetrics(Performance{}, false)
+    metrics.Values = test.values
+    metrics.Labels = test.labels
+    metrics.Method = "ranksum"
+    if auc, err := metrics.Eval("roc-auc"); err != nil {
+      t.Fatal(err)
+    } else if auc[0] != r {
+      t.Errorf("roc-auc %f does not match rank sum %f", auc[0], r)
+    }
+  }
+}
+
+func TestAUCRankSumInvalid(t *testing.T) {
+  if _, err := AUCRankSum([]float64{0.1, math.NaN(), 0.3}, []int{0, 1, 1}); err == nil {
+    t.Error("NaN prediction not rejected")
+  }
+  if _, err := AUCRankSum([]float64{0.1, 0.2}, []int{0, 2}); err == nil {
+    t.Error("invalid label not rejected")
+  }
+  if r, err := AUCRankSum([]float64{0.1, 0.2}, []int{1, 1}); err != nil || !math.IsNaN(r) {
+    t.Errorf("AUC of a single class is %f", r)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+func TestMerge(t *testing.T) {
+  values1 := []float64{0.1, 0.5, 0.9, 0.3}
+  labels1 := []int{0, 1, 1, 0}
+  values2 := []float64{0.5, 0.2, 0.7}
+  labels2 := []int{0, 0, 1}
+  a, err := EvalPerformance(append([]float64{}, values1...), append([]int{}, labels1...)); if err != nil {
+    t.Fatal(err)
+  }
+  b, err := EvalPerformance(append([]float64{}, values2...), append([]int{}, labels2...)); if err != nil {
+    t.Fatal(err)
+  }
+  // merging is equivalent to evaluating the union of both sets, including
+  // thresholds shared by both sets
+  r, err := EvalPerformance(append(values1, values2...), append(labels1, labels2...)); if err != nil {
+    t.Fatal(err)
+  }
+  if m := Merge(a, b); !reflect.DeepEqual(m, r) {
+    t.Errorf("invalid merged performance: %+v", m)
+  }
+  if m := Merge(b, a); !reflect.DeepEqual(m, r) {
+    t.Errorf("invalid merged performance: %+v", m)
+  }
+  if m := Merge(a, Performance{}); !reflect.DeepEqual(m, a) {
+    t.Errorf("merging with an empty performance changed the result: %+v", m)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+func TestQuantileGrid(t *testing.T) {
+  values := []float64{0.1, 0.2, 0.2, 0.3, 0.5, 0.8}
+  for _, test := range []struct {
+    n    int
+    grid []float64
+  }{
+    {0                  , []float64{}},
+    {1                  , []float64{0.8}},
+    {3                  , []float64{0.1, 0.2, 0.8}},
+    {6                  , []float64{0.1, 0.2, 0.3, 0.5, 0.8}},
+    // grids with more thresholds than predictions are reduced
+    {7                  , []float64{0.1, 0.2, 0.3, 0.5, 0.8}},
+    {MaxQuantileGridSize, []float64{0.1, 0.2, 0.3, 0.5, 0.8}},
+  } {
+    grid, err := QuantileGrid(values, test.n); if err != nil {
+      t.Errorf("n = %d: %v", test.n, err)
+    } else
+    if !reflect.DeepEqual(grid, test.grid) {
+      t.Errorf("n = %d: invalid grid %v", test.n, grid)
+    }
+  }
+  if _, err := QuantileGrid(values, MaxQuantileGridSize+1); err == nil {
+    t.Errorf("grid size above MaxQuantileGridSize accepted")
+  }
+  if _, err := QuantileGrid(values, math.MaxInt); err == nil {
+    t.Errorf("grid size above MaxQuantileGridSize accepted")
+  }
+}
@@ -0,0 +1,107 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math/rand/v2"
+import   "runtime"
+import   "sync"
+
+/* -------------------------------------------------------------------------- */
+
+type evaluatorShard struct {
+  sync.Mutex
+  Evaluator
+  // keep locks of different shards on separate cache lines
+  _ [64]byte
+}
+
+// ConcurrentEvaluator is a thread-safe variant of the Evaluator. Predictions
+// are distributed among several independently locked shards, which are merged
+// when the performance is computed.
+type ConcurrentEvaluator struct {
+  shards []evaluatorShard
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Create a new evaluator with n shards. If n is zero, the number of shards
+// is set to GOMAXPROCS.
+func NewConcurrentEvaluator(n int) *ConcurrentEvaluator {
+  if n <= 0 {
+    n = runtime.GOMAXPROCS(0)
+  }
+  return &ConcurrentEvaluator{shards: make([]evaluatorShard, n)}
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Select a random shard. The global generator of math/rand/v2 has a state
+// per thread, so that no memory is written that is shared between threads.
+func (obj *ConcurrentEvaluator) shard() *evaluatorShard {
+  return &obj.shards[rand.Uint64N(uint64(len(obj.shards)))]
+}
+
+func (obj *ConcurrentEvaluator) Add(value float64, label int) {
+  s := obj.shard()
+  s.Lock()
+  s.Evaluator.Add(value, label)
+  s.Unlock()
+}
+
+func (obj *ConcurrentEvaluator) AddBatch(values []float64, labels []int) {
+  s := obj.shard()
+  s.Lock()
+  s.Evaluator.AddBatch(values, labels)
+  s.Unlock()
+}
+
+func (obj *ConcurrentEvaluator) Len() int {
+  n := 0
+  for i := range obj.shards {
+    s := &obj.shards[i]
+    s.Lock()
+    n += s.Evaluator.Len()
+    s.Unlock()
+  }
+  return n
+}
+
+func (obj *ConcurrentEvaluator) Reset() {
+  for i := range obj.shards {
+    s := &obj.shards[i]
+    s.Lock()
+    s.Evaluator.Reset()
+    s.Unlock()
+  }
+}
+
+func (obj *ConcurrentEvaluator) Performance() (Performance, error) {
+  r := Performance{}
+  for i := range obj.shards {
+    s := &obj.shards[i]
+    s.Lock()
+    perf, err := s.Evaluator.Performance()
+    s.Unlock()
+    if err != nil {
+      return Performance{}, err
+    }
+    r = Merge(r, perf)
+  }
+  return r, nil
+}
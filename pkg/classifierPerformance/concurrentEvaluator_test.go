@@ -0,0 +1,69 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math/rand"
+import   "reflect"
+import   "sync"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestConcurrentEvaluator(t *testing.T) {
+  rng    := rand.New(rand.NewSource(1))
+  values := make([]float64, 10000)
+  labels := make([]int, 10000)
+  for i := range values {
+    labels[i] = rng.Intn(2)
+    values[i] = float64(rng.Intn(100) + 20*labels[i])/100.0
+  }
+  r, err := EvalPerformance(append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    t.Fatal(err)
+  }
+  evaluator := NewConcurrentEvaluator(4)
+  wg := sync.WaitGroup{}
+  for k := 0; k < 8; k++ {
+    wg.Add(1)
+    go func(k int) {
+      defer wg.Done()
+      for i := k; i < len(values); i += 8 {
+        if i % 16 < 8 {
+          evaluator.Add(values[i], labels[i])
+        } else {
+          evaluator.AddBatch(values[i:i+1], labels[i:i+1])
+        }
+      }
+    }(k)
+  }
+  wg.Wait()
+  if n := evaluator.Len(); n != len(values) {
+    t.Errorf("expected %d predictions but found %d", len(values), n)
+  }
+  perf, err := evaluator.Performance(); if err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(perf, r) {
+    t.Error("performance differs from the performance of all predictions")
+  }
+  evaluator.Reset()
+  if n := evaluator.Len(); n != 0 {
+    t.Errorf("%d predictions left after reset", n)
+  }
+}
@@ -0,0 +1,275 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+import   "strconv"
+import   "strings"
+
+/* -------------------------------------------------------------------------- */
+
+// ConfusionMatrix holds the number of true positives, false positives, true
+// negatives and false negatives at a single threshold.
+type ConfusionMatrix struct {
+  Tp, Fp, Tn, Fn int
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Confusion matrix at the i-th threshold.
+func (obj Performance) ConfusionMatrix(i int) ConfusionMatrix {
+  return ConfusionMatrix{Tp: obj.Tp[i], Fp: obj.Fp[i], Tn: obj.Tn[i], Fn: obj.Fn[i]}
+}
+
+// Confusion matrix at an arbitrary threshold t. Predictions with values
+// strictly larger than t are classified as positive, all others as negative.
+// This is the same convention used for the thresholds in Tr.
+func (obj Performance) At(t float64) ConfusionMatrix {
+  // first threshold larger than t
+  k := sort.Search(obj.Len(), func(i int) bool { return obj.Tr[i] > t })
+  if k == 0 {
+    // all predictions are larger than t
+    return ConfusionMatrix{Tp: obj.P, Fp: obj.N}
+  }
+  return obj.ConfusionMatrix(k-1)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// True positive rate (sensitivity, recall).
+func (obj ConfusionMatrix) TPR() float64 {
+  return float64(obj.Tp)/float64(obj.Tp + obj.Fn)
+}
+
+// False positive rate (1 - specificity).
+func (obj ConfusionMatrix) FPR() float64 {
+  return float64(obj.Fp)/float64(obj.Fp + obj.Tn)
+}
+
+// Fraction of positive predictions among all positive predictions that are
+// correct.
+func (obj ConfusionMatrix) Precision() float64 {
+  return float64(obj.Tp)/float64(obj.Tp + obj.Fp)
+}
+
+// True negative rate (specificity).
+func (obj ConfusionMatrix) TNR() float64 {
+  return float64(obj.Tn)/float64(obj.Tn + obj.Fp)
+}
+
+// Fraction of negative predictions that are correct (negative predictive
+// value).
+func (obj ConfusionMatrix) NPV() float64 {
+  return float64(obj.Tn)/float64(obj.Tn + obj.Fn)
+}
+
+// Informedness (Youden's J statistic), i.e. TPR + TNR - 1.
+func (obj ConfusionMatrix) Informedness() float64 {
+  return obj.TPR() + obj.TNR() - 1.0
+}
+
+// Markedness, i.e. PPV + NPV - 1.
+func (obj ConfusionMatrix) Markedness() float64 {
+  return obj.Precision() + obj.NPV() - 1.0
+}
+
+// Geometric mean of sensitivity and specificity.
+func (obj ConfusionMatrix) GMean() float64 {
+  return math.Sqrt(obj.TPR()*obj.TNR())
+}
+
+// Fowlkes-Mallows index, i.e. the geometric mean of precision and recall.
+func (obj ConfusionMatrix) FowlkesMallows() float64 {
+  return math.Sqrt(obj.Precision()*obj.TPR())
+}
+
+// Prevalence threshold, i.e. the prevalence below which the positive
+// predictive value declines most rapidly, computed as (sqrt(TPR*FPR) -
+// FPR)/(TPR - FPR).
+func (obj ConfusionMatrix) PrevalenceThreshold() float64 {
+  tpr, fpr := obj.TPR(), obj.FPR()
+  return (math.Sqrt(tpr*fpr) - fpr)/(tpr - fpr)
+}
+
+// Fraction of correct predictions.
+func (obj ConfusionMatrix) Accuracy() float64 {
+  return float64(obj.Tp + obj.Tn)/float64(obj.Tp + obj.Fp + obj.Tn + obj.Fn)
+}
+
+// Fraction of predictions that are classified as positive.
+func (obj ConfusionMatrix) PositiveRate() float64 {
+  return float64(obj.Tp + obj.Fp)/float64(obj.Tp + obj.Fp + obj.Tn + obj.Fn)
+}
+
+/* -------------------------------------------------------------------------- */
+
+type confusionMeasure struct {
+  f     func(ConfusionMatrix) float64
+  // smaller values are better
+  lower bool
+}
+
+// Measures that can be used in operating points such as precision@recall(0.9)
+var confusionMeasures = map[string]confusionMeasure{
+  "accuracy"   : {ConfusionMatrix.Accuracy , false},
+  "fpr"        : {ConfusionMatrix.FPR      , true },
+  "precision"  : {ConfusionMatrix.Precision, false},
+  "recall"     : {ConfusionMatrix.TPR      , false},
+  "sensitivity": {ConfusionMatrix.TPR      , false},
+  "specificity": {func(c ConfusionMatrix) float64 { return 1.0 - c.FPR() }, false},
+  "tnr"        : {func(c ConfusionMatrix) float64 { return 1.0 - c.FPR() }, false},
+  "tpr"        : {ConfusionMatrix.TPR      , false},
+}
+
+// Parse an operating point of the form measure@constraint(x), e.g.
+// precision@recall(0.9) or tpr@fpr(0.01).
+func ParseOperatingPoint(s string) (string, string, float64, error) {
+  measure, rest, ok := strings.Cut(s, "@")
+  if !ok || !strings.HasSuffix(rest, ")") {
+    return "", "", 0, fmt.Errorf("invalid operating point `%s'", s)
+  }
+  constraint, arg, ok := strings.Cut(strings.TrimSuffix(rest, ")"), "(")
+  if !ok {
+    return "", "", 0, fmt.Errorf("invalid operating point `%s'", s)
+  }
+  for _, name := range []string{measure, constraint} {
+    if _, ok := confusionMeasures[name]; !ok {
+      return "", "", 0, fmt.Errorf("invalid operating point `%s': unknown measure `%s'", s, name)
+    }
+  }
+  x, err := strconv.ParseFloat(strings.TrimSpace(arg), 64); if err != nil {
+    return "", "", 0, fmt.Errorf("invalid operating point `%s': %v", s, err)
+  }
+  return measure, constraint, x, nil
+}
+
+// Best value of a measure among all thresholds where the constraint measure
+// is at least x, or at most x if smaller values of the constraint are better
+// (e.g. fpr). Returns NaN if no threshold satisfies the constraint.
+func (obj Performance) OperatingPoint(measure, constraint string, x float64) (float64, error) {
+  m, ok := confusionMeasures[measure]; if !ok {
+    return math.NaN(), fmt.Errorf("unknown measure `%s'", measure)
+  }
+  c, ok := confusionMeasures[constraint]; if !ok {
+    return math.NaN(), fmt.Errorf("unknown measure `%s'", constraint)
+  }
+  r := math.NaN()
+  // the first confusion matrix classifies all predictions as positive
+  for i := -1; i < obj.Len(); i++ {
+    cm := ConfusionMatrix{Tp: obj.P, Fp: obj.N}
+    if i >= 0 {
+      cm = obj.ConfusionMatrix(i)
+    }
+    if y := c.f(cm); math.IsNaN(y) || (c.lower && y > x) || (!c.lower && y < x) {
+      continue
+    }
+    if y := m.f(cm); math.IsNaN(r) || (m.lower && y < r) || (!m.lower && y > r) {
+      r = y
+    }
+  }
+  return r, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Evaluate the confusion matrix at threshold t directly from predictions,
+// without sorting. Predictions with values strictly larger than t are
+// classified as positive.
+func EvalConfusionMatrix[T Float](values []T, labels []int, t T) (ConfusionMatrix, error) {
+  if len(values) != len(labels) {
+    return ConfusionMatrix{}, fmt.Errorf("number of predictions and labels do not match")
+  }
+  r := ConfusionMatrix{}
+  for i := range values {
+    switch {
+    case labels[i] == 1 && values[i] >  t: r.Tp++
+    case labels[i] == 1 && values[i] <= t: r.Fn++
+    case labels[i] == 0 && values[i] >  t: r.Fp++
+    case labels[i] == 0 && values[i] <= t: r.Tn++
+    default:
+      return ConfusionMatrix{}, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  return r, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Expected number of true positives among the k predictions with the largest
+// values. Ties are broken at random, i.e. the number of true positives is
+// interpolated linearly within blocks of tied predictions.
+func (obj Performance) TopK(k float64) float64 {
+  // number of predicted positives and true positives, starting with all
+  // predictions classified as positive
+  n0, tp0 := float64(obj.P + obj.N), float64(obj.P)
+  if k >= n0 {
+    return tp0
+  }
+  for i := 0; i < obj.Len(); i++ {
+    n1, tp1 := float64(obj.Tp[i] + obj.Fp[i]), float64(obj.Tp[i])
+    if n1 <= k {
+      return tp1 + (k - n1)*(tp0 - tp1)/(n0 - n1)
+    }
+    n0, tp0 = n1, tp1
+  }
+  return 0.0
+}
+
+// Precision and recall among the k top ranked predictions for each of the
+// given cutoffs, which must be sorted in increasing order. If no cutoffs are
+// given, all cutoffs k = 1, ..., P+N are used. As for TopK, the number of
+// true positives is interpolated linearly within blocks of tied predictions.
+func PrecisionRecallAtK(perf Performance, cutoffs []int) Table {
+  n := perf.P + perf.N
+  if cutoffs == nil {
+    cutoffs = make([]int, n)
+    for i := range cutoffs {
+      cutoffs[i] = i+1
+    }
+  }
+  r := Table{Names: []string{"k", "precision", "recall"}, Columns: make([][]float64, 3)}
+  // blocks of tied predictions from the top, where (n0, tp0) are the numbers
+  // of predicted positives and true positives above the current block
+  n0, tp0 := 0.0, 0.0
+  i := perf.Len()-1
+  for _, k := range cutoffs {
+    x := float64(min(k, n))
+    for {
+      n1, tp1 := float64(n), float64(perf.P)
+      if i >= 0 {
+        n1, tp1 = float64(perf.Tp[i] + perf.Fp[i]), float64(perf.Tp[i])
+      }
+      if n1 >= x || i < 0 {
+        tp := tp0
+        if n1 > n0 {
+          tp += (x - n0)*(tp1 - tp0)/(n1 - n0)
+        }
+        r.Columns[0] = append(r.Columns[0], float64(k))
+        r.Columns[1] = append(r.Columns[1], tp/x)
+        r.Columns[2] = append(r.Columns[2], tp/float64(perf.P))
+        break
+      }
+      n0, tp0 = n1, tp1
+      i--
+    }
+  }
+  return r
+}
@@ -0,0 +1,56 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestPerformanceAt(t *testing.T) {
+  values := []float64{0.1, 0.5, 0.9, 0.3, 0.5}
+  labels := []int{0, 1, 1, 0, 0}
+  perf, err := EvalPerformance(append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    t.Fatal(err)
+  }
+  // compare with counts at thresholds below, at, between and above the
+  // predictions, where predictions larger than t are positive
+  for _, v := range []float64{math.Inf(-1), 0.0, 0.1, 0.2, 0.5, 0.7, 0.9, 1.0, math.Inf(1)} {
+    r := ConfusionMatrix{}
+    for i := range values {
+      switch {
+      case values[i] >  v && labels[i] == 1: r.Tp++
+      case values[i] >  v && labels[i] == 0: r.Fp++
+      case values[i] <= v && labels[i] == 0: r.Tn++
+      default: r.Fn++
+      }
+    }
+    if c := perf.At(v); c != r {
+      t.Errorf("invalid confusion matrix at %v: %+v", v, c)
+    }
+  }
+}
+
+func TestConfusionMatrix(t *testing.T) {
+  c := ConfusionMatrix{Tp: 3, Fp: 1, Tn: 4, Fn: 2}
+  if c.TPR() != 0.6 || c.FPR() != 0.2 || c.Precision() != 0.75 || c.TNR() != 0.8 {
+    t.Errorf("invalid rates: %v %v %v %v", c.TPR(), c.FPR(), c.Precision(), c.TNR())
+  }
+}
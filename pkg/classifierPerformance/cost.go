@@ -0,0 +1,112 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "strconv"
+import   "strings"
+
+/* -------------------------------------------------------------------------- */
+
+// CostMatrix holds the costs of true positives, false positives, false
+// negatives and true negatives.
+type CostMatrix struct {
+  Tp, Fp, Fn, Tn float64
+}
+
+// Cost matrix of the misclassification rate.
+var DefaultCostMatrix = CostMatrix{Tp: 0.0, Fp: 1.0, Fn: 1.0, Tn: 0.0}
+
+// Parse a cost matrix given as comma separated list `tp,fp,fn,tn'.
+func ParseCostMatrix(s string) (CostMatrix, error) {
+  fields := strings.Split(s, ",")
+  if len(fields) != 4 {
+    return CostMatrix{}, fmt.Errorf("invalid cost matrix `%s'", s)
+  }
+  c := [4]float64{}
+  for i := range fields {
+    v, err := strconv.ParseFloat(strings.TrimSpace(fields[i]), 64); if err != nil {
+      return CostMatrix{}, fmt.Errorf("invalid cost matrix `%s'", s)
+    }
+    c[i] = v
+  }
+  return CostMatrix{Tp: c[0], Fp: c[1], Fn: c[2], Tn: c[3]}, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Expected cost per prediction at each threshold. If prevalence is in (0,1),
+// the expected cost is computed for a population with the given fraction of
+// positives, otherwise the prevalence of the data is used. The table
+// contains false and true positive rates, the expected cost and the
+// threshold, starting with all predictions classified as positive.
+func ExpectedCost(perf Performance, costs CostMatrix, prevalence float64) Table {
+  if prevalence <= 0.0 || prevalence >= 1.0 {
+    prevalence = float64(perf.P)/float64(perf.P + perf.N)
+  }
+  r := Table{Names: []string{"fpr", "tpr", "cost", "threshold"}, Columns: make([][]float64, 4)}
+  add := func(tpr, fpr, t float64) {
+    cost := prevalence*(tpr*costs.Tp + (1.0 - tpr)*costs.Fn) + (1.0 - prevalence)*(fpr*costs.Fp + (1.0 - fpr)*costs.Tn)
+    r.Columns[0] = append(r.Columns[0], fpr)
+    r.Columns[1] = append(r.Columns[1], tpr)
+    r.Columns[2] = append(r.Columns[2], cost)
+    r.Columns[3] = append(r.Columns[3], t)
+  }
+  add(1.0, 1.0, math.Inf(-1))
+  for i := 0; i < perf.Len(); i++ {
+    add(float64(perf.Tp[i])/float64(perf.P), float64(perf.Fp[i])/float64(perf.N), perf.Tr[i])
+  }
+  return r
+}
+
+// Expected cost tables for several assumed prevalences, which are stacked
+// with the prevalence in the first column.
+func ExpectedCostPrevalences(perf Performance, costs CostMatrix, prevalences []float64) Table {
+  r := Table{Names: []string{"prevalence"}}
+  for _, prevalence := range prevalences {
+    t := ExpectedCost(perf, costs, prevalence)
+    if r.Columns == nil {
+      r.Names   = append(r.Names, t.Names...)
+      r.Columns = make([][]float64, len(r.Names))
+    }
+    for i := 0; i < t.Rows(); i++ {
+      r.Columns[0] = append(r.Columns[0], prevalence)
+    }
+    for j := range t.Columns {
+      r.Columns[j+1] = append(r.Columns[j+1], t.Columns[j]...)
+    }
+  }
+  return r
+}
+
+// Row of the expected cost table with minimal cost.
+func OptimalCost(table Table) Table {
+  k := 0
+  for i := 1; i < table.Rows(); i++ {
+    if table.Columns[2][i] < table.Columns[2][k] {
+      k = i
+    }
+  }
+  r := Table{Names: table.Names, Columns: make([][]float64, len(table.Columns))}
+  for j := range table.Columns {
+    r.Columns[j] = []float64{table.Columns[j][k]}
+  }
+  return r
+}
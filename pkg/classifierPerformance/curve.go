@@ -0,0 +1,140 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Curve is a sequence of points (X[i], Y[i]) computed at thresholds Tr[i].
+// Thresholds are nil for curves that do not correspond to a threshold, e.g.
+// after interpolation.
+type Curve struct {
+  X  []float64
+  Y  []float64
+  Tr []float64
+}
+
+/* -------------------------------------------------------------------------- */
+
+func (obj Curve) Len() int {
+  return len(obj.X)
+}
+
+func (obj Curve) AUC() (float64, error) {
+  return AUC(obj.X, obj.Y)
+}
+
+// Points of the curve sorted by x. Points with equal x are sorted by y.
+func (obj Curve) sorted() ([]float64, []float64) {
+  x := make([]float64, obj.Len())
+  y := make([]float64, obj.Len())
+  copy(x, obj.X)
+  copy(y, obj.Y)
+  sort.Sort(curvePoints{x, y})
+  return x, y
+}
+
+func curveAt(x, y []float64, v float64) float64 {
+  if len(x) == 0 {
+    return math.NaN()
+  }
+  // first point with x larger than v
+  k := sort.Search(len(x), func(i int) bool { return x[i] > v })
+  switch {
+  case k == 0:
+    return y[0]
+  case x[k-1] == v || k == len(x):
+    return y[k-1]
+  default:
+    return y[k-1] + (y[k] - y[k-1])*(v - x[k-1])/(x[k] - x[k-1])
+  }
+}
+
+// Evaluate the curve at position x using linear interpolation. If the curve
+// has several points at x (i.e. a vertical segment), the largest y value is
+// returned. Outside the range of the curve, the y value of the closest end
+// point is returned.
+func (obj Curve) At(x float64) float64 {
+  xs, ys := obj.sorted()
+  return curveAt(xs, ys, x)
+}
+
+// Evaluate the curve at all positions in grid.
+func (obj Curve) Interpolate(grid []float64) Curve {
+  xs, ys := obj.sorted()
+  r := Curve{}
+  r.X = make([]float64, len(grid))
+  r.Y = make([]float64, len(grid))
+  for i, x := range grid {
+    r.X[i] = x
+    r.Y[i] = curveAt(xs, ys, x)
+  }
+  return r
+}
+
+// Reduce the curve to n points that are equally spaced in index. The first
+// and last points are always retained, so that n = 1 is treated as n = 2.
+// The curve is returned unchanged if n is not positive.
+func (obj Curve) Downsample(n int) Curve {
+  if n == 1 {
+    n = 2
+  }
+  if n >= obj.Len() || n <= 0 {
+    return obj
+  }
+  r := Curve{}
+  r.X = make([]float64, n)
+  r.Y = make([]float64, n)
+  if obj.Tr != nil {
+    r.Tr = make([]float64, n)
+  }
+  for i := 0; i < n; i++ {
+    k := i*(obj.Len()-1)/(n-1)
+    r.X[i] = obj.X[k]
+    r.Y[i] = obj.Y[k]
+    if obj.Tr != nil {
+      r.Tr[i] = obj.Tr[k]
+    }
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+type curvePoints struct {
+  x, y []float64
+}
+
+func (obj curvePoints) Len() int {
+  return len(obj.x)
+}
+
+func (obj curvePoints) Swap(i, j int) {
+  obj.x[i], obj.x[j] = obj.x[j], obj.x[i]
+  obj.y[i], obj.y[j] = obj.y[j], obj.y[i]
+}
+
+func (obj curvePoints) Less(i, j int) bool {
+  if obj.x[i] == obj.x[j] {
+    return obj.y[i] < obj.y[j]
+  }
+  return obj.x[i] < obj.x[j]
+}
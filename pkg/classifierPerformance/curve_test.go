@@ -0,0 +1,66 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "reflect"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestCurveAUC(t *testing.T) {
+  curve := Curve{X: []float64{0.0, 0.5, 0.5, 1.0}, Y: []float64{0.0, 0.5, 1.0, 1.0}}
+  if r, err := curve.AUC(); err != nil || r != 0.625 {
+    t.Errorf("invalid area: %v (%v)", r, err)
+  }
+}
+
+func TestCurveAt(t *testing.T) {
+  // unsorted curve with a vertical segment at x = 0.5
+  curve := Curve{X: []float64{1.0, 0.5, 0.0, 0.5}, Y: []float64{1.0, 0.8, 0.0, 0.4}}
+  for _, test := range [][2]float64{{-1.0, 0.0}, {0.0, 0.0}, {0.25, 0.2}, {0.5, 0.8}, {0.75, 0.9}, {1.0, 1.0}, {2.0, 1.0}} {
+    if r := curve.At(test[0]); math.Abs(r - test[1]) > 1e-12 {
+      t.Errorf("curve at %v is %v but expected %v", test[0], r, test[1])
+    }
+  }
+  if r := (Curve{}).At(0.5); !math.IsNaN(r) {
+    t.Errorf("empty curve at 0.5 is %v", r)
+  }
+  r := curve.Interpolate([]float64{0.25, 0.75})
+  if !reflect.DeepEqual(r.X, []float64{0.25, 0.75}) || math.Abs(r.Y[0] - 0.2) > 1e-12 || math.Abs(r.Y[1] - 0.9) > 1e-12 || r.Tr != nil {
+    t.Errorf("invalid interpolation: %+v", r)
+  }
+}
+
+func TestCurveDownsample(t *testing.T) {
+  curve := Curve{X: []float64{0, 1, 2, 3, 4}, Y: []float64{5, 6, 7, 8, 9}, Tr: []float64{4, 3, 2, 1, 0}}
+  if r := curve.Downsample(3); !reflect.DeepEqual(r, Curve{X: []float64{0, 2, 4}, Y: []float64{5, 7, 9}, Tr: []float64{4, 2, 0}}) {
+    t.Errorf("invalid curve: %+v", r)
+  }
+  // first and last points are always retained
+  if r := curve.Downsample(1); !reflect.DeepEqual(r.X, []float64{0, 4}) {
+    t.Errorf("invalid curve: %+v", r)
+  }
+  for _, n := range []int{0, 5, 10} {
+    if r := curve.Downsample(n); !reflect.DeepEqual(r, curve) {
+      t.Errorf("curve changed by downsampling to %d points: %+v", n, r)
+    }
+  }
+}
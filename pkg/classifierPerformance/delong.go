@@ -0,0 +1,216 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Compute placement values (structural components) of the area under the
+// ROC curve. For a positive prediction, the placement value is the fraction
+// of negative predictions with smaller values, for a negative prediction the
+// fraction of positive predictions with larger values. Ties count 1/2. The
+// result is aligned with the input. Placement values are undefined if one of
+// the classes is empty.
+func AUCPlacements[T Float](values []T, labels []int) ([]float64, error) {
+  if len(values) != len(labels) {
+    return nil, fmt.Errorf("number of predictions and labels do not match")
+  }
+  pos := []float64{}
+  neg := []float64{}
+  for i := range values {
+    switch labels[i] {
+    case 1: pos = append(pos, float64(values[i]))
+    case 0: neg = append(neg, float64(values[i]))
+    default:
+      return nil, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  sort.Float64s(pos)
+  sort.Float64s(neg)
+  // number of elements in x smaller than and equal to v
+  count := func(x []float64, v float64) (int, int) {
+    i := sort.SearchFloat64s(x, v)
+    j := sort.Search(len(x), func(k int) bool { return x[k] > v })
+    return i, j-i
+  }
+  r := make([]float64, len(values))
+  for i := range values {
+    v := float64(values[i])
+    if labels[i] == 1 {
+      n_less, n_equal := count(neg, v)
+      r[i] = (float64(n_less) + 0.5*float64(n_equal))/float64(len(neg))
+    } else {
+      n_less, n_equal := count(pos, v)
+      r[i] = (float64(len(pos) - n_less - n_equal) + 0.5*float64(n_equal))/float64(len(pos))
+    }
+  }
+  return r, nil
+}
+
+// Variance of a sum of n i.i.d. terms with mean zero, estimated from the
+// observed terms
+func delongVariance(c []float64) float64 {
+  if len(c) < 2 {
+    return math.NaN()
+  }
+  r := 0.0
+  for _, v := range c {
+    r += v*v
+  }
+  return r*float64(len(c))/float64(len(c)-1)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Area under the ROC curve and its variance estimated with the method of
+// DeLong et al. (1988).
+func DeLong[T Float](values []T, labels []int) (float64, float64, error) {
+  return SubgroupAUCDifference(values, labels, nil)
+}
+
+// Difference between the area under the ROC curve of a subgroup and the
+// area under the ROC curve of all predictions, together with its variance
+// estimated with the method of DeLong et al. (1988). Since the subgroup is
+// part of the full data, both estimates are correlated, which is accounted
+// for by expanding both statistics in terms of placement values. If subgroup
+// is nil, the area under the ROC curve of all predictions and its variance
+// are returned instead.
+func SubgroupAUCDifference[T Float](values []T, labels []int, subgroup []bool) (float64, float64, error) {
+  if subgroup != nil && len(subgroup) != len(values) {
+    return 0.0, 0.0, fmt.Errorf("number of predictions and subgroup indicators do not match")
+  }
+  p_all, err := AUCPlacements(values, labels); if err != nil {
+    return 0.0, 0.0, err
+  }
+  // placement values within the subgroup, aligned with the full data
+  p_sub := make([]float64, len(values))
+  if subgroup != nil {
+    v := []T{}
+    l := []int{}
+    for i := range values {
+      if subgroup[i] {
+        v = append(v, values[i])
+        l = append(l, labels[i])
+      }
+    }
+    p, err := AUCPlacements(v, l); if err != nil {
+      return 0.0, 0.0, err
+    }
+    for i, k := 0, 0; i < len(values); i++ {
+      if subgroup[i] {
+        p_sub[i] = p[k]; k++
+      }
+    }
+  }
+  // class sizes and AUCs
+  n_all, m_all := 0, 0
+  n_sub, m_sub := 0, 0
+  auc_all, auc_sub := 0.0, 0.0
+  for i := range values {
+    in := subgroup != nil && subgroup[i]
+    if labels[i] == 1 {
+      m_all++; auc_all += p_all[i]
+      if in {
+        m_sub++; auc_sub += p_sub[i]
+      }
+    } else {
+      n_all++
+      if in {
+        n_sub++
+      }
+    }
+  }
+  if m_all == 0 || n_all == 0 || (subgroup != nil && (m_sub == 0 || n_sub == 0)) {
+    return math.NaN(), math.NaN(), nil
+  }
+  auc_all /= float64(m_all)
+  if m_sub > 0 {
+    auc_sub /= float64(m_sub)
+  }
+  // contributions of positive and negative predictions
+  c_pos := make([]float64, 0, m_all)
+  c_neg := make([]float64, 0, n_all)
+  for i := range values {
+    in := subgroup != nil && subgroup[i]
+    if labels[i] == 1 {
+      c := (p_all[i] - auc_all)/float64(m_all)
+      if in {
+        c -= (p_sub[i] - auc_sub)/float64(m_sub)
+      }
+      c_pos = append(c_pos, c)
+    } else {
+      c := (p_all[i] - auc_all)/float64(n_all)
+      if in {
+        c -= (p_sub[i] - auc_sub)/float64(n_sub)
+      }
+      c_neg = append(c_neg, c)
+    }
+  }
+  variance := delongVariance(c_pos) + delongVariance(c_neg)
+  if subgroup == nil {
+    return auc_all, variance, nil
+  }
+  return auc_sub - auc_all, variance, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Difference between the areas under the ROC curves of two classifiers
+// evaluated on the same predictions (AUC of values2 minus AUC of values1),
+// together with its variance estimated with the method of DeLong et al.
+// (1988), which accounts for the correlation of both estimates.
+func DeLongPaired[T Float](values1, values2 []T, labels []int) (float64, float64, error) {
+  if len(values1) != len(values2) {
+    return 0.0, 0.0, fmt.Errorf("number of predictions of both classifiers do not match")
+  }
+  p1, err := AUCPlacements(values1, labels); if err != nil {
+    return 0.0, 0.0, err
+  }
+  p2, err := AUCPlacements(values2, labels); if err != nil {
+    return 0.0, 0.0, err
+  }
+  m, n := 0, 0
+  auc1, auc2 := 0.0, 0.0
+  for i := range labels {
+    if labels[i] == 1 {
+      m++; auc1 += p1[i]; auc2 += p2[i]
+    } else {
+      n++
+    }
+  }
+  if m == 0 || n == 0 {
+    return math.NaN(), math.NaN(), nil
+  }
+  auc1 /= float64(m)
+  auc2 /= float64(m)
+  c_pos := make([]float64, 0, m)
+  c_neg := make([]float64, 0, n)
+  for i := range labels {
+    d := (p2[i] - auc2) - (p1[i] - auc1)
+    if labels[i] == 1 {
+      c_pos = append(c_pos, d/float64(m))
+    } else {
+      c_neg = append(c_neg, d/float64(n))
+    }
+  }
+  return auc2 - auc1, delongVariance(c_pos) + delongVariance(c_neg), nil
+}
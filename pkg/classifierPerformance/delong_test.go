@@ -0,0 +1,138 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "math/rand"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+// Variance of the area under the ROC curve following the definitions of
+// DeLong et al. (1988), i.e. S10/m + S01/n computed from the kernel of the
+// Mann-Whitney statistic
+func bruteForceDeLong(values []float64, labels []int) (float64, float64) {
+  psi := func(x, y float64) float64 {
+    switch {
+    case x > y : return 1.0
+    case x == y: return 0.5
+    default    : return 0.0
+    }
+  }
+  pos, neg := []float64{}, []float64{}
+  for i := range values {
+    if labels[i] == 1 {
+      pos = append(pos, values[i])
+    } else {
+      neg = append(neg, values[i])
+    }
+  }
+  m, n := float64(len(pos)), float64(len(neg))
+  v10  := make([]float64, len(pos))
+  v01  := make([]float64, len(neg))
+  auc  := 0.0
+  for i := range pos {
+    for j := range neg {
+      v10[i] += psi(pos[i], neg[j])/n
+      v01[j] += psi(pos[i], neg[j])/m
+      auc    += psi(pos[i], neg[j])/(m*n)
+    }
+  }
+  s10, s01 := 0.0, 0.0
+  for _, v := range v10 {
+    s10 += (v - auc)*(v - auc)/(m - 1)
+  }
+  for _, v := range v01 {
+    s01 += (v - auc)*(v - auc)/(n - 1)
+  }
+  return auc, s10/m + s01/n
+}
+
+func TestDeLong(t *testing.T) {
+  // placement values of positives are 1 and 1/2, those of negatives 1/2
+  // and 1, so that S10 = S01 = 1/8
+  auc, variance, err := DeLong([]float64{0.8, 0.6, 0.4, 0.2}, []int{1, 0, 1, 0}); if err != nil {
+    t.Fatal(err)
+  }
+  if auc != 0.75 || variance != 0.125 {
+    t.Errorf("invalid result: auc=%v, variance=%v", auc, variance)
+  }
+}
+
+func TestDeLongTies(t *testing.T) {
+  rng    := rand.New(rand.NewSource(1))
+  values := make([]float64, 200)
+  labels := make([]int, 200)
+  for i := range values {
+    labels[i] = rng.Intn(2)
+    // rounding produces ties within and between classes
+    values[i] = math.Round(10.0*(rng.NormFloat64() + float64(labels[i])))/10.0
+  }
+  auc, variance, err := DeLong(values, labels); if err != nil {
+    t.Fatal(err)
+  }
+  r_auc, r_variance := bruteForceDeLong(values, labels)
+  if math.Abs(auc - r_auc) > 1e-12 || math.Abs(variance - r_variance) > 1e-12 {
+    t.Errorf("invalid result: auc=%v (%v), variance=%v (%v)", auc, r_auc, variance, r_variance)
+  }
+  if r, err := AUCRankSum(values, labels); err != nil || math.Abs(auc - r) > 1e-12 {
+    t.Errorf("auc %v does not match rank sum %v", auc, r)
+  }
+}
+
+func TestDeLongPaired(t *testing.T) {
+  values1 := []float64{0.8, 0.6, 0.4, 0.2, 0.7, 0.1}
+  values2 := []float64{0.9, 0.3, 0.6, 0.2, 0.4, 0.5}
+  labels  := []int{1, 0, 1, 0, 1, 0}
+  // identical classifiers do not differ
+  if d, v, err := DeLongPaired(values1, values1, labels); err != nil || d != 0.0 || v != 0.0 {
+    t.Errorf("identical classifiers differ: %v (%v)", d, v)
+  }
+  d1, v1, err := DeLongPaired(values1, values2, labels); if err != nil {
+    t.Fatal(err)
+  }
+  d2, v2, err := DeLongPaired(values2, values1, labels); if err != nil {
+    t.Fatal(err)
+  }
+  if d1 != -d2 || math.Abs(v1 - v2) > 1e-15 {
+    t.Errorf("difference is not antisymmetric: %v (%v) and %v (%v)", d1, v1, d2, v2)
+  }
+  auc1, _, _ := DeLong(values1, labels)
+  auc2, _, _ := DeLong(values2, labels)
+  if math.Abs(d1 - (auc2 - auc1)) > 1e-15 {
+    t.Errorf("invalid difference: %v", d1)
+  }
+}
+
+func TestSubgroupAUCDifference(t *testing.T) {
+  values   := []float64{0.8, 0.6, 0.4, 0.2, 0.7, 0.1}
+  labels   := []int{1, 0, 1, 0, 1, 0}
+  // the full data as subgroup does not differ
+  if d, v, err := SubgroupAUCDifference(values, labels, []bool{true, true, true, true, true, true}); err != nil || d != 0.0 || v != 0.0 {
+    t.Errorf("full data differs from itself: %v (%v)", d, v)
+  }
+  // subgroups with a single class are undefined
+  if d, _, err := SubgroupAUCDifference(values, labels, []bool{true, false, true, false, false, false}); err != nil || !math.IsNaN(d) {
+    t.Errorf("difference of a single class subgroup is %v", d)
+  }
+  if _, _, err := SubgroupAUCDifference(values, labels, []bool{true}); err == nil {
+    t.Error("invalid subgroup not rejected")
+  }
+}
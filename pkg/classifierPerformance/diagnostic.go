@@ -0,0 +1,162 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// DiagnosticMeasure is a measure of diagnostic accuracy with its confidence
+// interval. For proportions, Numerator and Denominator are the counts from
+// which the proportion is computed, for likelihood ratios both are zero.
+type DiagnosticMeasure struct {
+  Name        string
+  Numerator   int
+  Denominator int
+  Value       float64
+  Lower       float64
+  Upper       float64
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Continued fraction of the regularized incomplete beta function
+// (Numerical Recipes, 6.4)
+func betaContinuedFraction(a, b, x float64) float64 {
+  const eps  = 1e-15
+  const tiny = 1e-300
+  c := 1.0
+  d := 1.0 - (a + b)*x/(a + 1.0)
+  if math.Abs(d) < tiny {
+    d = tiny
+  }
+  d  = 1.0/d
+  r := d
+  for m := 1; m <= 1000; m++ {
+    fm := float64(m)
+    for _, n := range []float64{
+      fm*(b - fm)*x/((a + 2.0*fm - 1.0)*(a + 2.0*fm)),
+      -(a + fm)*(a + b + fm)*x/((a + 2.0*fm)*(a + 2.0*fm + 1.0)) } {
+      d = 1.0 + n*d
+      if math.Abs(d) < tiny {
+        d = tiny
+      }
+      c = 1.0 + n/c
+      if math.Abs(c) < tiny {
+        c = tiny
+      }
+      d  = 1.0/d
+      r *= d*c
+    }
+    if math.Abs(d*c - 1.0) < eps {
+      break
+    }
+  }
+  return r
+}
+
+// Regularized incomplete beta function I_x(a, b)
+func incompleteBeta(a, b, x float64) float64 {
+  switch {
+  case x <= 0.0:
+    return 0.0
+  case x >= 1.0:
+    return 1.0
+  }
+  la, _  := math.Lgamma(a)
+  lb, _  := math.Lgamma(b)
+  lab, _ := math.Lgamma(a + b)
+  f := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1.0 - x))
+  if x < (a + 1.0)/(a + b + 2.0) {
+    return f*betaContinuedFraction(a, b, x)/a
+  } else {
+    return 1.0 - f*betaContinuedFraction(b, a, 1.0 - x)/b
+  }
+}
+
+// Quantile of the beta distribution computed by bisection
+func betaQuantile(p, a, b float64) float64 {
+  lo, hi := 0.0, 1.0
+  for i := 0; i < 100; i++ {
+    m := 0.5*(lo + hi)
+    if incompleteBeta(a, b, m) < p {
+      lo = m
+    } else {
+      hi = m
+    }
+  }
+  return 0.5*(lo + hi)
+}
+
+// Exact (Clopper-Pearson) confidence interval of a binomial proportion x/n
+// at the given level (e.g. 0.95).
+func ClopperPearson(x, n int, level float64) (float64, float64) {
+  if n == 0 {
+    return math.NaN(), math.NaN()
+  }
+  alpha := 1.0 - level
+  lower := 0.0
+  upper := 1.0
+  if x > 0 {
+    lower = betaQuantile(alpha/2.0, float64(x), float64(n - x + 1))
+  }
+  if x < n {
+    upper = betaQuantile(1.0 - alpha/2.0, float64(x + 1), float64(n - x))
+  }
+  return lower, upper
+}
+
+// Confidence interval of the ratio (x1/n1)/(x2/n2) of two independent
+// proportions computed on the log scale (Simel et al., 1991)
+func ratioInterval(x1, n1, x2, n2 int, level float64) (float64, float64) {
+  r := (float64(x1)/float64(n1))/(float64(x2)/float64(n2))
+  s := math.Sqrt(1.0/float64(x1) - 1.0/float64(n1) + 1.0/float64(x2) - 1.0/float64(n2))
+  z := normalQuantile(0.5 + level/2.0)
+  return r*math.Exp(-z*s), r*math.Exp(z*s)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Measures of diagnostic accuracy as required by the STARD guidelines:
+// sensitivity, specificity, positive and negative predictive values,
+// prevalence, and positive and negative likelihood ratios. Proportions have
+// exact Clopper-Pearson confidence intervals, likelihood ratios intervals
+// computed on the log scale.
+func DiagnosticAccuracy(c ConfusionMatrix, level float64) []DiagnosticMeasure {
+  proportion := func(name string, x, n int) DiagnosticMeasure {
+    lower, upper := ClopperPearson(x, n, level)
+    return DiagnosticMeasure{Name: name, Numerator: x, Denominator: n, Value: float64(x)/float64(n), Lower: lower, Upper: upper}
+  }
+  p := c.Tp + c.Fn
+  n := c.Fp + c.Tn
+  r := []DiagnosticMeasure{
+    proportion("sensitivity", c.Tp, p),
+    proportion("specificity", c.Tn, n),
+    proportion("ppv"        , c.Tp, c.Tp + c.Fp),
+    proportion("npv"        , c.Tn, c.Tn + c.Fn),
+    proportion("prevalence" , p, p + n) }
+  lr_pos := DiagnosticMeasure{Name: "lr+"}
+  lr_pos.Value = (float64(c.Tp)/float64(p))/(float64(c.Fp)/float64(n))
+  lr_pos.Lower, lr_pos.Upper = ratioInterval(c.Tp, p, c.Fp, n, level)
+  lr_neg := DiagnosticMeasure{Name: "lr-"}
+  lr_neg.Value = (float64(c.Fn)/float64(p))/(float64(c.Tn)/float64(n))
+  lr_neg.Lower, lr_neg.Upper = ratioInterval(c.Fn, p, c.Tn, n, level)
+  return append(r, lr_pos, lr_neg)
+}
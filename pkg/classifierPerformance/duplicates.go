@@ -0,0 +1,103 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+
+/* -------------------------------------------------------------------------- */
+
+// Handle predictions with duplicate keys, e.g. IDs or raw rows of a table.
+// Policies are error (return an error on the first duplicate), first (keep
+// the first occurrence), mean (average predictions of duplicates, which must
+// have identical labels) and keep (keep all predictions). Predictions are
+// kept in the order of first occurrence, and additional columns, rows and
+// relevance values are taken from the first occurrence. The number of
+// duplicates, i.e. predictions with a key seen before, is returned together
+// with the new table.
+func DeduplicatePredictions(table PredictionTable, keys []string, policy string) (PredictionTable, int, error) {
+  if len(keys) != len(table.Values) {
+    return PredictionTable{}, 0, fmt.Errorf("number of predictions and keys do not match")
+  }
+  switch policy {
+  case "error", "first", "mean", "keep":
+  default:
+    return PredictionTable{}, 0, fmt.Errorf("invalid duplicates policy: %s", policy)
+  }
+  // index of the first occurrence of each key and number of occurrences
+  first := make(map[string]int)
+  count := make([]int, len(keys))
+  n     := 0
+  for i, key := range keys {
+    if j, ok := first[key]; ok {
+      switch {
+      case policy == "error":
+        return PredictionTable{}, 0, fmt.Errorf("duplicate key `%s' in rows %d and %d", key, j+1, i+1)
+      case policy == "mean" && table.Labels[i] != table.Labels[j]:
+        return PredictionTable{}, 0, fmt.Errorf("duplicate key `%s' has conflicting labels", key)
+      }
+      count[j]++
+      n++
+    } else {
+      first[key] = i
+      count[i]   = 1
+    }
+  }
+  if n == 0 || policy == "keep" || policy == "error" {
+    return table, n, nil
+  }
+  r := PredictionTable{}
+  r.Values  = make([]float64, 0, len(first))
+  r.Labels  = make([]int, 0, len(first))
+  r.Columns = make(map[string][]string)
+  if table.Relevance != nil {
+    r.Relevance = make([]float64, 0, len(first))
+  }
+  if table.Rows != nil {
+    r.Rows = make([]string, 0, len(first))
+  }
+  for name := range table.Columns {
+    r.Columns[name] = make([]string, 0, len(first))
+  }
+  // sums of predictions, indexed by first occurrences
+  sum := make([]float64, len(keys))
+  for i, key := range keys {
+    sum[first[key]] += table.Values[i]
+  }
+  for i := range keys {
+    if count[i] == 0 {
+      continue
+    }
+    if policy == "mean" {
+      r.Values = append(r.Values, sum[i]/float64(count[i]))
+    } else {
+      r.Values = append(r.Values, table.Values[i])
+    }
+    r.Labels = append(r.Labels, table.Labels[i])
+    if r.Relevance != nil {
+      r.Relevance = append(r.Relevance, table.Relevance[i])
+    }
+    if r.Rows != nil {
+      r.Rows = append(r.Rows, table.Rows[i])
+    }
+    for name, column := range table.Columns {
+      r.Columns[name] = append(r.Columns[name], column[i])
+    }
+  }
+  return r, n, nil
+}
@@ -0,0 +1,85 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "reflect"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func newDuplicatesTable() (PredictionTable, []string) {
+  table := PredictionTable{
+    Values : []float64{0.2, 0.4, 0.6, 0.8},
+    Labels : []int{1, 0, 1, 0},
+    Columns: map[string][]string{"id": {"a", "b", "a", "c"}} }
+  return table, table.Columns["id"]
+}
+
+func TestDeduplicatePredictions(t *testing.T) {
+  for _, test := range []struct {
+    policy string
+    values []float64
+    labels []int
+    ids    []string
+  }{
+    {"keep" , []float64{0.2, 0.4, 0.6, 0.8}, []int{1, 0, 1, 0}, []string{"a", "b", "a", "c"}},
+    {"first", []float64{0.2, 0.4, 0.8}     , []int{1, 0, 0}   , []string{"a", "b", "c"}},
+    {"mean" , []float64{0.4, 0.4, 0.8}     , []int{1, 0, 0}   , []string{"a", "b", "c"}},
+  } {
+    table, keys := newDuplicatesTable()
+    r, n, err := DeduplicatePredictions(table, keys, test.policy); if err != nil {
+      t.Fatalf("policy `%s': %v", test.policy, err)
+    }
+    if n != 1 {
+      t.Errorf("policy `%s': expected 1 duplicate but found %d", test.policy, n)
+    }
+    if !reflect.DeepEqual(r.Values, test.values) || !reflect.DeepEqual(r.Labels, test.labels) || !reflect.DeepEqual(r.Columns["id"], test.ids) {
+      t.Errorf("policy `%s': invalid table: %v %v %v", test.policy, r.Values, r.Labels, r.Columns["id"])
+    }
+  }
+}
+
+func TestDeduplicatePredictionsErrors(t *testing.T) {
+  table, keys := newDuplicatesTable()
+  if _, _, err := DeduplicatePredictions(table, keys, "error"); err == nil {
+    t.Error("duplicate not reported")
+  }
+  if _, _, err := DeduplicatePredictions(table, keys, "last"); err == nil {
+    t.Error("invalid policy not rejected")
+  }
+  if _, _, err := DeduplicatePredictions(table, keys[:2], "first"); err == nil {
+    t.Error("invalid number of keys not rejected")
+  }
+  // duplicates with conflicting labels cannot be averaged
+  table.Labels[2] = 0
+  if _, _, err := DeduplicatePredictions(table, keys, "mean"); err == nil {
+    t.Error("conflicting labels not rejected")
+  }
+}
+
+func TestDeduplicatePredictionsNone(t *testing.T) {
+  table, _ := newDuplicatesTable()
+  _, n, err := DeduplicatePredictions(table, []string{"a", "b", "c", "d"}, "error"); if err != nil {
+    t.Fatal(err)
+  }
+  if n != 0 {
+    t.Errorf("expected no duplicates but found %d", n)
+  }
+}
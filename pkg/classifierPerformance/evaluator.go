@@ -0,0 +1,59 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+// Evaluator accumulates predictions incrementally and computes the exact
+// performance on demand.
+type Evaluator struct {
+  values []float64
+  labels []int
+}
+
+/* -------------------------------------------------------------------------- */
+
+func NewEvaluator() *Evaluator {
+  return &Evaluator{}
+}
+
+/* -------------------------------------------------------------------------- */
+
+func (obj *Evaluator) Add(value float64, label int) {
+  obj.values = append(obj.values, value)
+  obj.labels = append(obj.labels, label)
+}
+
+func (obj *Evaluator) AddBatch(values []float64, labels []int) {
+  obj.values = append(obj.values, values...)
+  obj.labels = append(obj.labels, labels...)
+}
+
+func (obj *Evaluator) Len() int {
+  return len(obj.values)
+}
+
+func (obj *Evaluator) Reset() {
+  obj.values = obj.values[0:0]
+  obj.labels = obj.labels[0:0]
+}
+
+func (obj *Evaluator) Performance() (Performance, error) {
+  // EvalPerformance sorts its arguments, which is fine since the order
+  // of predictions is irrelevant
+  return EvalPerformance(obj.values, obj.labels)
+}
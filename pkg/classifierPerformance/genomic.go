@@ -0,0 +1,78 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+import   "fmt"
+import   "strconv"
+import   "strings"
+
+/* -------------------------------------------------------------------------- */
+
+// GenomicRegion is a region on a chromosome with zero-based start and
+// exclusive end position.
+type GenomicRegion struct {
+  Chrom string
+  Start int
+  End   int
+}
+
+func (obj GenomicRegion) String() string {
+  return fmt.Sprintf("%s:%d-%d", obj.Chrom, obj.Start, obj.End)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Parse a genomic region of the form chr:start-end, e.g. chr1:1000-2000.
+// Thousands separators (commas) in positions are accepted.
+func ParseGenomicRegion(s string) (GenomicRegion, error) {
+  i := strings.LastIndexByte(s, ':')
+  if i <= 0 {
+    return GenomicRegion{}, fmt.Errorf("invalid genomic region `%s'", s)
+  }
+  j := strings.IndexByte(s[i+1:], '-')
+  if j < 0 {
+    return GenomicRegion{}, fmt.Errorf("invalid genomic region `%s'", s)
+  }
+  start, err1 := strconv.Atoi(strings.ReplaceAll(s[i+1:i+1+j], ",", ""))
+  end  , err2 := strconv.Atoi(strings.ReplaceAll(s[i+2+j:]   , ",", ""))
+  if err1 != nil || err2 != nil || start < 0 || end < start {
+    return GenomicRegion{}, fmt.Errorf("invalid genomic region `%s'", s)
+  }
+  return GenomicRegion{Chrom: s[:i], Start: start, End: end}, nil
+}
+
+// Natural order of chromosome names, i.e. chr2 precedes chr10 and other
+// chromosomes such as chrX follow the numbered chromosomes in lexicographic
+// order
+func ChromosomeLess(a, b string) bool {
+  key := func(s string) (int, string) {
+    t := strings.TrimPrefix(strings.TrimPrefix(s, "chr"), "Chr")
+    if n, err := strconv.Atoi(t); err == nil {
+      return n, ""
+    }
+    return int(^uint(0) >> 1), t
+  }
+  n1, s1 := key(a)
+  n2, s2 := key(b)
+  if n1 != n2 {
+    return n1 < n2
+  }
+  if s1 != s2 {
+    return s1 < s2
+  }
+  return a < b
+}
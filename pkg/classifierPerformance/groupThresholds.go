@@ -0,0 +1,178 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Criteria for post-processing thresholds of groups.
+type FairnessCriterion int
+
+const (
+  // Equal true positive rates across groups
+  EqualOpportunity FairnessCriterion = iota
+  // Equal true and false positive rates across groups
+  EqualizedOdds
+)
+
+/* -------------------------------------------------------------------------- */
+
+// True and false positive rates of perf at index i, where i = -1 refers to
+// the threshold -Inf that classifies all predictions as positive
+func ratesAt(perf Performance, i int) (float64, float64) {
+  if i < 0 {
+    return 1.0, 1.0
+  }
+  return float64(perf.Tp[i])/float64(perf.P), float64(perf.Fp[i])/float64(perf.N)
+}
+
+func thresholdAt(perf Performance, i int) float64 {
+  if i < 0 {
+    return math.Inf(-1)
+  }
+  return perf.Tr[i]
+}
+
+// Threshold of perf at which the true positive rate is closest to tpr. A
+// threshold of -Inf classifies all predictions as positive.
+func thresholdAtTPR(perf Performance, tpr float64) float64 {
+  rate := func(i int) float64 {
+    r, _ := ratesAt(perf, i)
+    return r
+  }
+  // true positive rates are non-increasing in i
+  i := sort.Search(perf.Len(), func(i int) bool { return rate(i) <= tpr })
+  if i == perf.Len() || (i > 0 && math.Abs(rate(i-1) - tpr) < math.Abs(rate(i) - tpr)) {
+    i--
+  }
+  return thresholdAt(perf, i)
+}
+
+// Threshold of perf at which the true and false positive rates are closest
+// to tpr and fpr, i.e. at which the larger of both distances is minimal
+func thresholdAtRates(perf Performance, tpr, fpr float64) float64 {
+  dist := func(i int) (float64, float64) {
+    x, y := ratesAt(perf, i)
+    return math.Abs(x - tpr), math.Abs(y - fpr)
+  }
+  // both rates are non-increasing in i, hence both distances decrease
+  // before index lo and increase after index hi, where the rates cross
+  // their targets
+  a  := sort.Search(perf.Len(), func(i int) bool { x, _ := ratesAt(perf, i); return x <= tpr })
+  b  := sort.Search(perf.Len(), func(i int) bool { _, y := ratesAt(perf, i); return y <= fpr })
+  lo := min(a, b)
+  hi := max(a, b)
+  // between lo and hi one distance increases and the other decreases, so
+  // the optimum is where they cross
+  c := lo + sort.Search(hi-lo, func(j int) bool {
+    dx, dy := dist(lo+j)
+    if a <= b {
+      return dx >= dy
+    }
+    return dy >= dx
+  })
+  r, r_dist := -1, math.Inf(1)
+  for _, i := range []int{lo-1, lo, c-1, c, hi} {
+    if i < -1 || i >= perf.Len() {
+      continue
+    }
+    if dx, dy := dist(i); math.Max(dx, dy) < r_dist {
+      r, r_dist = i, math.Max(dx, dy)
+    }
+  }
+  return thresholdAt(perf, r)
+}
+
+// Fairness gap of the given criterion and overall accuracy when each group is
+// classified with its own threshold
+func evalGroupThresholds(perfs []Performance, thresholds []float64, criterion FairnessCriterion) (float64, float64) {
+  tpr_min, tpr_max := math.Inf(1), math.Inf(-1)
+  fpr_min, fpr_max := math.Inf(1), math.Inf(-1)
+  n_correct, n := 0, 0
+  for k, perf := range perfs {
+    c := perf.At(thresholds[k])
+    tpr_min, tpr_max = math.Min(tpr_min, c.TPR()), math.Max(tpr_max, c.TPR())
+    fpr_min, fpr_max = math.Min(fpr_min, c.FPR()), math.Max(fpr_max, c.FPR())
+    n_correct += c.Tp + c.Tn
+    n         += perf.P + perf.N
+  }
+  gap := tpr_max - tpr_min
+  if criterion == EqualizedOdds {
+    gap = math.Max(gap, fpr_max - fpr_min)
+  }
+  return gap, float64(n_correct)/float64(n)
+}
+
+// Search group specific thresholds that (approximately) satisfy the given
+// fairness criterion while maximizing the overall accuracy. For equal
+// opportunity, candidate solutions are obtained by matching the true
+// positive rate of all groups to a common value on a grid of n points in
+// [0,1]. For equalized odds, true and false positive rates of all groups are
+// matched to common values on an n x n grid. Among all candidates with a
+// fairness gap of at most tolerance, the one with maximal accuracy is
+// returned. If no candidate satisfies the tolerance, the one with the
+// smallest gap is selected. The fairness gap of the returned thresholds is
+// returned as well, so that callers can detect an infeasible tolerance.
+func OptimalGroupThresholds(perfs []Performance, criterion FairnessCriterion, tolerance float64, n int) ([]float64, float64, error) {
+  if n < 2 {
+    return nil, math.NaN(), fmt.Errorf("invalid number of grid points: %d", n)
+  }
+  if len(perfs) == 0 {
+    return nil, math.NaN(), fmt.Errorf("no groups given")
+  }
+  for _, perf := range perfs {
+    if perf.P == 0 || perf.N == 0 {
+      return nil, math.NaN(), fmt.Errorf("group contains only a single class")
+    }
+  }
+  m := 1
+  if criterion == EqualizedOdds {
+    m = n
+  }
+  var r []float64
+  r_feasible := false
+  r_gap      := math.Inf(1)
+  r_accuracy := math.Inf(-1)
+  for j := 0; j < n; j++ {
+    for l := 0; l < m; l++ {
+      tpr := float64(j)/float64(n-1)
+      thresholds := make([]float64, len(perfs))
+      for k, perf := range perfs {
+        if criterion == EqualizedOdds {
+          thresholds[k] = thresholdAtRates(perf, tpr, float64(l)/float64(m-1))
+        } else {
+          thresholds[k] = thresholdAtTPR(perf, tpr)
+        }
+      }
+      gap, accuracy := evalGroupThresholds(perfs, thresholds, criterion)
+      feasible := gap <= tolerance
+      switch {
+      case feasible && (!r_feasible || accuracy > r_accuracy):
+      case !feasible && !r_feasible && gap < r_gap:
+      default:
+        continue
+      }
+      r, r_feasible, r_gap, r_accuracy = thresholds, feasible, gap, accuracy
+    }
+  }
+  return r, r_gap, nil
+}
@@ -0,0 +1,59 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// Default grid of assumed prevalences of label-shift tables, i.e. 0.01,
+// 0.02, ..., 0.99.
+func PrevalenceGrid() []float64 {
+  r := make([]float64, 99)
+  for i := range r {
+    r[i] = float64(i+1)/100.0
+  }
+  return r
+}
+
+// Prevalence-dependent measures at a fixed operating point for each of the
+// given prevalences, i.e. under label shift, where the class-conditional
+// score distributions and hence TPR and FPR remain unchanged. The table
+// contains the prevalence, precision (PPV), negative predictive value,
+// normalized precision, the expected cost at the threshold, and the minimal
+// expected cost over all thresholds.
+func LabelShift(perf Performance, threshold float64, costs CostMatrix, prevalences []float64) Table {
+  c   := perf.At(threshold)
+  tpr := c.TPR()
+  fpr := c.FPR()
+  r   := Table{Names: []string{"prevalence", "precision", "npv", "normalized-precision", "cost", "min-cost"}, Columns: make([][]float64, 6)}
+  for _, p := range prevalences {
+    ppv  := p*tpr/(p*tpr + (1.0 - p)*fpr)
+    npv  := (1.0 - p)*(1.0 - fpr)/((1.0 - p)*(1.0 - fpr) + p*(1.0 - tpr))
+    cost := p*(tpr*costs.Tp + (1.0 - tpr)*costs.Fn) + (1.0 - p)*(fpr*costs.Fp + (1.0 - fpr)*costs.Tn)
+    min  := math.Inf(1)
+    for _, v := range ExpectedCost(perf, costs, p).Columns[2] {
+      min = math.Min(min, v)
+    }
+    for j, v := range []float64{p, ppv, npv, (ppv - p)/(1.0 - p), cost, min} {
+      r.Columns[j] = append(r.Columns[j], v)
+    }
+  }
+  return r
+}
@@ -0,0 +1,78 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "bufio"
+import   "io"
+
+/* -------------------------------------------------------------------------- */
+
+// lineReader reads lines of arbitrary length without allocating memory for
+// each line. The returned slices are only valid until the next call to
+// ReadLine.
+type lineReader struct {
+  reader *bufio.Reader
+  buffer []byte
+}
+
+func newLineReader(reader io.Reader) *lineReader {
+  return &lineReader{reader: bufio.NewReaderSize(reader, 1024*1024)}
+}
+
+func (obj *lineReader) ReadLine() ([]byte, error) {
+  line, err := obj.reader.ReadSlice('\n')
+  if err == bufio.ErrBufferFull {
+    // line does not fit into the buffer of the reader, accumulate it in
+    // a separate buffer
+    obj.buffer = append(obj.buffer[0:0], line...)
+    for err == bufio.ErrBufferFull {
+      line, err = obj.reader.ReadSlice('\n')
+      obj.buffer = append(obj.buffer, line...)
+    }
+    line = obj.buffer
+  }
+  if err == io.EOF && len(line) > 0 {
+    err = nil
+  }
+  return line, err
+}
+
+/* -------------------------------------------------------------------------- */
+
+func isSpace(c byte) bool {
+  return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// Split a line into fields separated by white space. The fields slice is
+// reused to avoid allocations.
+func splitFields(line []byte, fields [][]byte) [][]byte {
+  fields = fields[0:0]
+  for i := 0; i < len(line); {
+    for ; i < len(line) && isSpace(line[i]); i++ {
+    }
+    j := i
+    for ; j < len(line) && !isSpace(line[j]); j++ {
+    }
+    if j > i {
+      fields = append(fields, line[i:j])
+    }
+    i = j
+  }
+  return fields
+}
@@ -0,0 +1,319 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "sync"
+
+/* -------------------------------------------------------------------------- */
+
+type MetricKind int
+
+const (
+  // a single number, e.g. the area under the ROC curve
+  ScalarMetric MetricKind = iota
+  // a table with one row per threshold, e.g. the ROC curve
+  CurveMetric
+  // a single row of values, e.g. an optimal operating point
+  PointMetric
+)
+
+// Table is the result of evaluating a metric. Scalar metrics return a table
+// with a single column and row.
+type Table struct {
+  Names   []string
+  Columns [][]float64
+}
+
+func (obj Table) Rows() int {
+  if len(obj.Columns) == 0 {
+    return 0
+  }
+  return len(obj.Columns[0])
+}
+
+// Metric is the interface of performance measures that can be registered
+// and evaluated by name.
+type Metric interface {
+  Name() string
+  Kind() MetricKind
+  Eval(data *Metrics) (Table, error)
+}
+
+/* -------------------------------------------------------------------------- */
+
+type metric struct {
+  name string
+  kind MetricKind
+  f    func(data *Metrics) (Table, error)
+}
+
+func (obj metric) Name() string {
+  return obj.name
+}
+
+func (obj metric) Kind() MetricKind {
+  return obj.kind
+}
+
+func (obj metric) Eval(data *Metrics) (Table, error) {
+  return obj.f(data)
+}
+
+func NewMetric(name string, kind MetricKind, f func(data *Metrics) (Table, error)) Metric {
+  return metric{name: name, kind: kind, f: f}
+}
+
+func NewScalarMetric(name string, f func(data *Metrics) (float64, error)) Metric {
+  return metric{name: name, kind: ScalarMetric, f: func(data *Metrics) (Table, error) {
+    if v, err := f(data); err != nil {
+      return Table{}, err
+    } else {
+      return Table{Names: []string{name}, Columns: [][]float64{{v}}}, nil
+    }
+  }}
+}
+
+func NewCurveMetric(name string, names []string, f func(data *Metrics) (Curve, error)) Metric {
+  return metric{name: name, kind: CurveMetric, f: func(data *Metrics) (Table, error) {
+    if c, err := f(data); err != nil {
+      return Table{}, err
+    } else {
+      return Table{Names: names, Columns: [][]float64{c.X, c.Y, c.Tr}}, nil
+    }
+  }}
+}
+
+/* -------------------------------------------------------------------------- */
+
+var metricRegistry = struct {
+  sync.RWMutex
+  metrics map[string]Metric
+  names   []string
+}{metrics: make(map[string]Metric)}
+
+// Register a new metric. Metrics are typically registered in init functions,
+// which also allows packages importing this library to add new metrics.
+func RegisterMetric(m Metric) error {
+  metricRegistry.Lock()
+  defer metricRegistry.Unlock()
+  if _, ok := metricRegistry.metrics[m.Name()]; ok {
+    return fmt.Errorf("metric `%s' is already registered", m.Name())
+  }
+  metricRegistry.metrics[m.Name()] = m
+  metricRegistry.names = append(metricRegistry.names, m.Name())
+  return nil
+}
+
+func LookupMetric(name string) (Metric, bool) {
+  metricRegistry.RLock()
+  defer metricRegistry.RUnlock()
+  m, ok := metricRegistry.metrics[name]
+  return m, ok
+}
+
+// Names of all registered metrics in order of registration.
+func RegisteredMetrics() []string {
+  metricRegistry.RLock()
+  defer metricRegistry.RUnlock()
+  r := make([]string, len(metricRegistry.names))
+  copy(r, metricRegistry.names)
+  return r
+}
+
+func mustRegisterMetric(m Metric) {
+  if err := RegisterMetric(m); err != nil {
+    panic(err)
+  }
+}
+
+/* -------------------------------------------------------------------------- */
+
+func init() {
+  mustRegisterMetric(NewCurveMetric("precision-recall", []string{"recall", "precision", "threshold"}, func(data *Metrics) (Curve, error) {
+    return data.CompatPrecisionRecall(), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("precision-recall-auc", func(data *Metrics) (float64, error) {
+    return data.PrecisionRecallAUC()
+  }))
+  mustRegisterMetric(NewCurveMetric("roc", []string{"FPR", "TPR", "threshold"}, func(data *Metrics) (Curve, error) {
+    return data.CompatRoc(), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("roc-auc", func(data *Metrics) (float64, error) {
+    return data.RocAUC()
+  }))
+  mustRegisterMetric(NewMetric("optimal-precision-recall", PointMetric, func(data *Metrics) (Table, error) {
+    pr := data.PrecisionRecall()
+    i  := Optimum(pr.Tr, pr.X, pr.Y)
+    return Table{
+      Names  : []string{"recall", "precision", "threshold"},
+      Columns: [][]float64{{pr.X[i]}, {pr.Y[i]}, {pr.Tr[i]}} }, nil
+  }))
+  mustRegisterMetric(NewMetric("optimal-roc", PointMetric, func(data *Metrics) (Table, error) {
+    roc := data.Roc()
+    i   := OptimumRoc(roc.Tr, roc.X, roc.Y)
+    return Table{
+      Names  : []string{"fpr", "tpr", "threshold"},
+      Columns: [][]float64{{roc.X[i]}, {roc.Y[i]}, {roc.Tr[i]}} }, nil
+  }))
+  mustRegisterMetric(NewScalarMetric("ks", func(data *Metrics) (float64, error) {
+    return data.KS(), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("brier", func(data *Metrics) (float64, error) {
+    return data.Brier()
+  }))
+  mustRegisterMetric(NewMetric("calibration", CurveMetric, func(data *Metrics) (Table, error) {
+    r, err := data.Reliability(); if err != nil {
+      return Table{}, err
+    }
+    count := make([]float64, r.Len())
+    for k := range count {
+      count[k] = float64(r.Count[k])
+    }
+    return Table{
+      Names  : []string{"predicted", "observed", "n"},
+      Columns: [][]float64{r.Predicted, r.Observed, count} }, nil
+  }))
+  mustRegisterMetric(NewMetric("ks-table", CurveMetric, func(data *Metrics) (Table, error) {
+    bins, err := data.QuantileBins(); if err != nil {
+      return Table{}, err
+    }
+    return KSTable(bins), nil
+  }))
+  mustRegisterMetric(NewCurveMetric("gains", []string{"fraction", "captured", "threshold"}, func(data *Metrics) (Curve, error) {
+    return Gains(data.Perf), nil
+  }))
+  mustRegisterMetric(NewCurveMetric("lift", []string{"fraction", "lift", "threshold"}, func(data *Metrics) (Curve, error) {
+    return Lift(data.Perf), nil
+  }))
+  mustRegisterMetric(NewMetric("gains-table", CurveMetric, func(data *Metrics) (Table, error) {
+    bins, err := data.QuantileBins(); if err != nil {
+      return Table{}, err
+    }
+    return GainsTable(bins), nil
+  }))
+  mustRegisterMetric(NewMetric("alert-volume", CurveMetric, func(data *Metrics) (Table, error) {
+    return AlertVolume(data.Perf), nil
+  }))
+  mustRegisterMetric(NewMetric("cost-curve", CurveMetric, func(data *Metrics) (Table, error) {
+    return data.ExpectedCost(), nil
+  }))
+  mustRegisterMetric(NewMetric("optimal-cost", PointMetric, func(data *Metrics) (Table, error) {
+    return data.OptimalCost()
+  }))
+  mustRegisterMetric(NewScalarMetric("lift@k", func(data *Metrics) (float64, error) {
+    return data.LiftAtK()
+  }))
+  mustRegisterMetric(NewScalarMetric("capture@k", func(data *Metrics) (float64, error) {
+    return data.CaptureAtK()
+  }))
+  mustRegisterMetric(NewScalarMetric("ece", func(data *Metrics) (float64, error) {
+    return data.ECE()
+  }))
+  mustRegisterMetric(NewCurveMetric("roc-smooth", []string{"FPR", "TPR", "threshold"}, func(data *Metrics) (Curve, error) {
+    return data.SmoothRoc()
+  }))
+  mustRegisterMetric(NewScalarMetric("roc-smooth-auc", func(data *Metrics) (float64, error) {
+    if roc, err := data.SmoothRoc(); err != nil {
+      return 0.0, err
+    } else {
+      return roc.AUC()
+    }
+  }))
+  mustRegisterMetric(NewCurveMetric("roc-binormal", []string{"FPR", "TPR", "threshold"}, func(data *Metrics) (Curve, error) {
+    r, err := data.Binormal(); if err != nil {
+      return Curve{}, err
+    }
+    return r.Curve(binormalPoints), nil
+  }))
+  mustRegisterMetric(NewMetric("binormal", PointMetric, func(data *Metrics) (Table, error) {
+    r, err := data.Binormal(); if err != nil {
+      return Table{}, err
+    }
+    return Table{
+      Names  : []string{"a", "b", "auc", "se"},
+      Columns: [][]float64{{r.A}, {r.B}, {r.AUC()}, {r.AUCStdErr()}} }, nil
+  }))
+  mustRegisterMetric(NewScalarMetric("binormal-auc", func(data *Metrics) (float64, error) {
+    r, err := data.Binormal(); if err != nil {
+      return 0.0, err
+    }
+    return r.AUC(), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("kl-divergence", func(data *Metrics) (float64, error) {
+    p, q, err := data.ScoreDistributions(densityPseudocount); if err != nil {
+      return 0.0, err
+    }
+    return KLDivergence(p, q), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("js-divergence", func(data *Metrics) (float64, error) {
+    p, q, err := data.ScoreDistributions(0.0); if err != nil {
+      return 0.0, err
+    }
+    return JSDivergence(p, q), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("bhattacharyya", func(data *Metrics) (float64, error) {
+    p, q, err := data.ScoreDistributions(0.0); if err != nil {
+      return 0.0, err
+    }
+    return BhattacharyyaDistance(p, q), nil
+  }))
+  mustRegisterMetric(NewMetric("histogram", CurveMetric, func(data *Metrics) (Table, error) {
+    return data.Histogram()
+  }))
+  mustRegisterMetric(NewMetric("accuracy-rejection", CurveMetric, func(data *Metrics) (Table, error) {
+    return data.AccuracyRejection()
+  }))
+  mustRegisterMetric(NewMetric("informedness", CurveMetric, func(data *Metrics) (Table, error) {
+    return Informedness(data.Perf), nil
+  }))
+  mustRegisterMetric(NewMetric("optimal-informedness", PointMetric, func(data *Metrics) (Table, error) {
+    return TableOptimum(Informedness(data.Perf), 0), nil
+  }))
+  mustRegisterMetric(NewMetric("markedness", CurveMetric, func(data *Metrics) (Table, error) {
+    return Markedness(data.Perf), nil
+  }))
+  mustRegisterMetric(NewMetric("optimal-markedness", PointMetric, func(data *Metrics) (Table, error) {
+    return TableOptimum(Markedness(data.Perf), 0), nil
+  }))
+  mustRegisterMetric(NewMetric("gmean", CurveMetric, func(data *Metrics) (Table, error) {
+    return GMean(data.Perf), nil
+  }))
+  mustRegisterMetric(NewMetric("optimal-gmean", PointMetric, func(data *Metrics) (Table, error) {
+    return TableOptimum(GMean(data.Perf), 0), nil
+  }))
+  mustRegisterMetric(NewMetric("fowlkes-mallows", CurveMetric, func(data *Metrics) (Table, error) {
+    return FowlkesMallows(data.Perf), nil
+  }))
+  mustRegisterMetric(NewMetric("optimal-fowlkes-mallows", PointMetric, func(data *Metrics) (Table, error) {
+    return TableOptimum(FowlkesMallows(data.Perf), 0), nil
+  }))
+  mustRegisterMetric(NewMetric("prevalence-threshold-curve", CurveMetric, func(data *Metrics) (Table, error) {
+    return PrevalenceThreshold(data.Perf), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("prevalence-threshold", func(data *Metrics) (float64, error) {
+    return data.Perf.At(data.SelectedThreshold()).PrevalenceThreshold(), nil
+  }))
+  mustRegisterMetric(NewScalarMetric("average-precision", func(data *Metrics) (float64, error) {
+    return data.AveragePrecision(), nil
+  }))
+  mustRegisterMetric(NewMetric("label-shift", CurveMetric, func(data *Metrics) (Table, error) {
+    return data.LabelShift(), nil
+  }))
+}
@@ -0,0 +1,123 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "slices"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestRegisterMetric(t *testing.T) {
+  m := NewScalarMetric("test-positives", func(data *Metrics) (float64, error) {
+    return float64(data.Perf.P), nil
+  })
+  if err := RegisterMetric(m); err != nil {
+    t.Fatal(err)
+  }
+  if err := RegisterMetric(m); err == nil {
+    t.Error("metric registered twice")
+  }
+  if r, ok := LookupMetric("test-positives"); !ok || r.Kind() != ScalarMetric {
+    t.Error("registered metric not found")
+  }
+  if names := RegisteredMetrics(); names[len(names)-1] != "test-positives" || !slices.Contains(names, "roc-auc") {
+    t.Errorf("invalid names of registered metrics: %v", names)
+  }
+  perf, err := EvalPerformance([]float64{0.1, 0.5, 0.9, 0.3}, []int{0, 1, 1, 0}); if err != nil {
+    t.Fatal(err)
+  }
+  if r, err := NewMetrics(perf, false).Eval("test-positives", "roc-auc"); err != nil {
+    t.Fatal(err)
+  } else if r[0] != 2.0 {
+    t.Errorf("invalid value: %v", r[0])
+  }
+  if _, err := NewMetrics(perf, false).Eval("no-such-metric"); err == nil {
+    t.Error("unknown metric not rejected")
+  }
+}
+
+// All built-in metrics must either return a result of their kind or an
+// error
+func TestRegisteredMetrics(t *testing.T) {
+  values := []float64{0.1, 0.5, 0.9, 0.3, 0.5, 0.7, 0.2, 0.8}
+  labels := []int{0, 1, 1, 0, 0, 1, 0, 1}
+  perf, err := EvalPerformance(append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    t.Fatal(err)
+  }
+  for _, name := range RegisteredMetrics() {
+    m, _ := LookupMetric(name)
+    data := NewMetrics(perf, false)
+    data.Values = values
+    data.Labels = labels
+    table, err := m.Eval(data); if err != nil {
+      continue
+    }
+    switch {
+    case len(table.Names) != len(table.Columns):
+      t.Errorf("metric `%s' has %d names but %d columns", name, len(table.Names), len(table.Columns))
+    case m.Kind() == ScalarMetric && (len(table.Columns) != 1 || table.Rows() != 1):
+      t.Errorf("scalar metric `%s' returned %d columns and %d rows", name, len(table.Columns), table.Rows())
+    case m.Kind() == PointMetric && table.Rows() != 1:
+      t.Errorf("point metric `%s' returned %d rows", name, table.Rows())
+    }
+  }
+}
+
+func TestCaptureAndLiftAtK(t *testing.T) {
+  eval := func(labels []int, topN int, topFraction float64) (float64, float64, error, error) {
+    values := []float64{0.9, 0.8, 0.7, 0.6, 0.5, 0.4, 0.3, 0.2, 0.1, 0.0}
+    perf, err := EvalPerformance(values, append([]int{}, labels...)); if err != nil {
+      t.Fatal(err)
+    }
+    data := NewMetrics(perf, false)
+    data.TopN        = topN
+    data.TopFraction = topFraction
+    capture, err1 := data.CaptureAtK()
+    lift   , err2 := data.LiftAtK()
+    return capture, lift, err1, err2
+  }
+  labels := []int{1, 1, 0, 1, 0, 0, 1, 0, 0, 0}
+  for _, test := range []struct {
+    topN          int
+    topFraction   float64
+    capture, lift float64
+  }{
+    {2, 0.0, 0.50, 2.5},
+    {0, 0.0, 0.25, 2.5},
+    {0, 0.3, 0.50, 5.0/3.0},
+  } {
+    capture, lift, err1, err2 := eval(labels, test.topN, test.topFraction)
+    if err1 != nil || err2 != nil {
+      t.Errorf("unexpected errors: %v %v", err1, err2)
+    } else
+    if math.Abs(capture - test.capture) > 1e-12 || math.Abs(lift - test.lift) > 1e-12 {
+      t.Errorf("top %d (fraction %v): unexpected capture %v and lift %v", test.topN, test.topFraction, capture, lift)
+    }
+  }
+  // less than one top scored prediction
+  if _, _, err1, err2 := eval(labels, 0, 0.05); err1 == nil || err2 == nil {
+    t.Errorf("empty top k accepted")
+  }
+  // no positives
+  if _, _, err1, err2 := eval(make([]int, 10), 2, 0.0); err1 == nil || err2 == nil {
+    t.Errorf("predictions without positives accepted")
+  }
+}
@@ -0,0 +1,442 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// Kolmogorov-Smirnov statistic, i.e. the maximum difference between TPR and
+// FPR over all thresholds.
+func KS(perf Performance) float64 {
+  return ks(Roc(perf))
+}
+
+func ks(roc Curve) float64 {
+  r := 0.0
+  for i := 0; i < roc.Len(); i++ {
+    if d := math.Abs(roc.Y[i] - roc.X[i]); d > r {
+      r = d
+    }
+  }
+  return r
+}
+
+// Brier score, i.e. the mean squared difference between predicted
+// probabilities and labels.
+func Brier[T Float](values []T, labels []int) (float64, error) {
+  if len(values) != len(labels) {
+    return 0.0, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if len(values) == 0 {
+    return math.NaN(), nil
+  }
+  r := 0.0
+  for i := 0; i < len(values); i++ {
+    if labels[i] != 0 && labels[i] != 1 {
+      return 0.0, fmt.Errorf("invalid label: %d", labels[i])
+    }
+    d := float64(values[i]) - float64(labels[i])
+    r += d*d
+  }
+  return r/float64(len(values)), nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Metrics computes several scalar performance measures from a single
+// Performance object. Intermediate results such as the ROC and
+// precision-recall curves are computed only once and shared between
+// measures. Values and Labels are optional and only required for measures
+// that depend on the raw predictions (e.g. the Brier score). Method selects
+// how the area under the ROC curve is computed [integration (default),
+// ranksum]. Bins is the number of bins used for calibration measures, score
+// tables and rating categories of binormal fits (default: DefaultBins).
+// TopN or TopFraction define the number of top scored predictions for
+// measures such as lift@k (default: DefaultTopFraction). Costs and
+// CostPrevalences are used by cost measures (default: DefaultCostMatrix and
+// the prevalence of the data), where cost curves are computed for each
+// prevalence. Bandwidth is the kernel bandwidth of smoothed ROC curves and
+// kernel density estimates (default: estimated for each class). Density
+// selects how score distributions of positives and negatives are estimated
+// for divergence measures and score histograms [histogram (default) with
+// Bins bins, kde]. Threshold is the decision threshold marked in score
+// histograms and the operating point of the prevalence threshold (default:
+// NaN, i.e. the optimal threshold of the ROC curve), which is also used by
+// the accuracy-rejection curve. Rejection is the confidence of predictions
+// for the accuracy-rejection curve [margin (default), score]. Prevalences
+// are the assumed prevalences of label-shift tables (default:
+// PrevalenceGrid()). Compat selects conventions of another implementation
+// for ROC and precision-recall curves and their areas [sklearn], in which
+// case Performance must be evaluated at all unique prediction values.
+type Metrics struct {
+  Perf            Performance
+  Values          []float64
+  Labels          []int
+  Normalize       bool
+  Method          string
+  Bins            int
+  TopN            int
+  TopFraction     float64
+  Costs           *CostMatrix
+  CostPrevalences []float64
+  Bandwidth       float64
+  Density         string
+  Threshold       float64
+  Rejection       string
+  Prevalences     []float64
+  Compat          string
+  roc             *Curve
+  pr              *Curve
+  binormal        *Binormal
+  smoothRoc       *Curve
+}
+
+// Default number of bins for calibration measures and score tables.
+const DefaultBins = 10
+
+// Deprecated: use DefaultBins.
+const DefaultCalibrationBins = DefaultBins
+
+// Default fraction of top scored predictions.
+const DefaultTopFraction = 0.1
+
+// Names of the scalar measures evaluated by default.
+var MetricNames = []string{"roc-auc", "precision-recall-auc", "ks", "brier"}
+
+/* -------------------------------------------------------------------------- */
+
+func NewMetrics(perf Performance, normalize bool) *Metrics {
+  return &Metrics{Perf: perf, Normalize: normalize, Threshold: math.NaN()}
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Degenerate reports whether predictions contain only positives or only
+// negatives, in which case measures that compare both classes are undefined.
+func (obj *Metrics) Degenerate() bool {
+  return obj.Perf.P == 0 || obj.Perf.N == 0
+}
+
+func (obj *Metrics) Roc() Curve {
+  if obj.roc == nil {
+    roc := Roc(obj.Perf)
+    obj.roc = &roc
+  }
+  return *obj.roc
+}
+
+func (obj *Metrics) PrecisionRecall() Curve {
+  if obj.pr == nil {
+    pr := PrecisionRecall(obj.Perf, obj.Normalize)
+    obj.pr = &pr
+  }
+  return *obj.pr
+}
+
+// ROC curve following the conventions selected by Compat.
+func (obj *Metrics) CompatRoc() Curve {
+  if obj.Compat == "sklearn" {
+    return SklearnRoc(obj.Perf, true)
+  }
+  return obj.Roc()
+}
+
+// Precision-recall curve following the conventions selected by Compat.
+func (obj *Metrics) CompatPrecisionRecall() Curve {
+  if obj.Compat == "sklearn" {
+    return SklearnPrecisionRecall(obj.Perf)
+  }
+  return obj.PrecisionRecall()
+}
+
+func (obj *Metrics) RocAUC() (float64, error) {
+  switch obj.Method {
+  case "", "integration":
+    if obj.Compat == "sklearn" {
+      roc := obj.CompatRoc()
+      return SklearnAUC(roc.X, roc.Y)
+    }
+    return obj.Roc().AUC()
+  case "ranksum":
+    if obj.Values == nil {
+      return 0.0, fmt.Errorf("method `ranksum' requires raw predictions")
+    }
+    return AUCRankSum(obj.Values, obj.Labels)
+  default:
+    return 0.0, fmt.Errorf("invalid method: %s", obj.Method)
+  }
+}
+
+func (obj *Metrics) PrecisionRecallAUC() (float64, error) {
+  if obj.Compat == "sklearn" {
+    pr := obj.CompatPrecisionRecall()
+    return SklearnAUC(pr.X, pr.Y)
+  }
+  return obj.PrecisionRecall().AUC()
+}
+
+// Average precision, i.e. precisions weighted by the increase in recall at
+// each threshold without interpolation between points of the curve.
+func (obj *Metrics) AveragePrecision() float64 {
+  return SklearnAveragePrecision(obj.Perf)
+}
+
+func (obj *Metrics) KS() float64 {
+  return ks(obj.Roc())
+}
+
+func (obj *Metrics) Brier() (float64, error) {
+  if obj.Values == nil {
+    return 0.0, fmt.Errorf("brier score requires raw predictions")
+  }
+  return Brier(obj.Values, obj.Labels)
+}
+
+func (obj *Metrics) bins() int {
+  if obj.Bins == 0 {
+    return DefaultBins
+  }
+  return obj.Bins
+}
+
+// Predictions binned into quantiles of decreasing scores.
+func (obj *Metrics) QuantileBins() ([]ScoreBin, error) {
+  if obj.Values == nil {
+    return nil, fmt.Errorf("score bins require raw predictions")
+  }
+  return QuantileBins(obj.Values, obj.Labels, obj.bins())
+}
+
+func (obj *Metrics) Reliability() (Reliability, error) {
+  if obj.Values == nil {
+    return Reliability{}, fmt.Errorf("calibration requires raw predictions")
+  }
+  return EvalReliability(obj.Values, obj.Labels, obj.bins())
+}
+
+func (obj *Metrics) ECE() (float64, error) {
+  if r, err := obj.Reliability(); err != nil {
+    return 0.0, err
+  } else {
+    return r.ECE(), nil
+  }
+}
+
+// Kernel-smoothed ROC curve.
+func (obj *Metrics) SmoothRoc() (Curve, error) {
+  if obj.smoothRoc == nil {
+    if obj.Values == nil {
+      return Curve{}, fmt.Errorf("smoothed ROC curve requires raw predictions")
+    }
+    r, err := SmoothRoc(obj.Values, obj.Labels, obj.Bandwidth, smoothRocPoints); if err != nil {
+      return Curve{}, err
+    }
+    obj.smoothRoc = &r
+  }
+  return *obj.smoothRoc, nil
+}
+
+// Binormal ROC model fitted to predictions.
+func (obj *Metrics) Binormal() (Binormal, error) {
+  if obj.binormal == nil {
+    if obj.Values == nil {
+      return Binormal{}, fmt.Errorf("binormal fit requires raw predictions")
+    }
+    r, err := FitBinormal(obj.Values, obj.Labels, obj.bins()); if err != nil {
+      return Binormal{}, err
+    }
+    obj.binormal = &r
+  }
+  return *obj.binormal, nil
+}
+
+// Expected cost at each threshold. With several cost prevalences, costs are
+// computed for each prevalence.
+func (obj *Metrics) ExpectedCost() Table {
+  costs := DefaultCostMatrix
+  if obj.Costs != nil {
+    costs = *obj.Costs
+  }
+  switch len(obj.CostPrevalences) {
+  case 0:
+    return ExpectedCost(obj.Perf, costs, 0.0)
+  case 1:
+    return ExpectedCost(obj.Perf, costs, obj.CostPrevalences[0])
+  default:
+    return ExpectedCostPrevalences(obj.Perf, costs, obj.CostPrevalences)
+  }
+}
+
+// Operating point with minimal expected cost, which requires a single cost
+// prevalence.
+func (obj *Metrics) OptimalCost() (Table, error) {
+  if len(obj.CostPrevalences) > 1 {
+    return Table{}, fmt.Errorf("optimal-cost requires a single prevalence")
+  }
+  return OptimalCost(obj.ExpectedCost()), nil
+}
+
+// Prevalence-dependent measures at the selected threshold for each assumed
+// prevalence.
+func (obj *Metrics) LabelShift() Table {
+  costs := DefaultCostMatrix
+  if obj.Costs != nil {
+    costs = *obj.Costs
+  }
+  prevalences := obj.Prevalences
+  if len(prevalences) == 0 {
+    prevalences = PrevalenceGrid()
+  }
+  return LabelShift(obj.Perf, obj.SelectedThreshold(), costs, prevalences)
+}
+
+// Number of top scored predictions
+func (obj *Metrics) topK() float64 {
+  switch {
+  case obj.TopN > 0:
+    return float64(obj.TopN)
+  case obj.TopFraction > 0.0:
+    return obj.TopFraction*float64(obj.Perf.P + obj.Perf.N)
+  default:
+    return DefaultTopFraction*float64(obj.Perf.P + obj.Perf.N)
+  }
+}
+
+// Number of top scored predictions for capture@k and lift@k, which must
+// contain at least one prediction and requires positives to be given.
+func (obj *Metrics) checkTopK() (float64, error) {
+  k := obj.topK()
+  if !(k >= 1.0) {
+    return 0.0, fmt.Errorf("number of top scored predictions must be at least one")
+  }
+  if obj.Perf.P == 0 {
+    return 0.0, fmt.Errorf("no positive predictions given")
+  }
+  return k, nil
+}
+
+// Fraction of all positives among the top scored predictions.
+func (obj *Metrics) CaptureAtK() (float64, error) {
+  k, err := obj.checkTopK(); if err != nil {
+    return 0.0, err
+  }
+  return obj.Perf.TopK(k)/float64(obj.Perf.P), nil
+}
+
+// Ratio of the fraction of positives among the top scored predictions and
+// the fraction of positives among all predictions.
+func (obj *Metrics) LiftAtK() (float64, error) {
+  k, err := obj.checkTopK(); if err != nil {
+    return 0.0, err
+  }
+  return (obj.Perf.TopK(k)/k)/(float64(obj.Perf.P)/float64(obj.Perf.P + obj.Perf.N)), nil
+}
+
+// Number of points at which kernel density estimates are discretized
+const densityPoints = 512
+
+// Pseudocount added to histogram counts for the Kullback-Leibler
+// divergence, which is otherwise infinite if a bin contains no negatives
+const densityPseudocount = 0.5
+
+// Discrete distributions of the scores of positives and negatives used by
+// divergence measures.
+func (obj *Metrics) ScoreDistributions(pseudocount float64) ([]float64, []float64, error) {
+  if obj.Values == nil {
+    return nil, nil, fmt.Errorf("score distributions require raw predictions")
+  }
+  switch obj.Density {
+  case "", "histogram":
+    bins, err := ClassHistogram(obj.Values, obj.Labels, obj.bins()); if err != nil {
+      return nil, nil, err
+    }
+    p, q := HistogramDistributions(bins, pseudocount)
+    return p, q, nil
+  case "kde":
+    _, p, q, err := KernelDistributions(obj.Values, obj.Labels, obj.Bandwidth, densityPoints)
+    return p, q, err
+  default:
+    return nil, nil, fmt.Errorf("invalid density estimate: %s", obj.Density)
+  }
+}
+
+// Decision threshold given by Threshold, or the optimal threshold of the ROC
+// curve if Threshold is NaN.
+func (obj *Metrics) SelectedThreshold() float64 {
+  if !math.IsNaN(obj.Threshold) {
+    return obj.Threshold
+  }
+  roc := obj.Roc()
+  return roc.Tr[OptimumRoc(roc.Tr, roc.X, roc.Y)]
+}
+
+// Histogram or kernel density estimates of the scores of positives and
+// negatives with the selected threshold marked.
+func (obj *Metrics) Histogram() (Table, error) {
+  if obj.Values == nil {
+    return Table{}, fmt.Errorf("score histograms require raw predictions")
+  }
+  threshold := obj.SelectedThreshold()
+  switch obj.Density {
+  case "", "histogram":
+    bins, err := ClassHistogram(obj.Values, obj.Labels, obj.bins()); if err != nil {
+      return Table{}, err
+    }
+    return HistogramTable(bins, threshold), nil
+  case "kde":
+    t, p, q, err := KernelDistributions(obj.Values, obj.Labels, obj.Bandwidth, densityPoints); if err != nil {
+      return Table{}, err
+    }
+    return DensityTable(t, p, q, threshold), nil
+  default:
+    return Table{}, fmt.Errorf("invalid density estimate: %s", obj.Density)
+  }
+}
+
+// Accuracy of retained predictions as a function of the fraction of least
+// confident predictions that are rejected.
+func (obj *Metrics) AccuracyRejection() (Table, error) {
+  if obj.Values == nil {
+    return Table{}, fmt.Errorf("accuracy-rejection curve requires raw predictions")
+  }
+  return AccuracyRejection(obj.Values, obj.Labels, obj.SelectedThreshold(), obj.Rejection)
+}
+
+// Evaluate the given scalar measures from the metric registry. If no names
+// are given, all measures in MetricNames are evaluated.
+func (obj *Metrics) Eval(names ...string) ([]float64, error) {
+  if len(names) == 0 {
+    names = MetricNames
+  }
+  r := make([]float64, len(names))
+  for i, name := range names {
+    m, ok := LookupMetric(name)
+    if !ok || m.Kind() != ScalarMetric {
+      return nil, fmt.Errorf("invalid metric: %s", name)
+    }
+    if t, err := m.Eval(obj); err != nil {
+      return nil, err
+    } else {
+      r[i] = t.Columns[0][0]
+    }
+  }
+  return r, nil
+}
@@ -0,0 +1,214 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "archive/zip"
+import   "bytes"
+import   "encoding/binary"
+import   "fmt"
+import   "io"
+import   "math"
+import   "regexp"
+import   "strconv"
+import   "strings"
+
+/* -------------------------------------------------------------------------- */
+
+var npyDescr = regexp.MustCompile(`'descr'\s*:\s*'([<>|=])([a-z])(\d+)'`)
+var npyShape = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+
+// Parse the header of a .npy file and return the byte order, kind and size
+// of the data type together with the number of elements. Arrays must be
+// one-dimensional, except for additional dimensions of length one.
+func readNpyHeader(reader io.Reader) (binary.ByteOrder, byte, int, int, error) {
+  magic := make([]byte, 8)
+  if _, err := io.ReadFull(reader, magic); err != nil {
+    return nil, 0, 0, 0, fmt.Errorf("invalid npy header: %v", err)
+  }
+  if !bytes.HasPrefix(magic, []byte("\x93NUMPY")) {
+    return nil, 0, 0, 0, fmt.Errorf("invalid npy header: not a NumPy array")
+  }
+  var n int
+  switch magic[6] {
+  case 1:
+    var m uint16
+    if err := binary.Read(reader, binary.LittleEndian, &m); err != nil {
+      return nil, 0, 0, 0, fmt.Errorf("invalid npy header: %v", err)
+    }
+    n = int(m)
+  case 2, 3:
+    var m uint32
+    if err := binary.Read(reader, binary.LittleEndian, &m); err != nil {
+      return nil, 0, 0, 0, fmt.Errorf("invalid npy header: %v", err)
+    }
+    n = int(m)
+  default:
+    return nil, 0, 0, 0, fmt.Errorf("invalid npy header: unsupported version %d", magic[6])
+  }
+  header := make([]byte, n)
+  if _, err := io.ReadFull(reader, header); err != nil {
+    return nil, 0, 0, 0, fmt.Errorf("invalid npy header: %v", err)
+  }
+  descr := npyDescr.FindSubmatch(header)
+  shape := npyShape.FindSubmatch(header)
+  if descr == nil || shape == nil {
+    return nil, 0, 0, 0, fmt.Errorf("invalid npy header: %s", strings.TrimSpace(string(header)))
+  }
+  var order binary.ByteOrder = binary.LittleEndian
+  if descr[1][0] == '>' {
+    order = binary.BigEndian
+  }
+  size, _ := strconv.Atoi(string(descr[3]))
+  length  := 1
+  dims    := 0
+  for _, field := range strings.Split(string(shape[1]), ",") {
+    if field = strings.TrimSpace(field); field == "" {
+      continue
+    }
+    d, err := strconv.Atoi(field); if err != nil || d < 0 {
+      return nil, 0, 0, 0, fmt.Errorf("invalid npy shape: (%s)", shape[1])
+    }
+    if d != 1 {
+      dims++
+    }
+    if d > 0 && length > math.MaxInt/d {
+      return nil, 0, 0, 0, fmt.Errorf("array with shape (%s) is too large", shape[1])
+    }
+    length *= d
+  }
+  if dims > 1 {
+    return nil, 0, 0, 0, fmt.Errorf("array with shape (%s) is not one-dimensional", shape[1])
+  }
+  if size < 1 {
+    return nil, 0, 0, 0, fmt.Errorf("unsupported npy data type: %s", descr[0])
+  }
+  if length > math.MaxInt/size {
+    return nil, 0, 0, 0, fmt.Errorf("array with shape (%s) is too large", shape[1])
+  }
+  return order, descr[2][0], size, length, nil
+}
+
+// Read a one-dimensional NumPy array of floating point numbers, integers or
+// booleans from a .npy file.
+func ReadNpy(reader io.Reader) ([]float64, error) {
+  order, kind, size, n, err := readNpyHeader(reader); if err != nil {
+    return nil, err
+  }
+  var decode func(b []byte) float64
+  switch {
+  case kind == 'f' && size == 4: decode = func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }
+  case kind == 'f' && size == 8: decode = func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }
+  case kind == 'i' && size == 1: decode = func(b []byte) float64 { return float64(int8(b[0])) }
+  case kind == 'i' && size == 2: decode = func(b []byte) float64 { return float64(int16(order.Uint16(b))) }
+  case kind == 'i' && size == 4: decode = func(b []byte) float64 { return float64(int32(order.Uint32(b))) }
+  case kind == 'i' && size == 8: decode = func(b []byte) float64 { return float64(int64(order.Uint64(b))) }
+  case kind == 'u' && size == 1: decode = func(b []byte) float64 { return float64(b[0]) }
+  case kind == 'u' && size == 2: decode = func(b []byte) float64 { return float64(order.Uint16(b)) }
+  case kind == 'u' && size == 4: decode = func(b []byte) float64 { return float64(order.Uint32(b)) }
+  case kind == 'u' && size == 8: decode = func(b []byte) float64 { return float64(order.Uint64(b)) }
+  case kind == 'b' && size == 1:
+    decode = func(b []byte) float64 {
+      if b[0] != 0 {
+        return 1.0
+      }
+      return 0.0
+    }
+  default:
+    return nil, fmt.Errorf("unsupported npy data type: %c%d", kind, size)
+  }
+  // do not trust the shape given in the header for allocating memory, the
+  // buffer only grows with the data actually present
+  data, err := io.ReadAll(io.LimitReader(reader, int64(n)*int64(size))); if err != nil {
+    return nil, fmt.Errorf("reading npy data failed: %v", err)
+  }
+  if len(data) != n*size {
+    return nil, fmt.Errorf("reading npy data failed: expected %d bytes but found %d", n*size, len(data))
+  }
+  r := make([]float64, n)
+  for i := range r {
+    r[i] = decode(data[i*size:(i+1)*size])
+  }
+  return r, nil
+}
+
+// Read a one-dimensional NumPy array from an .npz archive. If name is empty,
+// the archive must contain a single array.
+func ReadNpz(reader io.ReaderAt, size int64, name string) ([]float64, error) {
+  archive, err := zip.NewReader(reader, size); if err != nil {
+    return nil, err
+  }
+  var file *zip.File
+  for _, f := range archive.File {
+    if name == "" && len(archive.File) == 1 || strings.TrimSuffix(f.Name, ".npy") == name {
+      file = f
+    }
+  }
+  if file == nil {
+    names := []string{}
+    for _, f := range archive.File {
+      names = append(names, strings.TrimSuffix(f.Name, ".npy"))
+    }
+    if name == "" {
+      return nil, fmt.Errorf("archive contains several arrays, select one of: %s", strings.Join(names, ", "))
+    }
+    return nil, fmt.Errorf("no array called `%s' found, select one of: %s", name, strings.Join(names, ", "))
+  }
+  f, err := file.Open(); if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+  return ReadNpy(f)
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Prediction table from separate arrays of predictions and labels, e.g. read
+// from NumPy files. If threshold is NaN, labels must be 0 or 1. Otherwise,
+// labels are graded relevance values as in ReadGradedPredictionTable.
+func NewPredictionTable(values, labels []float64, threshold float64) (PredictionTable, error) {
+  if len(values) != len(labels) {
+    return PredictionTable{}, fmt.Errorf("number of predictions (%d) and labels (%d) do not match", len(values), len(labels))
+  }
+  r := PredictionTable{}
+  r.Values  = values
+  r.Labels  = make([]int, len(labels))
+  r.Columns = make(map[string][]string)
+  if !math.IsNaN(threshold) {
+    r.Relevance = labels
+  }
+  for i, label := range labels {
+    if math.IsNaN(values[i]) {
+      return PredictionTable{}, fmt.Errorf("prediction %d: prediction is not a number", i+1)
+    }
+    if math.IsNaN(threshold) {
+      if label != 0.0 && label != 1.0 {
+        return PredictionTable{}, fmt.Errorf("label %d: labels must be 0 or 1", i+1)
+      }
+      r.Labels[i] = int(label)
+    } else {
+      if label < 0.0 || math.IsNaN(label) || math.IsInf(label, 1) {
+        return PredictionTable{}, fmt.Errorf("label %d: relevance must be a non-negative number", i+1)
+      }
+      if label >= threshold {
+        r.Labels[i] = 1
+      }
+    }
+  }
+  return r, nil
+}
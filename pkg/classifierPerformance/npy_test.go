@@ -0,0 +1,97 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "encoding/binary"
+import   "math"
+import   "reflect"
+import   "strings"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+// Version 1.0 .npy file with the given header dictionary and data
+func newNpy(dict string, data []byte) []byte {
+  header := dict + strings.Repeat(" ", 15 - (len(dict) + 10) % 16) + "\n"
+  buffer := bytes.NewBuffer([]byte("\x93NUMPY\x01\x00"))
+  binary.Write(buffer, binary.LittleEndian, uint16(len(header)))
+  buffer.WriteString(header)
+  buffer.Write(data)
+  return buffer.Bytes()
+}
+
+func TestReadNpy(t *testing.T) {
+  data := new(bytes.Buffer)
+  binary.Write(data, binary.LittleEndian, []float64{0.5, -1.0, math.Inf(1)})
+  r, err := ReadNpy(bytes.NewReader(newNpy("{'descr': '<f8', 'fortran_order': False, 'shape': (3,), }", data.Bytes()))); if err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(r, []float64{0.5, -1.0, math.Inf(1)}) {
+    t.Errorf("invalid data: %v", r)
+  }
+}
+
+func TestReadNpyIntegers(t *testing.T) {
+  data := new(bytes.Buffer)
+  binary.Write(data, binary.BigEndian, []int16{-2, 0, 7})
+  r, err := ReadNpy(bytes.NewReader(newNpy("{'descr': '>i2', 'fortran_order': False, 'shape': (3, 1), }", data.Bytes()))); if err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(r, []float64{-2, 0, 7}) {
+    t.Errorf("invalid data: %v", r)
+  }
+}
+
+func TestReadNpyInvalid(t *testing.T) {
+  data := make([]byte, 16)
+  for _, dict := range []string{
+    // negative dimensions
+    "{'descr': '<f8', 'fortran_order': False, 'shape': (-2,), }",
+    "{'descr': '<f8', 'fortran_order': False, 'shape': (2, -1), }",
+    // not one-dimensional
+    "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 2), }",
+    // overflow of the number of elements
+    "{'descr': '<f8', 'fortran_order': False, 'shape': (4294967296, 4294967296), }",
+    // unsupported data types
+    "{'descr': '<f0', 'fortran_order': False, 'shape': (2,), }",
+    "{'descr': '<c16', 'fortran_order': False, 'shape': (1,), }",
+  } {
+    if _, err := ReadNpy(bytes.NewReader(newNpy(dict, data))); err == nil {
+      t.Errorf("invalid header not rejected: %s", dict)
+    }
+  }
+}
+
+func TestReadNpyTruncated(t *testing.T) {
+  // header announces more elements than present
+  data := make([]byte, 12)
+  if _, err := ReadNpy(bytes.NewReader(newNpy("{'descr': '<f8', 'fortran_order': False, 'shape': (2,), }", data))); err == nil {
+    t.Error("truncated data not rejected")
+  }
+  // a huge shape must not allocate memory for data that is not present
+  if _, err := ReadNpy(bytes.NewReader(newNpy("{'descr': '<f8', 'fortran_order': False, 'shape': (1000000000000,), }", data))); err == nil {
+    t.Error("truncated data not rejected")
+  }
+  // truncated header
+  if _, err := ReadNpy(bytes.NewReader([]byte("\x93NUMPY\x01\x00\xff\x00{'descr'"))); err == nil {
+    t.Error("truncated header not rejected")
+  }
+}
@@ -0,0 +1,56 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "strings"
+
+/* -------------------------------------------------------------------------- */
+
+// ParseError is returned when a prediction table cannot be parsed. It records
+// the line number (starting at 1), the name of the column and the value of
+// the offending field (if applicable), and the raw text of the line.
+type ParseError struct {
+  Line   int
+  Column string
+  Field  string
+  Text   string
+  Err    error
+}
+
+func newParseError(line int, column string, field, text []byte, err error) *ParseError {
+  return &ParseError{
+    Line  : line,
+    Column: column,
+    Field : string(field),
+    Text  : strings.TrimRight(string(text), "\r\n"),
+    Err   : err }
+}
+
+func (obj *ParseError) Error() string {
+  if obj.Column != "" {
+    return fmt.Sprintf("line %d: invalid value `%s' in column `%s' (%v)", obj.Line, obj.Field, obj.Column, obj.Err)
+  } else {
+    return fmt.Sprintf("line %d: %v", obj.Line, obj.Err)
+  }
+}
+
+func (obj *ParseError) Unwrap() error {
+  return obj.Err
+}
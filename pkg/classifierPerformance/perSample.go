@@ -0,0 +1,102 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Predicted probabilities are clipped to [eps, 1-eps] when computing the
+// log-loss so that confidently wrong predictions have a finite loss.
+const logLossEpsilon = 1e-15
+
+// SampleLoss is the contribution of a single prediction to the log-loss and
+// the Brier score. Index is the position of the prediction in the input and
+// Rank its position when predictions are sorted by decreasing loss.
+type SampleLoss struct {
+  Index   int
+  Rank    int
+  Value   float64
+  Label   int
+  LogLoss float64
+  Brier   float64
+}
+
+// Log-loss and Brier contribution of each prediction, sorted by decreasing
+// log-loss. Ties are broken by the order of predictions. Predictions must
+// be probabilities.
+func PerSampleLoss(values []float64, labels []int) ([]SampleLoss, error) {
+  if len(values) != len(labels) {
+    return nil, fmt.Errorf("number of predictions and labels do not match")
+  }
+  r := make([]SampleLoss, len(values))
+  for i := range values {
+    if labels[i] != 0 && labels[i] != 1 {
+      return nil, fmt.Errorf("invalid label: %d", labels[i])
+    }
+    if !(values[i] >= 0.0 && values[i] <= 1.0) {
+      return nil, fmt.Errorf("prediction `%v' is not a probability", values[i])
+    }
+    p := math.Min(math.Max(values[i], logLossEpsilon), 1.0 - logLossEpsilon)
+    if labels[i] == 0 {
+      p = 1.0 - p
+    }
+    d := values[i] - float64(labels[i])
+    r[i] = SampleLoss{Index: i, Value: values[i], Label: labels[i], LogLoss: -math.Log(p), Brier: d*d}
+  }
+  sort.SliceStable(r, func(i, j int) bool { return r[i].LogLoss > r[j].LogLoss })
+  for i := range r {
+    r[i].Rank = i+1
+  }
+  return r, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Indices of the n highest scoring negatives and the n lowest scoring
+// positives, i.e. the most confident errors. Ties are broken by the order
+// of predictions. If threshold is not NaN, only negatives predicted as
+// positive (value > threshold) and positives predicted as negative are
+// returned.
+func HardestErrors(values []float64, labels []int, n int, threshold float64) ([]int, []int, error) {
+  if len(values) != len(labels) {
+    return nil, nil, fmt.Errorf("number of predictions and labels do not match")
+  }
+  neg := []int{}
+  pos := []int{}
+  for i := range values {
+    switch labels[i] {
+    case 0:
+      if math.IsNaN(threshold) || values[i] > threshold {
+        neg = append(neg, i)
+      }
+    case 1:
+      if math.IsNaN(threshold) || values[i] <= threshold {
+        pos = append(pos, i)
+      }
+    default:
+      return nil, nil, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  sort.SliceStable(neg, func(i, j int) bool { return values[neg[i]] > values[neg[j]] })
+  sort.SliceStable(pos, func(i, j int) bool { return values[pos[i]] < values[pos[j]] })
+  return neg[:min(n, len(neg))], pos[:min(n, len(pos))], nil
+}
@@ -0,0 +1,65 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "math/rand"
+
+/* -------------------------------------------------------------------------- */
+
+// Draw n noisy replicates of the predictions and evaluate f on each of them.
+// Gaussian noise with standard deviation sd is added to all predictions and
+// labels of a randomly selected fraction flip of predictions are flipped. The
+// result of replicate i is stored in the i-th row. Arguments passed to f are
+// reused between replicates and may be modified by f.
+func Perturb(values []float64, labels []int, sd, flip float64, n int, rng *rand.Rand, f func(values []float64, labels []int) ([]float64, error)) ([][]float64, error) {
+  if !(sd >= 0.0) || math.IsInf(sd, 1) {
+    return nil, fmt.Errorf("invalid noise standard deviation: %f", sd)
+  }
+  if !(flip >= 0.0 && flip <= 1.0) {
+    return nil, fmt.Errorf("invalid fraction of flipped labels: %f", flip)
+  }
+  r := make([][]float64, n)
+  v := make([]float64, len(values))
+  l := make([]int,     len(labels))
+  k := int(math.Round(flip*float64(len(labels))))
+  index := make([]int, len(labels))
+  for i := range index {
+    index[i] = i
+  }
+  for i := 0; i < n; i++ {
+    for j := range v {
+      v[j] = values[j] + sd*rng.NormFloat64()
+      l[j] = labels[j]
+    }
+    // select k predictions by a partial Fisher-Yates shuffle
+    for j := 0; j < k; j++ {
+      m := j + rng.Intn(len(index)-j)
+      index[j], index[m] = index[m], index[j]
+      l[index[j]] = 1 - l[index[j]]
+    }
+    if x, err := f(v, l); err != nil {
+      return nil, err
+    } else {
+      r[i] = x
+    }
+  }
+  return r, nil
+}
@@ -0,0 +1,187 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// RankCorrelation is a rank correlation coefficient of n pairs of
+// predictions with its confidence interval.
+type RankCorrelation struct {
+  Name  string
+  N     int
+  Value float64
+  Lower float64
+  Upper float64
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Ranks of values starting at one, where tied values obtain the average of
+// their ranks
+func midRanks[T Float](values []T) []float64 {
+  index := make([]int, len(values))
+  for i := range index {
+    index[i] = i
+  }
+  sort.Slice(index, func(i, j int) bool { return values[index[i]] < values[index[j]] })
+  r := make([]float64, len(values))
+  for i := 0; i < len(index); {
+    j := i+1
+    for j < len(index) && values[index[j]] == values[index[i]] {
+      j++
+    }
+    for k := i; k < j; k++ {
+      r[index[k]] = float64(i+j+1)/2.0
+    }
+    i = j
+  }
+  return r
+}
+
+// Confidence interval of a correlation coefficient r with standard error se
+// on the scale of Fisher's z transformation
+func fisherInterval(r, se, level float64) (float64, float64) {
+  if math.IsNaN(se) || math.Abs(r) >= 1.0 {
+    return math.NaN(), math.NaN()
+  }
+  z := math.Atanh(r)
+  c := normalQuantile(0.5 + level/2.0)
+  return math.Tanh(z - c*se), math.Tanh(z + c*se)
+}
+
+func checkRankCorrelation[T Float](x, y []T, level float64) error {
+  if len(x) != len(y) {
+    return fmt.Errorf("number of predictions do not match")
+  }
+  if len(x) < 2 {
+    return fmt.Errorf("at least two predictions are required")
+  }
+  if level <= 0.0 || level >= 1.0 {
+    return fmt.Errorf("invalid confidence level: %f", level)
+  }
+  return nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Spearman's rank correlation coefficient of two sets of predictions, i.e.
+// the Pearson correlation of their mid-ranks. The confidence interval uses
+// Fisher's z transformation with the standard error of Bonett and Wright
+// (2000), which requires at least four predictions.
+func Spearman[T Float](x, y []T, level float64) (RankCorrelation, error) {
+  if err := checkRankCorrelation(x, y, level); err != nil {
+    return RankCorrelation{}, err
+  }
+  rx := midRanks(x)
+  ry := midRanks(y)
+  m  := float64(len(x)+1)/2.0
+  sxy, sxx, syy := 0.0, 0.0, 0.0
+  for i := range rx {
+    sxy += (rx[i] - m)*(ry[i] - m)
+    sxx += (rx[i] - m)*(rx[i] - m)
+    syy += (ry[i] - m)*(ry[i] - m)
+  }
+  r  := RankCorrelation{Name: "spearman", N: len(x), Value: sxy/math.Sqrt(sxx*syy)}
+  se := math.NaN()
+  if n := float64(len(x)); n > 3 {
+    se = math.Sqrt((1.0 + r.Value*r.Value/2.0)/(n - 3.0))
+  }
+  r.Lower, r.Upper = fisherInterval(r.Value, se, level)
+  return r, nil
+}
+
+// Sort x in place and return the number of inversions, i.e. pairs i < j with
+// x[i] > x[j]
+func mergeSortInversions(x, buffer []float64) int {
+  if len(x) < 2 {
+    return 0
+  }
+  m := len(x)/2
+  r := mergeSortInversions(x[:m], buffer[:m]) + mergeSortInversions(x[m:], buffer[m:])
+  i, j, k := 0, m, 0
+  for i < m && j < len(x) {
+    if x[j] < x[i] {
+      buffer[k] = x[j]; j++
+      r += m - i
+    } else {
+      buffer[k] = x[i]; i++
+    }
+    k++
+  }
+  k += copy(buffer[k:], x[i:m])
+  copy(buffer[k:], x[j:])
+  copy(x, buffer[:len(x)])
+  return r
+}
+
+// Number of pairs with tied values, where x must be sorted
+func tiedPairs(x []float64, equal func(i, j int) bool) int {
+  r := 0
+  for i := 0; i < len(x); {
+    j := i+1
+    for j < len(x) && equal(i, j) {
+      j++
+    }
+    r += (j-i)*(j-i-1)/2
+    i  = j
+  }
+  return r
+}
+
+// Kendall's tau-b rank correlation coefficient of two sets of predictions,
+// computed in O(n log n) time (Knight, 1966). The confidence interval uses
+// Fisher's z transformation with the standard error of Fieller, Hartley and
+// Pearson (1957), which requires at least five predictions.
+func Kendall[T Float](x, y []T, level float64) (RankCorrelation, error) {
+  if err := checkRankCorrelation(x, y, level); err != nil {
+    return RankCorrelation{}, err
+  }
+  n     := len(x)
+  index := make([]int, n)
+  for i := range index {
+    index[i] = i
+  }
+  sort.Slice(index, func(i, j int) bool {
+    if x[index[i]] != x[index[j]] {
+      return x[index[i]] < x[index[j]]
+    }
+    return y[index[i]] < y[index[j]]
+  })
+  xs := make([]float64, n)
+  ys := make([]float64, n)
+  for i, k := range index {
+    xs[i] = float64(x[k])
+    ys[i] = float64(y[k])
+  }
+  n0 := n*(n-1)/2
+  n1 := tiedPairs(xs, func(i, j int) bool { return xs[i] == xs[j] })
+  n3 := tiedPairs(xs, func(i, j int) bool { return xs[i] == xs[j] && ys[i] == ys[j] })
+  swaps := mergeSortInversions(ys, make([]float64, n))
+  n2 := tiedPairs(ys, func(i, j int) bool { return ys[i] == ys[j] })
+  r  := RankCorrelation{Name: "kendall", N: n}
+  r.Value = float64(n0 - n1 - n2 + n3 - 2*swaps)/math.Sqrt(float64(n0 - n1)*float64(n0 - n2))
+  se := math.NaN()
+  if n > 4 {
+    se = math.Sqrt(0.437/float64(n - 4))
+  }
+  r.Lower, r.Upper = fisherInterval(r.Value, se, level)
+  return r, nil
+}
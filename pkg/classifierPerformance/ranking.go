@@ -0,0 +1,230 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Indices of predictions sorted by decreasing value, where ties are broken
+// by the order of predictions
+func rankingOrder[T Float](values []T) []int {
+  index := make([]int, len(values))
+  for i := range index {
+    index[i] = i
+  }
+  sort.SliceStable(index, func(i, j int) bool { return values[index[i]] > values[index[j]] })
+  return index
+}
+
+// Number of ranks considered at cutoff k, where k <= 0 selects all ranks
+func rankingCutoff(k, n int) int {
+  if k <= 0 || k > n {
+    return n
+  }
+  return k
+}
+
+func dcg(relevance []float64, index []int, k int) float64 {
+  r := 0.0
+  for i := 0; i < rankingCutoff(k, len(index)); i++ {
+    r += (math.Pow(2.0, relevance[index[i]]) - 1.0)/math.Log2(float64(i) + 2.0)
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Discounted cumulative gain of the k top ranked predictions with gains
+// 2^rel - 1, where predictions are ranked by decreasing value and ties are
+// broken by the order of predictions. If k <= 0, all predictions are
+// considered.
+func DCG[T Float](values []T, relevance []float64, k int) (float64, error) {
+  if len(values) != len(relevance) {
+    return 0.0, fmt.Errorf("number of predictions and relevance values do not match")
+  }
+  return dcg(relevance, rankingOrder(values), k), nil
+}
+
+// Normalized discounted cumulative gain, i.e. the DCG of the k top ranked
+// predictions divided by the DCG of the ideal ranking. The result is NaN if
+// no prediction is relevant.
+func NDCG[T Float](values []T, relevance []float64, k int) (float64, error) {
+  if len(values) != len(relevance) {
+    return 0.0, fmt.Errorf("number of predictions and relevance values do not match")
+  }
+  for _, v := range relevance {
+    if v < 0.0 || math.IsNaN(v) {
+      return 0.0, fmt.Errorf("invalid relevance: %f", v)
+    }
+  }
+  ideal := dcg(relevance, rankingOrder(relevance), k)
+  if ideal == 0.0 {
+    return math.NaN(), nil
+  }
+  return dcg(relevance, rankingOrder(values), k)/ideal, nil
+}
+
+// Expected reciprocal rank of the k top ranked predictions (Chapelle et al.,
+// 2009) under a cascade model, in which a user stops at a prediction with
+// probability (2^rel - 1)/2^maxRelevance, where maxRelevance is the maximum
+// relevance of the scale. Predictions are ranked by decreasing value and
+// ties are broken by the order of predictions. If k <= 0, all predictions
+// are considered.
+func ERR[T Float](values []T, relevance []float64, k int, maxRelevance float64) (float64, error) {
+  if len(values) != len(relevance) {
+    return 0.0, fmt.Errorf("number of predictions and relevance values do not match")
+  }
+  for _, v := range relevance {
+    if v < 0.0 || v > maxRelevance || math.IsNaN(v) {
+      return 0.0, fmt.Errorf("invalid relevance: %f", v)
+    }
+  }
+  index := rankingOrder(values)
+  r := 0.0
+  p := 1.0
+  for i := 0; i < rankingCutoff(k, len(index)); i++ {
+    stop := (math.Pow(2.0, relevance[index[i]]) - 1.0)/math.Pow(2.0, maxRelevance)
+    r += p*stop/float64(i+1)
+    p *= 1.0 - stop
+  }
+  return r, nil
+}
+
+func checkBinaryLabels[T Float](values []T, labels []int) error {
+  if len(values) != len(labels) {
+    return fmt.Errorf("number of predictions and labels do not match")
+  }
+  for _, l := range labels {
+    if l != 0 && l != 1 {
+      return fmt.Errorf("invalid label: %d", l)
+    }
+  }
+  return nil
+}
+
+// Average precision of a ranking, i.e. the mean of the precision at the rank
+// of each positive prediction. Predictions are ranked by decreasing value
+// and ties are broken by the order of predictions. The result is NaN if no
+// prediction is positive.
+func AveragePrecision[T Float](values []T, labels []int) (float64, error) {
+  if err := checkBinaryLabels(values, labels); err != nil {
+    return 0.0, err
+  }
+  r, n := 0.0, 0
+  for i, j := range rankingOrder(values) {
+    if labels[j] == 1 {
+      n++
+      r += float64(n)/float64(i+1)
+    }
+  }
+  if n == 0 {
+    return math.NaN(), nil
+  }
+  return r/float64(n), nil
+}
+
+// Reciprocal rank of the first positive prediction, where predictions are
+// ranked by decreasing value and ties are broken by the order of
+// predictions. The result is NaN if no prediction is positive.
+func ReciprocalRank[T Float](values []T, labels []int) (float64, error) {
+  if err := checkBinaryLabels(values, labels); err != nil {
+    return 0.0, err
+  }
+  for i, j := range rankingOrder(values) {
+    if labels[j] == 1 {
+      return 1.0/float64(i+1), nil
+    }
+  }
+  return math.NaN(), nil
+}
+
+// Hit indicator of a ranking, which is one if at least one of the k top
+// ranked predictions is positive and zero otherwise. Predictions are ranked
+// by decreasing value and ties are broken by the order of predictions. The
+// result is NaN if no prediction is positive.
+func HitAtK[T Float](values []T, labels []int, k int) (float64, error) {
+  if err := checkBinaryLabels(values, labels); err != nil {
+    return 0.0, err
+  }
+  n := 0
+  for _, l := range labels {
+    n += l
+  }
+  if n == 0 {
+    return math.NaN(), nil
+  }
+  index := rankingOrder(values)
+  for i := 0; i < rankingCutoff(k, len(index)); i++ {
+    if labels[index[i]] == 1 {
+      return 1.0, nil
+    }
+  }
+  return 0.0, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Overlap between the k top ranked predictions of two models on the same
+// population for each of the given cutoffs, which must be sorted in
+// increasing order. If no cutoffs are given, all cutoffs k = 1, ..., n are
+// used. Ties are broken by the order of predictions. The table reports the
+// number of shared predictions, the Jaccard index and the overlap
+// coefficient, i.e. the fraction of shared predictions.
+func TopKOverlap[T Float](x, y []T, cutoffs []int) (Table, error) {
+  if len(x) != len(y) {
+    return Table{}, fmt.Errorf("number of predictions do not match")
+  }
+  n := len(x)
+  if cutoffs == nil {
+    cutoffs = make([]int, n)
+    for i := range cutoffs {
+      cutoffs[i] = i+1
+    }
+  }
+  r := Table{Names: []string{"k", "shared", "jaccard", "overlap"}, Columns: make([][]float64, 4)}
+  ix := rankingOrder(x)
+  iy := rankingOrder(y)
+  // 1: in top ranks of x, 2: in top ranks of y
+  in := make([]int, n)
+  shared := 0
+  j := 0
+  for _, k := range cutoffs {
+    if k < 1 {
+      return Table{}, fmt.Errorf("invalid cutoff: %d", k)
+    }
+    for ; j < min(k, n); j++ {
+      if in[ix[j]] |= 1; in[ix[j]] == 3 {
+        shared++
+      }
+      if in[iy[j]] |= 2; in[iy[j]] == 3 {
+        shared++
+      }
+    }
+    m := float64(min(k, n))
+    r.Columns[0] = append(r.Columns[0], float64(k))
+    r.Columns[1] = append(r.Columns[1], float64(shared))
+    r.Columns[2] = append(r.Columns[2], float64(shared)/(2.0*m - float64(shared)))
+    r.Columns[3] = append(r.Columns[3], float64(shared)/m)
+  }
+  return r, nil
+}
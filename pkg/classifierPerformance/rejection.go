@@ -0,0 +1,103 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Accuracy, F1 score and confusion matrix of the retained predictions as a
+// function of the fraction of least confident predictions that are
+// rejected (e.g. deferred to human review). Predictions are classified as
+// positive if their value is larger than the threshold. The confidence of a
+// prediction is either its distance to the threshold (rejection "margin") or
+// its value (rejection "score"). Predictions with equal confidence are
+// rejected together.
+func AccuracyRejection[T Float](values []T, labels []int, threshold float64, rejection string) (Table, error) {
+  if len(values) != len(labels) {
+    return Table{}, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if math.IsNaN(threshold) {
+    return Table{}, fmt.Errorf("invalid threshold: NaN")
+  }
+  n := len(values)
+  confidence := make([]float64, n)
+  for i, v := range values {
+    if math.IsNaN(float64(v)) {
+      return Table{}, fmt.Errorf("invalid prediction: NaN")
+    }
+    switch rejection {
+    case "", "margin":
+      confidence[i] = math.Abs(float64(v) - threshold)
+    case "score":
+      confidence[i] = float64(v)
+    default:
+      return Table{}, fmt.Errorf("invalid rejection method: %s", rejection)
+    }
+  }
+  // least confident predictions first
+  index := make([]int, n)
+  for i := range index {
+    index[i] = i
+  }
+  sort.SliceStable(index, func(i, j int) bool { return confidence[index[i]] < confidence[index[j]] })
+
+  c := ConfusionMatrix{}
+  for i := range values {
+    switch {
+    case labels[i] == 1 && float64(values[i]) >  threshold: c.Tp++
+    case labels[i] == 1 && float64(values[i]) <= threshold: c.Fn++
+    case labels[i] == 0 && float64(values[i]) >  threshold: c.Fp++
+    case labels[i] == 0 && float64(values[i]) <= threshold: c.Tn++
+    default:
+      return Table{}, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  r := Table{Names: []string{"rejected", "accuracy", "f1", "retained", "tp", "fp", "tn", "fn", "confidence"}, Columns: make([][]float64, 9)}
+  // the confidence column gives the smallest confidence of retained
+  // predictions
+  for k := 0; k < n; {
+    r.Columns[0] = append(r.Columns[0], float64(k)/float64(n))
+    r.Columns[1] = append(r.Columns[1], c.Accuracy())
+    r.Columns[2] = append(r.Columns[2], 2.0*float64(c.Tp)/float64(2*c.Tp + c.Fp + c.Fn))
+    r.Columns[3] = append(r.Columns[3], float64(n-k))
+    r.Columns[4] = append(r.Columns[4], float64(c.Tp))
+    r.Columns[5] = append(r.Columns[5], float64(c.Fp))
+    r.Columns[6] = append(r.Columns[6], float64(c.Tn))
+    r.Columns[7] = append(r.Columns[7], float64(c.Fn))
+    r.Columns[8] = append(r.Columns[8], confidence[index[k]])
+    // reject the next block of predictions with equal confidence
+    j := k+1
+    for j < n && confidence[index[j]] == confidence[index[k]] {
+      j++
+    }
+    for ; k < j; k++ {
+      i := index[k]
+      switch {
+      case labels[i] == 1 && float64(values[i]) >  threshold: c.Tp--
+      case labels[i] == 1 && float64(values[i]) <= threshold: c.Fn--
+      case labels[i] == 0 && float64(values[i]) >  threshold: c.Fp--
+      case labels[i] == 0 && float64(values[i]) <= threshold: c.Tn--
+      }
+    }
+  }
+  return r, nil
+}
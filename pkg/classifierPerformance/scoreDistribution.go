@@ -0,0 +1,288 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// ScoreStatistics summarizes the scores of a single class.
+type ScoreStatistics struct {
+  N      int
+  Mean   float64
+  Sd     float64
+  Min    float64
+  Q05    float64
+  Q25    float64
+  Median float64
+  Q75    float64
+  Q95    float64
+  Max    float64
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Split scores by label into sorted scores of positives and negatives.
+func SplitScores[T Float](values []T, labels []int) ([]float64, []float64, error) {
+  if len(values) != len(labels) {
+    return nil, nil, fmt.Errorf("number of predictions and labels do not match")
+  }
+  pos := []float64{}
+  neg := []float64{}
+  for i := range values {
+    switch labels[i] {
+    case 1: pos = append(pos, float64(values[i]))
+    case 0: neg = append(neg, float64(values[i]))
+    default:
+      return nil, nil, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  sort.Float64s(pos)
+  sort.Float64s(neg)
+  return pos, neg, nil
+}
+
+// Summary statistics of sorted scores, where quantiles are interpolated
+// linearly.
+func ScoreStats(x []float64) ScoreStatistics {
+  r := ScoreStatistics{N: len(x)}
+  r.Mean, r.Sd = MeanSd(x)
+  r.Min    = Quantile(x, 0.00)
+  r.Q05    = Quantile(x, 0.05)
+  r.Q25    = Quantile(x, 0.25)
+  r.Median = Quantile(x, 0.50)
+  r.Q75    = Quantile(x, 0.75)
+  r.Q95    = Quantile(x, 0.95)
+  r.Max    = Quantile(x, 1.00)
+  return r
+}
+
+// Cohen's d, i.e. the difference of mean scores of positives and negatives
+// in units of the pooled standard deviation.
+func CohensD(pos, neg []float64) float64 {
+  n1, n0 := float64(len(pos)), float64(len(neg))
+  if n1 + n0 <= 2 {
+    return math.NaN()
+  }
+  m1, s1 := MeanSd(pos)
+  m0, s0 := MeanSd(neg)
+  return (m1 - m0)/math.Sqrt(((n1-1)*s1*s1 + (n0-1)*s0*s0)/(n1 + n0 - 2))
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Partition predictions into n bins of equal width that span the range of
+// values, ordered by increasing value. Unlike QuantileBins, bins may be
+// empty. All values must be finite.
+func ClassHistogram[T Float](values []T, labels []int, n int) ([]ScoreBin, error) {
+  if len(values) != len(labels) {
+    return nil, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if n < 1 {
+    return nil, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  if len(values) == 0 {
+    return nil, fmt.Errorf("no predictions given")
+  }
+  min, max := float64(values[0]), float64(values[0])
+  for _, v := range values {
+    if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+      return nil, fmt.Errorf("invalid prediction: %f", float64(v))
+    }
+    min = math.Min(min, float64(v))
+    max = math.Max(max, float64(v))
+  }
+  r := make([]ScoreBin, n)
+  for i := range r {
+    r[i].Min = min + float64(i  )*(max-min)/float64(n)
+    r[i].Max = min + float64(i+1)*(max-min)/float64(n)
+  }
+  r[n-1].Max = max
+  for i, v := range values {
+    // the largest value falls into the last bin
+    k := n-1
+    if max > min {
+      k = int(math.Min(math.Floor((float64(v) - min)/(max - min)*float64(n)), float64(n-1)))
+    }
+    switch labels[i] {
+    case 1: r[k].Positives++
+    case 0: r[k].Negatives++
+    default:
+      return nil, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  return r, nil
+}
+
+// Overlap coefficient of the score distributions of positives and negatives,
+// i.e. the sum over bins of the minimum of both relative frequencies.
+func OverlapCoefficient(bins []ScoreBin) float64 {
+  p, n := 0, 0
+  for _, bin := range bins {
+    p += bin.Positives
+    n += bin.Negatives
+  }
+  r := 0.0
+  for _, bin := range bins {
+    r += math.Min(float64(bin.Positives)/float64(p), float64(bin.Negatives)/float64(n))
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Relative frequencies of positives and negatives in each bin, where a
+// pseudocount is added to the counts of all bins.
+func HistogramDistributions(bins []ScoreBin, pseudocount float64) ([]float64, []float64) {
+  p := make([]float64, len(bins))
+  q := make([]float64, len(bins))
+  sp, sq := 0.0, 0.0
+  for i, bin := range bins {
+    p[i] = float64(bin.Positives) + pseudocount; sp += p[i]
+    q[i] = float64(bin.Negatives) + pseudocount; sq += q[i]
+  }
+  for i := range bins {
+    p[i] /= sp
+    q[i] /= sq
+  }
+  return p, q
+}
+
+// Gaussian kernel density estimates of the scores of positives and negatives
+// discretized at n equally spaced points, which cover the support of both
+// densities. If the bandwidth is zero, a separate bandwidth is estimated for
+// each class with Silverman's rule of thumb. Returns the points and the
+// probabilities of both classes at each point.
+func KernelDistributions[T Float](values []T, labels []int, bandwidth float64, n int) ([]float64, []float64, []float64, error) {
+  if bandwidth < 0.0 || math.IsNaN(bandwidth) {
+    return nil, nil, nil, fmt.Errorf("invalid bandwidth: %f", bandwidth)
+  }
+  if n < 2 {
+    return nil, nil, nil, fmt.Errorf("invalid number of points: %d", n)
+  }
+  pos, neg, err := SplitScores(values, labels); if err != nil {
+    return nil, nil, nil, err
+  }
+  if len(pos) == 0 || len(neg) == 0 {
+    return nil, nil, nil, fmt.Errorf("kernel density estimates require positive and negative predictions")
+  }
+  h_pos, h_neg, err := classBandwidths(pos, neg, bandwidth); if err != nil {
+    return nil, nil, nil, err
+  }
+  density := func(x []float64, h, t float64) float64 {
+    r := 0.0
+    for _, v := range x {
+      z := (t - v)/h
+      r += math.Exp(-0.5*z*z)
+    }
+    return r/(float64(len(x))*h*math.Sqrt(2.0*math.Pi))
+  }
+  h  := math.Max(h_pos, h_neg)
+  t0 := math.Min(pos[0], neg[0]) - 5.0*h
+  t1 := math.Max(pos[len(pos)-1], neg[len(neg)-1]) + 5.0*h
+  t  := make([]float64, n)
+  p  := make([]float64, n)
+  q  := make([]float64, n)
+  sp, sq := 0.0, 0.0
+  for i := 0; i < n; i++ {
+    t[i] = t0 + float64(i)*(t1 - t0)/float64(n-1)
+    p[i] = density(pos, h_pos, t[i]); sp += p[i]
+    q[i] = density(neg, h_neg, t[i]); sq += q[i]
+  }
+  for i := range p {
+    p[i] /= sp
+    q[i] /= sq
+  }
+  return t, p, q, nil
+}
+
+// Histogram of the scores of positives and negatives, where the column
+// selected marks the bin that contains the given threshold.
+func HistogramTable(bins []ScoreBin, threshold float64) Table {
+  r := Table{Names: []string{"min", "max", "positives", "negatives", "selected"}, Columns: make([][]float64, 5)}
+  for i, bin := range bins {
+    selected := 0.0
+    if (i == 0 || threshold > bin.Min) && (i == len(bins)-1 || threshold <= bin.Max) {
+      selected = 1.0
+    }
+    r.Columns[0] = append(r.Columns[0], bin.Min)
+    r.Columns[1] = append(r.Columns[1], bin.Max)
+    r.Columns[2] = append(r.Columns[2], float64(bin.Positives))
+    r.Columns[3] = append(r.Columns[3], float64(bin.Negatives))
+    r.Columns[4] = append(r.Columns[4], selected)
+  }
+  return r
+}
+
+// Kernel density estimates of the scores of positives and negatives at
+// equally spaced points, where the column selected marks the point closest
+// to the given threshold.
+func DensityTable(t, p, q []float64, threshold float64) Table {
+  r := Table{Names: []string{"score", "positives", "negatives", "selected"}, Columns: make([][]float64, 4)}
+  dt := (t[len(t)-1] - t[0])/float64(len(t)-1)
+  k  := int(math.Round((threshold - t[0])/dt))
+  for i := range t {
+    selected := 0.0
+    if i == k {
+      selected = 1.0
+    }
+    r.Columns[0] = append(r.Columns[0], t[i])
+    r.Columns[1] = append(r.Columns[1], p[i]/dt)
+    r.Columns[2] = append(r.Columns[2], q[i]/dt)
+    r.Columns[3] = append(r.Columns[3], selected)
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Kullback-Leibler divergence KL(p||q) of discrete distributions (natural
+// logarithm), which is infinite if q is zero where p is positive.
+func KLDivergence(p, q []float64) float64 {
+  r := 0.0
+  for i := range p {
+    if p[i] > 0.0 {
+      r += p[i]*math.Log(p[i]/q[i])
+    }
+  }
+  return r
+}
+
+// Jensen-Shannon divergence of discrete distributions (natural logarithm),
+// i.e. the mean Kullback-Leibler divergence of p and q from their mixture.
+// The divergence is bounded by log(2).
+func JSDivergence(p, q []float64) float64 {
+  m := make([]float64, len(p))
+  for i := range p {
+    m[i] = 0.5*(p[i] + q[i])
+  }
+  return 0.5*KLDivergence(p, m) + 0.5*KLDivergence(q, m)
+}
+
+// Bhattacharyya distance of discrete distributions, i.e. the negative
+// logarithm of the Bhattacharyya coefficient.
+func BhattacharyyaDistance(p, q []float64) float64 {
+  r := 0.0
+  for i := range p {
+    r += math.Sqrt(p[i]*q[i])
+  }
+  return -math.Log(r)
+}
@@ -0,0 +1,46 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestClassHistogram(t *testing.T) {
+  bins, err := ClassHistogram([]float64{0.0, 0.2, 0.5, 1.0}, []int{0, 1, 0, 1}, 2); if err != nil {
+    t.Fatal(err)
+  }
+  if len(bins) != 2 {
+    t.Fatalf("expected 2 bins but found %d", len(bins))
+  }
+  // the largest value falls into the last bin
+  if bins[0].Positives != 1 || bins[0].Negatives != 1 || bins[1].Positives != 1 || bins[1].Negatives != 1 {
+    t.Errorf("invalid bins: %+v", bins)
+  }
+}
+
+func TestClassHistogramNonFinite(t *testing.T) {
+  for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+    if _, err := ClassHistogram([]float64{0.1, v, 0.3}, []int{0, 1, 1}, 2); err == nil {
+      t.Errorf("prediction %f not rejected", v)
+    }
+  }
+}
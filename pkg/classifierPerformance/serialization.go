@@ -0,0 +1,179 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "encoding/json"
+import   "fmt"
+import   "math"
+import   "strconv"
+
+/* -------------------------------------------------------------------------- */
+
+// Performance and Curve objects can be (un)marshaled with encoding/json and
+// encoding/gob. For gob the default encoding of exported fields is used.
+
+/* -------------------------------------------------------------------------- */
+
+// JSON does not support non-finite numbers, which are therefore encoded as
+// strings "NaN", "+Inf" and "-Inf".
+type jsonFloats []float64
+
+func writeJsonFloat(b *bytes.Buffer, v float64) {
+  switch {
+  case math.IsNaN(v):
+    b.WriteString(`"NaN"`)
+  case math.IsInf(v,  1):
+    b.WriteString(`"+Inf"`)
+  case math.IsInf(v, -1):
+    b.WriteString(`"-Inf"`)
+  default:
+    b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+  }
+}
+
+func readJsonFloat(v interface{}) (float64, error) {
+  switch x := v.(type) {
+  case float64:
+    return x, nil
+  case string:
+    switch x {
+    case "NaN" : return math.NaN(), nil
+    case "+Inf": return math.Inf( 1), nil
+    case "-Inf": return math.Inf(-1), nil
+    default:
+      return 0.0, fmt.Errorf("invalid value `%s'", x)
+    }
+  default:
+    return 0.0, fmt.Errorf("invalid value `%v'", v)
+  }
+}
+
+func (obj jsonFloats) MarshalJSON() ([]byte, error) {
+  if obj == nil {
+    return []byte("null"), nil
+  }
+  var b bytes.Buffer
+  b.WriteByte('[')
+  for i, v := range obj {
+    if i > 0 {
+      b.WriteByte(',')
+    }
+    writeJsonFloat(&b, v)
+  }
+  b.WriteByte(']')
+  return b.Bytes(), nil
+}
+
+func (obj *jsonFloats) UnmarshalJSON(data []byte) error {
+  values := []interface{}{}
+  if err := json.Unmarshal(data, &values); err != nil {
+    return err
+  }
+  if values == nil {
+    *obj = nil; return nil
+  }
+  r := make([]float64, len(values))
+  for i, v := range values {
+    if x, err := readJsonFloat(v); err != nil {
+      return err
+    } else {
+      r[i] = x
+    }
+  }
+  *obj = r
+  return nil
+}
+
+// JSONFloat is a float64 that is (un)marshaled with the same encoding of
+// non-finite numbers as Performance and Curve objects.
+type JSONFloat float64
+
+func (obj JSONFloat) MarshalJSON() ([]byte, error) {
+  var b bytes.Buffer
+  writeJsonFloat(&b, float64(obj))
+  return b.Bytes(), nil
+}
+
+func (obj *JSONFloat) UnmarshalJSON(data []byte) error {
+  var v interface{}
+  if err := json.Unmarshal(data, &v); err != nil {
+    return err
+  }
+  x, err := readJsonFloat(v); if err != nil {
+    return err
+  }
+  *obj = JSONFloat(x)
+  return nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+type performanceJson struct {
+  Thresholds jsonFloats `json:"thresholds"`
+  Tp         []int      `json:"tp"`
+  Fp         []int      `json:"fp"`
+  Tn         []int      `json:"tn"`
+  Fn         []int      `json:"fn"`
+  P          int        `json:"p"`
+  N          int        `json:"n"`
+}
+
+func (obj Performance) MarshalJSON() ([]byte, error) {
+  return json.Marshal(performanceJson{
+    Thresholds: obj.Tr,
+    Tp: obj.Tp, Fp: obj.Fp, Tn: obj.Tn, Fn: obj.Fn, P: obj.P, N: obj.N })
+}
+
+func (obj *Performance) UnmarshalJSON(data []byte) error {
+  r := performanceJson{}
+  if err := json.Unmarshal(data, &r); err != nil {
+    return err
+  }
+  n := len(r.Thresholds)
+  if len(r.Tp) != n || len(r.Fp) != n || len(r.Tn) != n || len(r.Fn) != n {
+    return fmt.Errorf("invalid performance object: inconsistent number of thresholds")
+  }
+  *obj = Performance{Tr: r.Thresholds, Tp: r.Tp, Fp: r.Fp, Tn: r.Tn, Fn: r.Fn, P: r.P, N: r.N}
+  return nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+type curveJson struct {
+  X          jsonFloats `json:"x"`
+  Y          jsonFloats `json:"y"`
+  Thresholds jsonFloats `json:"thresholds,omitempty"`
+}
+
+func (obj Curve) MarshalJSON() ([]byte, error) {
+  return json.Marshal(curveJson{X: obj.X, Y: obj.Y, Thresholds: obj.Tr})
+}
+
+func (obj *Curve) UnmarshalJSON(data []byte) error {
+  r := curveJson{}
+  if err := json.Unmarshal(data, &r); err != nil {
+    return err
+  }
+  if len(r.X) != len(r.Y) || (r.Thresholds != nil && len(r.Thresholds) != len(r.X)) {
+    return fmt.Errorf("invalid curve: inconsistent number of points")
+  }
+  *obj = Curve{X: r.X, Y: r.Y, Tr: r.Thresholds}
+  return nil
+}
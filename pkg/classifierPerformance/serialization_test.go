@@ -0,0 +1,92 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "bytes"
+import   "encoding/gob"
+import   "encoding/json"
+import   "math"
+import   "reflect"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+func TestPerformanceJSON(t *testing.T) {
+  perf, err := EvalPerformance([]float64{0.1, 0.5, 0.9, 0.3}, []int{0, 1, 1, 0}); if err != nil {
+    t.Fatal(err)
+  }
+  data, err := json.Marshal(perf); if err != nil {
+    t.Fatal(err)
+  }
+  r := Performance{}
+  if err := json.Unmarshal(data, &r); err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(r, perf) {
+    t.Errorf("invalid performance: %+v", r)
+  }
+  // thresholds and counts must have the same length
+  if err := json.Unmarshal([]byte(`{"thresholds": [0.1, 0.2], "tp": [1], "fp": [1], "tn": [1], "fn": [1], "p": 1, "n": 1}`), &r); err == nil {
+    t.Error("inconsistent performance object not rejected")
+  }
+}
+
+func TestCurveJSON(t *testing.T) {
+  curve := Curve{X: []float64{0.0, 0.5, 1.0}, Y: []float64{math.NaN(), 0.5, 1.0}, Tr: []float64{math.Inf(1), 0.5, math.Inf(-1)}}
+  data, err := json.Marshal(curve); if err != nil {
+    t.Fatal(err)
+  }
+  if s := string(data); s != `{"x":[0,0.5,1],"y":["NaN",0.5,1],"thresholds":["+Inf",0.5,"-Inf"]}` {
+    t.Errorf("invalid encoding: %s", s)
+  }
+  r := Curve{}
+  if err := json.Unmarshal(data, &r); err != nil {
+    t.Fatal(err)
+  }
+  if !math.IsNaN(r.Y[0]) || r.Y[1] != 0.5 || !reflect.DeepEqual(r.X, curve.X) || !reflect.DeepEqual(r.Tr, curve.Tr) {
+    t.Errorf("invalid curve: %+v", r)
+  }
+  // thresholds are optional
+  if err := json.Unmarshal([]byte(`{"x": [0, 1], "y": [0, 1]}`), &r); err != nil || r.Tr != nil {
+    t.Errorf("curve without thresholds not decoded: %v", err)
+  }
+  for _, s := range []string{`{"x": [0, 1], "y": [0]}`, `{"x": [0], "y": ["Inf"]}`} {
+    if err := json.Unmarshal([]byte(s), &r); err == nil {
+      t.Errorf("invalid curve not rejected: %s", s)
+    }
+  }
+}
+
+func TestPerformanceGob(t *testing.T) {
+  perf, err := EvalPerformance([]float64{0.1, 0.5, 0.9, 0.3}, []int{0, 1, 1, 0}); if err != nil {
+    t.Fatal(err)
+  }
+  var buffer bytes.Buffer
+  if err := gob.NewEncoder(&buffer).Encode(perf); err != nil {
+    t.Fatal(err)
+  }
+  r := Performance{}
+  if err := gob.NewDecoder(&buffer).Decode(&r); err != nil {
+    t.Fatal(err)
+  }
+  if !reflect.DeepEqual(r, perf) {
+    t.Errorf("invalid performance: %+v", r)
+  }
+}
@@ -0,0 +1,100 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "math/rand"
+
+/* -------------------------------------------------------------------------- */
+
+// AUC of the beta model, where scores of positives follow Beta(k,1) and scores
+// of negatives Beta(1,k)
+func betaModelAUC(k float64) float64 {
+  lk , _ := math.Lgamma(k)
+  lk1, _ := math.Lgamma(k+1.0)
+  l2k, _ := math.Lgamma(2.0*k+1.0)
+  return 1.0 - k*math.Exp(lk + lk1 - l2k)
+}
+
+// Shape parameter of the beta model with the given AUC. The AUC is monotone
+// in k, so that k is found by bisection on a logarithmic scale.
+func betaModelShape(auc float64) float64 {
+  lo, hi := -30.0, 30.0
+  for i := 0; i < 200; i++ {
+    m := 0.5*(lo + hi)
+    if betaModelAUC(math.Exp(m)) < auc {
+      lo = m
+    } else {
+      hi = m
+    }
+  }
+  return math.Exp(0.5*(lo + hi))
+}
+
+// Simulate draws n labels with the given prevalence and predictions from a
+// model with the given AUC. The binormal model draws latent scores of negatives
+// from N(0,1) and of positives from N(a,1) with a = sqrt(2) Phi^-1(auc), which
+// are returned as posterior probabilities of the positive class. For an AUC
+// below 0.5 the roles of both classes are swapped. The beta model draws
+// predictions of positives from Beta(k,1) and of negatives from Beta(1,k),
+// where k is chosen such that the AUC 1 - k B(k,k+1) matches.
+func Simulate(model string, n int, auc, prevalence float64, rng *rand.Rand) ([]float64, []int, error) {
+  if n < 0 {
+    return nil, nil, fmt.Errorf("invalid sample size: %d", n)
+  }
+  if !(auc > 0.0 && auc < 1.0) {
+    return nil, nil, fmt.Errorf("invalid auc: %f", auc)
+  }
+  if !(prevalence > 0.0 && prevalence < 1.0) {
+    return nil, nil, fmt.Errorf("invalid prevalence: %f", prevalence)
+  }
+  values := make([]float64, n)
+  labels := make([]int    , n)
+  for i := range labels {
+    if rng.Float64() < prevalence {
+      labels[i] = 1
+    }
+  }
+  switch model {
+  case "", "binormal":
+    a := math.Sqrt2*normalQuantile(math.Max(auc, 1.0 - auc))
+    b := math.Log(prevalence/(1.0 - prevalence)) - 0.5*a*a
+    for i := range values {
+      x := rng.NormFloat64()
+      if (labels[i] == 1) == (auc >= 0.5) {
+        x += a
+      }
+      values[i] = 1.0/(1.0 + math.Exp(-a*x - b))
+    }
+  case "beta":
+    k := betaModelShape(auc)
+    for i := range values {
+      x := math.Pow(rng.Float64(), 1.0/k)
+      if labels[i] == 1 {
+        values[i] = x
+      } else {
+        values[i] = 1.0 - x
+      }
+    }
+  default:
+    return nil, nil, fmt.Errorf("invalid model `%s'", model)
+  }
+  return values, labels, nil
+}
@@ -0,0 +1,182 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// Sum of x with the pairwise summation of numpy, which is required to
+// reproduce results of scikit-learn bit by bit. Blocks of at most 128
+// values are summed with eight interleaved accumulators.
+func numpySum(x []float64) float64 {
+  var pairwise func(x []float64) float64
+  pairwise = func(x []float64) float64 {
+    n := len(x)
+    switch {
+    case n < 8:
+      r := 0.0
+      for i := 0; i < n; i++ {
+        r += x[i]
+      }
+      return r
+    case n <= 128:
+      var r [8]float64
+      copy(r[:], x[0:8])
+      i := 8
+      for ; i < n - n%8; i += 8 {
+        for j := 0; j < 8; j++ {
+          r[j] += x[i+j]
+        }
+      }
+      s := ((r[0] + r[1]) + (r[2] + r[3])) + ((r[4] + r[5]) + (r[6] + r[7]))
+      for ; i < n; i++ {
+        s += x[i]
+      }
+      return s
+    default:
+      m := n/2
+      m -= m%8
+      return pairwise(x[0:m]) + pairwise(x[m:])
+    }
+  }
+  // numpy starts reductions with the identity
+  return 0.0 + pairwise(x)
+}
+
+// Numbers of true and false positives at each unique prediction value in
+// decreasing order, where predictions with values greater than or equal to
+// the threshold are classified as positive (_binary_clf_curve of
+// scikit-learn). Performance must be evaluated at all unique prediction
+// values.
+func sklearnCounts(perf Performance) ([]float64, []float64, []float64) {
+  n   := perf.Len()
+  tps := make([]float64, n)
+  fps := make([]float64, n)
+  tr  := make([]float64, n)
+  for k := 0; k < n; k++ {
+    i := n-1-k
+    if i == 0 {
+      tps[k], fps[k] = float64(perf.P), float64(perf.N)
+    } else {
+      tps[k], fps[k] = float64(perf.Tp[i-1]), float64(perf.Fp[i-1])
+    }
+    tr[k] = perf.Tr[i]
+  }
+  return tps, fps, tr
+}
+
+// ROC curve as computed by roc_curve of scikit-learn, i.e. with thresholds in
+// decreasing order starting at (0,0) with threshold +Inf. If drop is true,
+// points on straight segments of the curve are dropped (drop_intermediate).
+// Performance must be evaluated at all unique prediction values.
+func SklearnRoc(perf Performance, drop bool) Curve {
+  tps, fps, tr := sklearnCounts(perf)
+  if drop && len(tps) > 2 {
+    k := 1
+    for i := 1; i < len(tps)-1; i++ {
+      if fps[i+1] - 2*fps[i] + fps[i-1] != 0 || tps[i+1] - 2*tps[i] + tps[i-1] != 0 {
+        tps[k], fps[k], tr[k] = tps[i], fps[i], tr[i]; k++
+      }
+    }
+    tps[k], fps[k], tr[k] = tps[len(tps)-1], fps[len(fps)-1], tr[len(tr)-1]; k++
+    tps, fps, tr = tps[0:k], fps[0:k], tr[0:k]
+  }
+  r := Curve{X: []float64{0}, Y: []float64{0}, Tr: []float64{math.Inf(1)}}
+  for i := range tps {
+    r.X  = append(r.X , fps[i]/float64(perf.N))
+    r.Y  = append(r.Y , tps[i]/float64(perf.P))
+    r.Tr = append(r.Tr, tr[i])
+  }
+  return r
+}
+
+// Precision-recall curve as computed by precision_recall_curve of
+// scikit-learn, i.e. with thresholds in increasing order and a final point
+// with recall 0 and precision 1, which is assigned the threshold +Inf.
+// Performance must be evaluated at all unique prediction values.
+func SklearnPrecisionRecall(perf Performance) Curve {
+  tps, fps, tr := sklearnCounts(perf)
+  r := Curve{}
+  for k := len(tps)-1; k >= 0; k-- {
+    r.Y = append(r.Y, tps[k]/(tps[k] + fps[k]))
+    if perf.P == 0 {
+      r.X = append(r.X, 1.0)
+    } else {
+      r.X = append(r.X, tps[k]/float64(perf.P))
+    }
+    r.Tr = append(r.Tr, tr[k])
+  }
+  r.X  = append(r.X , 0.0)
+  r.Y  = append(r.Y , 1.0)
+  r.Tr = append(r.Tr, math.Inf(1))
+  return r
+}
+
+// Area under a curve computed with the trapezoidal rule as by auc of
+// scikit-learn. Values of x must be either increasing or decreasing.
+func SklearnAUC(x, y []float64) (float64, error) {
+  if len(x) != len(y) {
+    return 0.0, fmt.Errorf("curve has invalid number of points: len(x)=%d, len(y)=%d", len(x), len(y))
+  }
+  if len(x) < 2 {
+    return 0.0, fmt.Errorf("at least 2 points are needed to compute the area under a curve")
+  }
+  for i := 0; i < len(x); i++ {
+    if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+      return 0.0, fmt.Errorf("curve has undefined value at point %d", i)
+    }
+  }
+  increasing := true
+  decreasing := true
+  for i := 1; i < len(x); i++ {
+    if x[i] < x[i-1] {
+      increasing = false
+    }
+    if x[i] > x[i-1] {
+      decreasing = false
+    }
+  }
+  if !increasing && !decreasing {
+    return 0.0, fmt.Errorf("x is neither increasing nor decreasing")
+  }
+  t := make([]float64, len(x)-1)
+  for i := range t {
+    // explicit conversions prevent fused multiply-add operations
+    t[i] = float64(float64(x[i+1] - x[i])*(y[i+1] + y[i]))/2.0
+  }
+  if increasing {
+    return numpySum(t), nil
+  } else {
+    return -numpySum(t), nil
+  }
+}
+
+// Average precision as computed by average_precision_score of scikit-learn,
+// i.e. the sum of precisions at each threshold weighted by the increase in
+// recall. Performance must be evaluated at all unique prediction values.
+func SklearnAveragePrecision(perf Performance) float64 {
+  pr := SklearnPrecisionRecall(perf)
+  t  := make([]float64, pr.Len()-1)
+  for i := range t {
+    t[i] = float64((pr.X[i+1] - pr.X[i])*pr.Y[i])
+  }
+  return -numpySum(t)
+}
@@ -0,0 +1,129 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+import   "reflect"
+import   "testing"
+
+/* -------------------------------------------------------------------------- */
+
+// Fixtures are the results of scikit-learn given in the examples of its
+// documentation and in its test suite, which must be reproduced exactly
+
+func sklearnPerformance(t *testing.T, values []float64, labels []int) Performance {
+  perf, err := EvalPerformance(append([]float64{}, values...), append([]int{}, labels...)); if err != nil {
+    t.Fatal(err)
+  }
+  return perf
+}
+
+func TestSklearnRoc(t *testing.T) {
+  // roc_curve(y, scores, pos_label=2) with y = [1, 1, 2, 2]
+  perf := sklearnPerformance(t, []float64{0.1, 0.4, 0.35, 0.8}, []int{0, 0, 1, 1})
+  roc  := SklearnRoc(perf, true)
+  if !reflect.DeepEqual(roc.X, []float64{0.0, 0.0, 0.5, 0.5, 1.0}) {
+    t.Errorf("invalid fpr: %v", roc.X)
+  }
+  if !reflect.DeepEqual(roc.Y, []float64{0.0, 0.5, 0.5, 1.0, 1.0}) {
+    t.Errorf("invalid tpr: %v", roc.Y)
+  }
+  if !reflect.DeepEqual(roc.Tr, []float64{math.Inf(1), 0.8, 0.4, 0.35, 0.1}) {
+    t.Errorf("invalid thresholds: %v", roc.Tr)
+  }
+  // auc(fpr, tpr)
+  if r, err := SklearnAUC(roc.X, roc.Y); err != nil {
+    t.Fatal(err)
+  } else if r != 0.75 {
+    t.Errorf("invalid auc: %v", r)
+  }
+  // roc_auc_score(y, scores) with --compat sklearn
+  metrics := NewMetrics(perf, false)
+  metrics.Compat = "sklearn"
+  if r, err := metrics.Eval("roc-auc"); err != nil {
+    t.Fatal(err)
+  } else if r[0] != 0.75 {
+    t.Errorf("invalid roc-auc: %v", r[0])
+  }
+}
+
+func TestSklearnRocDropIntermediate(t *testing.T) {
+  // test_roc_curve_drop_intermediate
+  perf := sklearnPerformance(t, []float64{0.0, 0.2, 0.5, 0.6, 0.7, 1.0}, []int{0, 0, 0, 0, 1, 1})
+  if roc := SklearnRoc(perf, true); !reflect.DeepEqual(roc.Tr, []float64{math.Inf(1), 1.0, 0.7, 0.0}) {
+    t.Errorf("invalid thresholds: %v", roc.Tr)
+  }
+  // all points are kept without drop_intermediate
+  if roc := SklearnRoc(perf, false); roc.Len() != 7 {
+    t.Errorf("expected 7 points but found %d", roc.Len())
+  }
+}
+
+func TestSklearnPrecisionRecall(t *testing.T) {
+  // precision_recall_curve(y_true, y_scores) with y_true = [0, 0, 1, 1]
+  perf := sklearnPerformance(t, []float64{0.1, 0.4, 0.35, 0.8}, []int{0, 0, 1, 1})
+  pr   := SklearnPrecisionRecall(perf)
+  if !reflect.DeepEqual(pr.Y, []float64{0.5, 2.0/3.0, 0.5, 1.0, 1.0}) {
+    t.Errorf("invalid precision: %v", pr.Y)
+  }
+  if !reflect.DeepEqual(pr.X, []float64{1.0, 1.0, 0.5, 0.5, 0.0}) {
+    t.Errorf("invalid recall: %v", pr.X)
+  }
+  // scikit-learn returns one threshold less, the last point is assigned +Inf
+  if !reflect.DeepEqual(pr.Tr, []float64{0.1, 0.35, 0.4, 0.8, math.Inf(1)}) {
+    t.Errorf("invalid thresholds: %v", pr.Tr)
+  }
+  // average_precision_score(y_true, y_scores)
+  if r := SklearnAveragePrecision(perf); r != 0.8333333333333333 {
+    t.Errorf("invalid average precision: %v", r)
+  }
+}
+
+func TestSklearnAUC(t *testing.T) {
+  // decreasing x gives the same area
+  if r, err := SklearnAUC([]float64{1.0, 0.5, 0.0}, []float64{1.0, 0.5, 0.0}); err != nil || r != 0.5 {
+    t.Errorf("invalid auc: %v (%v)", r, err)
+  }
+  if _, err := SklearnAUC([]float64{0.0, 1.0, 0.5}, []float64{0.0, 1.0, 1.0}); err == nil {
+    t.Error("non-monotonic x not rejected")
+  }
+  if _, err := SklearnAUC([]float64{0.0}, []float64{0.0}); err == nil {
+    t.Error("single point not rejected")
+  }
+}
+
+func TestNumpySum(t *testing.T) {
+  // blocks are summed pairwise, so that the sum of many equal values is
+  // exact as long as partial sums are exact
+  x := make([]float64, 1000)
+  for i := range x {
+    x[i] = 0.1
+  }
+  s := 0.0
+  for i := range x {
+    s += x[i]
+  }
+  if r := numpySum(x); r == s || math.Abs(r - 100.0) > math.Abs(s - 100.0) {
+    t.Errorf("pairwise sum %v is not more accurate than the naive sum %v", r, s)
+  }
+  if r := numpySum([]float64{1.0, 2.0, 3.0}); r != 6.0 {
+    t.Errorf("invalid sum: %v", r)
+  }
+}
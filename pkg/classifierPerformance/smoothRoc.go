@@ -0,0 +1,136 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Number of thresholds at which kernel-smoothed ROC curves are evaluated
+const smoothRocPoints = 201
+
+// Bandwidth of a Gaussian kernel density estimate computed with Silverman's
+// rule of thumb.
+func SilvermanBandwidth[T Float](values []T) float64 {
+  n := len(values)
+  if n < 2 {
+    return math.NaN()
+  }
+  x := make([]float64, n)
+  m := 0.0
+  for i := range values {
+    x[i] = float64(values[i])
+    m   += x[i]/float64(n)
+  }
+  s := 0.0
+  for i := range x {
+    s += (x[i] - m)*(x[i] - m)
+  }
+  s = math.Sqrt(s/float64(n-1))
+  sort.Float64s(x)
+  quantile := func(p float64) float64 {
+    k := p*float64(n-1)
+    i := int(k)
+    if i+1 >= n {
+      return x[n-1]
+    }
+    return x[i] + (k - float64(i))*(x[i+1] - x[i])
+  }
+  if iqr := (quantile(0.75) - quantile(0.25))/1.34; iqr > 0.0 && iqr < s {
+    s = iqr
+  }
+  return 0.9*s*math.Pow(float64(n), -0.2)
+}
+
+// Kernel bandwidths of positives and negatives, which are estimated for each
+// class with Silverman's rule of thumb if the given bandwidth is zero.
+func classBandwidths(pos, neg []float64, bandwidth float64) (float64, float64, error) {
+  if bandwidth != 0.0 {
+    return bandwidth, bandwidth, nil
+  }
+  h_pos := SilvermanBandwidth(pos)
+  h_neg := SilvermanBandwidth(neg)
+  if !(h_pos > 0.0) || !(h_neg > 0.0) {
+    return 0.0, 0.0, fmt.Errorf("bandwidth estimation failed, predictions of each class must have at least two distinct values")
+  }
+  return h_pos, h_neg, nil
+}
+
+// ROC curve computed from Gaussian kernel density estimates of the
+// predictions of positives and negatives. If the bandwidth is zero, a
+// separate bandwidth is estimated for each class with Silverman's rule of
+// thumb. The curve is evaluated at n equally spaced thresholds, which are
+// sorted in increasing order as for empirical ROC curves.
+func SmoothRoc[T Float](values []T, labels []int, bandwidth float64, n int) (Curve, error) {
+  if len(values) != len(labels) {
+    return Curve{}, fmt.Errorf("number of predictions and labels do not match")
+  }
+  if bandwidth < 0.0 || math.IsNaN(bandwidth) {
+    return Curve{}, fmt.Errorf("invalid bandwidth: %f", bandwidth)
+  }
+  if n < 2 {
+    return Curve{}, fmt.Errorf("invalid number of points: %d", n)
+  }
+  pos := []float64{}
+  neg := []float64{}
+  for i := range values {
+    switch labels[i] {
+    case 1: pos = append(pos, float64(values[i]))
+    case 0: neg = append(neg, float64(values[i]))
+    default:
+      return Curve{}, fmt.Errorf("invalid label: %d", labels[i])
+    }
+  }
+  if len(pos) == 0 || len(neg) == 0 {
+    return Curve{}, fmt.Errorf("smoothed ROC curve requires positive and negative predictions")
+  }
+  h_pos, h_neg, err := classBandwidths(pos, neg, bandwidth); if err != nil {
+    return Curve{}, err
+  }
+  // survival function of the kernel density estimate
+  survival := func(x []float64, h, t float64) float64 {
+    r := 0.0
+    for _, v := range x {
+      r += normalCdf((v - t)/h)
+    }
+    return r/float64(len(x))
+  }
+  min_v, max_v := math.Inf(1), math.Inf(-1)
+  for _, x := range [][]float64{pos, neg} {
+    for _, v := range x {
+      min_v = math.Min(min_v, v)
+      max_v = math.Max(max_v, v)
+    }
+  }
+  // thresholds cover the support of both densities up to negligible tails
+  h  := math.Max(h_pos, h_neg)
+  t0 := min_v - 5.0*h
+  t1 := max_v + 5.0*h
+  r  := Curve{}
+  for i := 0; i < n; i++ {
+    t := t0 + float64(i)*(t1 - t0)/float64(n-1)
+    r.X  = append(r.X , survival(neg, h_neg, t))
+    r.Y  = append(r.Y , survival(pos, h_pos, t))
+    r.Tr = append(r.Tr, t)
+  }
+  return r, nil
+}
@@ -0,0 +1,51 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "errors"
+import   "io"
+import   "iter"
+
+/* -------------------------------------------------------------------------- */
+
+type Prediction struct {
+  Value float64
+  Label int
+}
+
+/* -------------------------------------------------------------------------- */
+
+var errStopIteration = errors.New("stop iteration")
+
+// Iterate over all predictions in a table without reading the full table
+// into memory. Reading stops at the first error, which is passed to the
+// caller as the last element of the sequence.
+func StreamPredictions(reader io.Reader) iter.Seq2[Prediction, error] {
+  return func(yield func(Prediction, error) bool) {
+    err := ScanPredictions(reader, func(value float64, label int) error {
+      if !yield(Prediction{Value: value, Label: label}, nil) {
+        return errStopIteration
+      }
+      return nil
+    })
+    if err != nil && err != errStopIteration {
+      yield(Prediction{}, err)
+    }
+  }
+}
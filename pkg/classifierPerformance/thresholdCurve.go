@@ -0,0 +1,93 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+/* -------------------------------------------------------------------------- */
+
+import   "math"
+
+/* -------------------------------------------------------------------------- */
+
+// Measures computed from the confusion matrix at each threshold. The table
+// has one column for each measure followed by the thresholds.
+func ThresholdCurve(perf Performance, names []string, f func(ConfusionMatrix) []float64) Table {
+  r := Table{Names: append(append([]string{}, names...), "threshold"), Columns: make([][]float64, len(names)+1)}
+  for i := 0; i < perf.Len(); i++ {
+    for j, v := range f(perf.ConfusionMatrix(i)) {
+      r.Columns[j] = append(r.Columns[j], v)
+    }
+    r.Columns[len(names)] = append(r.Columns[len(names)], perf.Tr[i])
+  }
+  return r
+}
+
+// Row of a table with the largest value in the given column, where
+// undefined values are ignored. The result is a table with a single row.
+func TableOptimum(table Table, column int) Table {
+  k := -1
+  for i, v := range table.Columns[column] {
+    if !math.IsNaN(v) && (k == -1 || v > table.Columns[column][k]) {
+      k = i
+    }
+  }
+  r := Table{Names: table.Names, Columns: make([][]float64, len(table.Columns))}
+  for j := range table.Columns {
+    if k == -1 {
+      r.Columns[j] = []float64{math.NaN()}
+    } else {
+      r.Columns[j] = []float64{table.Columns[j][k]}
+    }
+  }
+  return r
+}
+
+/* -------------------------------------------------------------------------- */
+
+// Informedness (TPR + TNR - 1) at each threshold.
+func Informedness(perf Performance) Table {
+  return ThresholdCurve(perf, []string{"informedness", "tpr", "tnr"}, func(c ConfusionMatrix) []float64 {
+    return []float64{c.Informedness(), c.TPR(), c.TNR()}
+  })
+}
+
+// Markedness (PPV + NPV - 1) at each threshold.
+func Markedness(perf Performance) Table {
+  return ThresholdCurve(perf, []string{"markedness", "ppv", "npv"}, func(c ConfusionMatrix) []float64 {
+    return []float64{c.Markedness(), c.Precision(), c.NPV()}
+  })
+}
+
+// Geometric mean of sensitivity and specificity at each threshold.
+func GMean(perf Performance) Table {
+  return ThresholdCurve(perf, []string{"gmean", "tpr", "tnr"}, func(c ConfusionMatrix) []float64 {
+    return []float64{c.GMean(), c.TPR(), c.TNR()}
+  })
+}
+
+// Fowlkes-Mallows index at each threshold.
+func FowlkesMallows(perf Performance) Table {
+  return ThresholdCurve(perf, []string{"fowlkes-mallows", "precision", "recall"}, func(c ConfusionMatrix) []float64 {
+    return []float64{c.FowlkesMallows(), c.Precision(), c.TPR()}
+  })
+}
+
+// Prevalence threshold at each threshold.
+func PrevalenceThreshold(perf Performance) Table {
+  return ThresholdCurve(perf, []string{"prevalence-threshold", "tpr", "fpr"}, func(c ConfusionMatrix) []float64 {
+    return []float64{c.PrevalenceThreshold(), c.TPR(), c.FPR()}
+  })
+}
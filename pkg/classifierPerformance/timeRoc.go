@@ -0,0 +1,108 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+// Kaplan-Meier estimate of the censoring distribution G(t) = P(C > t), where
+// censored observations (event = 0) are treated as events. At tied times,
+// events precede censoring. Returns the left limit G(t-) if left is true.
+func censoringSurvival(times []float64, events []int) func(t float64, left bool) float64 {
+  index := make([]int, len(times))
+  for i := range index {
+    index[i] = i
+  }
+  sort.Slice(index, func(i, j int) bool { return times[index[i]] < times[index[j]] })
+  x := []float64{}
+  y := []float64{}
+  s := 1.0
+  for i := 0; i < len(index); {
+    t := times[index[i]]
+    n := len(index) - i
+    d := 0
+    j := i
+    for ; j < len(index) && times[index[j]] == t; j++ {
+      if events[index[j]] == 0 {
+        d++
+      }
+    }
+    if d > 0 {
+      s *= 1.0 - float64(d)/float64(n)
+      x = append(x, t)
+      y = append(y, s)
+    }
+    i = j
+  }
+  return func(t float64, left bool) float64 {
+    // number of steps at times smaller than (or equal to) t
+    k := sort.Search(len(x), func(i int) bool { return x[i] > t || (left && x[i] == t) })
+    if k == 0 {
+      return 1.0
+    }
+    return y[k-1]
+  }
+}
+
+// Cumulative/dynamic time-dependent ROC curve at the given time horizon for
+// survival data. Cases are observations with an event (event = 1) up to the
+// horizon, controls are observations without event up to the horizon.
+// Observations censored before the horizon are accounted for with inverse
+// probability of censoring weights (Uno et al., 2007). As for empirical ROC
+// curves, points are sorted by increasing thresholds and a prediction is
+// classified as positive if its value is larger than the threshold.
+func TimeDependentRoc[T Float](values []T, times []float64, events []int, horizon float64) (Curve, error) {
+  if len(values) != len(times) || len(values) != len(events) {
+    return Curve{}, fmt.Errorf("number of predictions, event times and events do not match")
+  }
+  for i := range events {
+    if events[i] != 0 && events[i] != 1 {
+      return Curve{}, fmt.Errorf("invalid event indicator: %d", events[i])
+    }
+    if math.IsNaN(times[i]) {
+      return Curve{}, fmt.Errorf("invalid event time: %f", times[i])
+    }
+  }
+  G := censoringSurvival(times, events)
+  // cases have label 1 and controls label 0, all other observations have
+  // zero weight
+  labels  := make([]int,     len(values))
+  weights := make([]float64, len(values))
+  for i := range values {
+    switch {
+    case times[i] <= horizon && events[i] == 1:
+      if g := G(times[i], true); g > 0.0 {
+        labels [i] = 1
+        weights[i] = 1.0/g
+      }
+    case times[i] > horizon:
+      if g := G(horizon, false); g > 0.0 {
+        weights[i] = 1.0/g
+      }
+    }
+  }
+  r, err := WeightedRoc(values, labels, weights); if err != nil {
+    return Curve{}, fmt.Errorf("no cases or controls at time horizon %v", horizon)
+  }
+  return r, nil
+}
@@ -0,0 +1,64 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+
+/* -------------------------------------------------------------------------- */
+
+// Inverse probability of verification weights for partially verified data,
+// where the probability of verification is estimated as the fraction of
+// verified predictions within strata of prediction values. Strata are the
+// distinct prediction values if there are at most n of them and quantile
+// bins otherwise. Weighting verified predictions within strata of the test
+// result corrects sensitivity and specificity for verification bias (Begg
+// and Greenes, 1983), assuming that verification depends only on the
+// prediction. Unverified predictions have zero weight.
+func VerificationWeights[T Float](values []T, verified []bool, n int) ([]float64, error) {
+  if len(values) != len(verified) {
+    return nil, fmt.Errorf("number of predictions and verification indicators do not match")
+  }
+  if n < 1 {
+    return nil, fmt.Errorf("invalid number of bins: %d", n)
+  }
+  strata, m, err := ratingCategories(values, n); if err != nil {
+    return nil, err
+  }
+  n_all := make([]int, m)
+  n_ver := make([]int, m)
+  for i, k := range strata {
+    n_all[k]++
+    if verified[i] {
+      n_ver[k]++
+    }
+  }
+  for k := range n_ver {
+    if n_ver[k] == 0 {
+      return nil, fmt.Errorf("stratum %d of %d contains no verified predictions", k+1, m)
+    }
+  }
+  r := make([]float64, len(values))
+  for i, k := range strata {
+    if verified[i] {
+      r[i] = float64(n_all[k])/float64(n_ver[k])
+    }
+  }
+  return r, nil
+}
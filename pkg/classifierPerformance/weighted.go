@@ -0,0 +1,117 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+
+/* -------------------------------------------------------------------------- */
+
+import   "fmt"
+import   "math"
+import   "sort"
+
+/* -------------------------------------------------------------------------- */
+
+func checkWeights[T Float](values []T, labels []int, weights []float64) error {
+  if len(values) != len(labels) || len(values) != len(weights) {
+    return fmt.Errorf("number of predictions, labels and weights do not match")
+  }
+  for i := range labels {
+    if math.IsNaN(float64(values[i])) {
+      return fmt.Errorf("invalid prediction: NaN")
+    }
+    if labels[i] != 0 && labels[i] != 1 {
+      return fmt.Errorf("invalid label: %d", labels[i])
+    }
+    if weights[i] < 0.0 || math.IsNaN(weights[i]) || math.IsInf(weights[i], 1) {
+      return fmt.Errorf("invalid weight: %f", weights[i])
+    }
+  }
+  return nil
+}
+
+// Weighted sums of positives and negatives
+func weightedTotals(labels []int, weights []float64) (float64, float64) {
+  p, n := 0.0, 0.0
+  for i := range labels {
+    if labels[i] == 1 {
+      p += weights[i]
+    } else {
+      n += weights[i]
+    }
+  }
+  return p, n
+}
+
+// ROC curve of weighted predictions, where each prediction counts with its
+// weight. Predictions with zero weight are ignored. As for empirical ROC
+// curves, points are sorted by increasing thresholds and a prediction is
+// classified as positive if its value is larger than the threshold.
+func WeightedRoc[T Float](values []T, labels []int, weights []float64) (Curve, error) {
+  if err := checkWeights(values, labels, weights); err != nil {
+    return Curve{}, err
+  }
+  s_pos, s_neg := weightedTotals(labels, weights)
+  if s_pos == 0.0 || s_neg == 0.0 {
+    return Curve{}, fmt.Errorf("weighted ROC curve requires positive and negative predictions")
+  }
+  index := make([]int, len(values))
+  for i := range index {
+    index[i] = i
+  }
+  sort.Slice(index, func(i, j int) bool { return values[index[i]] < values[index[j]] })
+  // weighted numbers of positives and negatives with values larger than the
+  // current threshold
+  c_pos, c_neg := s_pos, s_neg
+  r := Curve{X: []float64{1.0}, Y: []float64{1.0}, Tr: []float64{math.Inf(-1)}}
+  for i := 0; i < len(index); {
+    v := values[index[i]]
+    j := i+1
+    for j < len(index) && values[index[j]] == v {
+      j++
+    }
+    for ; i < j; i++ {
+      if labels[index[i]] == 1 {
+        c_pos -= weights[index[i]]
+      } else {
+        c_neg -= weights[index[i]]
+      }
+    }
+    r.X  = append(r.X , math.Max(0.0, c_neg/s_neg))
+    r.Y  = append(r.Y , math.Max(0.0, c_pos/s_pos))
+    r.Tr = append(r.Tr, float64(v))
+  }
+  return r, nil
+}
+
+// True and false positive rates of weighted predictions at threshold t.
+func WeightedRates[T Float](values []T, labels []int, weights []float64, t T) (float64, float64, error) {
+  if err := checkWeights(values, labels, weights); err != nil {
+    return 0.0, 0.0, err
+  }
+  s_pos, s_neg := weightedTotals(labels, weights)
+  c_pos, c_neg := 0.0, 0.0
+  for i := range values {
+    if values[i] > t {
+      if labels[i] == 1 {
+        c_pos += weights[i]
+      } else {
+        c_neg += weights[i]
+      }
+    }
+  }
+  return c_pos/s_pos, c_neg/s_neg, nil
+}
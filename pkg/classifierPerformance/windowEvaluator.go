@@ -0,0 +1,95 @@
+/* Copyright (C) 2019 Philipp Benner
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package classifierPerformance
+
+import   "fmt"
+
+/* -------------------------------------------------------------------------- */
+
+// WindowEvaluator keeps the n most recent predictions in a ring buffer and
+// computes the exact performance of this rolling window on demand. The
+// buffer grows with the window until it is full.
+type WindowEvaluator struct {
+  values []float64
+  labels []int
+  size   int
+  next   int
+  full   bool
+}
+
+/* -------------------------------------------------------------------------- */
+
+func NewWindowEvaluator(n int) (*WindowEvaluator, error) {
+  if n <= 0 {
+    return nil, fmt.Errorf("invalid window size: %d", n)
+  }
+  return &WindowEvaluator{size: n}, nil
+}
+
+/* -------------------------------------------------------------------------- */
+
+func (obj *WindowEvaluator) Add(value float64, label int) {
+  if obj.full {
+    obj.values[obj.next] = value
+    obj.labels[obj.next] = label
+  } else {
+    obj.values = append(obj.values, value)
+    obj.labels = append(obj.labels, label)
+  }
+  if obj.next++; obj.next == obj.size {
+    obj.next = 0
+    obj.full = true
+  }
+}
+
+func (obj *WindowEvaluator) AddBatch(values []float64, labels []int) {
+  for i := range values {
+    obj.Add(values[i], labels[i])
+  }
+}
+
+func (obj *WindowEvaluator) Len() int {
+  if obj.full {
+    return len(obj.values)
+  }
+  return obj.next
+}
+
+func (obj *WindowEvaluator) Reset() {
+  obj.values = obj.values[:0]
+  obj.labels = obj.labels[:0]
+  obj.next   = 0
+  obj.full   = false
+}
+
+// Predictions of the current window in the order they were added
+func (obj *WindowEvaluator) Predictions() ([]float64, []int) {
+  values := make([]float64, 0, obj.Len())
+  labels := make([]int,     0, obj.Len())
+  if obj.full {
+    values = append(values, obj.values[obj.next:]...)
+    labels = append(labels, obj.labels[obj.next:]...)
+  }
+  values = append(values, obj.values[:obj.next]...)
+  labels = append(labels, obj.labels[:obj.next]...)
+  return values, labels
+}
+
+func (obj *WindowEvaluator) Performance() (Performance, error) {
+  // the window must not be reordered, therefore evaluate a copy
+  return EvalPerformance(obj.Predictions())
+}
@@ -0,0 +1,304 @@
+// Copyright (C) 2019 Philipp Benner
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: evaluationService.proto
+
+package evaluationService
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Evaluation_EvaluateScalar_FullMethodName = "/classifierPerformance.Evaluation/EvaluateScalar"
+	Evaluation_EvaluateCurve_FullMethodName  = "/classifierPerformance.Evaluation/EvaluateCurve"
+	Evaluation_Evaluate_FullMethodName       = "/classifierPerformance.Evaluation/Evaluate"
+	Evaluation_AddPredictions_FullMethodName = "/classifierPerformance.Evaluation/AddPredictions"
+	Evaluation_ResetSession_FullMethodName   = "/classifierPerformance.Evaluation/ResetSession"
+)
+
+// EvaluationClient is the client API for Evaluation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Evaluation of classifier predictions. Predictions are either given with
+// each request or accumulated in named sessions with AddPredictions, which
+// allows to stream large numbers of predictions and to request metrics
+// periodically.
+type EvaluationClient interface {
+	// Evaluate scalar metrics, e.g. roc-auc
+	EvaluateScalar(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*ScalarResponse, error)
+	// Evaluate curves and other tables, e.g. roc
+	EvaluateCurve(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*CurveResponse, error)
+	// Evaluate targets of all kinds, including the confusion matrices of the
+	// performance target
+	Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*Results, error)
+	// Add a stream of prediction batches to a session
+	AddPredictions(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PredictionBatch, SessionStatus], error)
+	// Remove all predictions of a session
+	ResetSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*SessionStatus, error)
+}
+
+type evaluationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEvaluationClient(cc grpc.ClientConnInterface) EvaluationClient {
+	return &evaluationClient{cc}
+}
+
+func (c *evaluationClient) EvaluateScalar(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*ScalarResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScalarResponse)
+	err := c.cc.Invoke(ctx, Evaluation_EvaluateScalar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *evaluationClient) EvaluateCurve(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*CurveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CurveResponse)
+	err := c.cc.Invoke(ctx, Evaluation_EvaluateCurve_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *evaluationClient) Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*Results, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Results)
+	err := c.cc.Invoke(ctx, Evaluation_Evaluate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *evaluationClient) AddPredictions(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PredictionBatch, SessionStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Evaluation_ServiceDesc.Streams[0], Evaluation_AddPredictions_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PredictionBatch, SessionStatus]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Evaluation_AddPredictionsClient = grpc.ClientStreamingClient[PredictionBatch, SessionStatus]
+
+func (c *evaluationClient) ResetSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*SessionStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SessionStatus)
+	err := c.cc.Invoke(ctx, Evaluation_ResetSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EvaluationServer is the server API for Evaluation service.
+// All implementations must embed UnimplementedEvaluationServer
+// for forward compatibility.
+//
+// Evaluation of classifier predictions. Predictions are either given with
+// each request or accumulated in named sessions with AddPredictions, which
+// allows to stream large numbers of predictions and to request metrics
+// periodically.
+type EvaluationServer interface {
+	// Evaluate scalar metrics, e.g. roc-auc
+	EvaluateScalar(context.Context, *EvaluateRequest) (*ScalarResponse, error)
+	// Evaluate curves and other tables, e.g. roc
+	EvaluateCurve(context.Context, *EvaluateRequest) (*CurveResponse, error)
+	// Evaluate targets of all kinds, including the confusion matrices of the
+	// performance target
+	Evaluate(context.Context, *EvaluateRequest) (*Results, error)
+	// Add a stream of prediction batches to a session
+	AddPredictions(grpc.ClientStreamingServer[PredictionBatch, SessionStatus]) error
+	// Remove all predictions of a session
+	ResetSession(context.Context, *SessionRequest) (*SessionStatus, error)
+	mustEmbedUnimplementedEvaluationServer()
+}
+
+// UnimplementedEvaluationServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEvaluationServer struct{}
+
+func (UnimplementedEvaluationServer) EvaluateScalar(context.Context, *EvaluateRequest) (*ScalarResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvaluateScalar not implemented")
+}
+func (UnimplementedEvaluationServer) EvaluateCurve(context.Context, *EvaluateRequest) (*CurveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvaluateCurve not implemented")
+}
+func (UnimplementedEvaluationServer) Evaluate(context.Context, *EvaluateRequest) (*Results, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Evaluate not implemented")
+}
+func (UnimplementedEvaluationServer) AddPredictions(grpc.ClientStreamingServer[PredictionBatch, SessionStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method AddPredictions not implemented")
+}
+func (UnimplementedEvaluationServer) ResetSession(context.Context, *SessionRequest) (*SessionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetSession not implemented")
+}
+func (UnimplementedEvaluationServer) mustEmbedUnimplementedEvaluationServer() {}
+func (UnimplementedEvaluationServer) testEmbeddedByValue()                    {}
+
+// UnsafeEvaluationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EvaluationServer will
+// result in compilation errors.
+type UnsafeEvaluationServer interface {
+	mustEmbedUnimplementedEvaluationServer()
+}
+
+func RegisterEvaluationServer(s grpc.ServiceRegistrar, srv EvaluationServer) {
+	// If the following call pancis, it indicates UnimplementedEvaluationServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Evaluation_ServiceDesc, srv)
+}
+
+func _Evaluation_EvaluateScalar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EvaluationServer).EvaluateScalar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Evaluation_EvaluateScalar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EvaluationServer).EvaluateScalar(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Evaluation_EvaluateCurve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EvaluationServer).EvaluateCurve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Evaluation_EvaluateCurve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EvaluationServer).EvaluateCurve(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Evaluation_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EvaluationServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Evaluation_Evaluate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EvaluationServer).Evaluate(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Evaluation_AddPredictions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EvaluationServer).AddPredictions(&grpc.GenericServerStream[PredictionBatch, SessionStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Evaluation_AddPredictionsServer = grpc.ClientStreamingServer[PredictionBatch, SessionStatus]
+
+func _Evaluation_ResetSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EvaluationServer).ResetSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Evaluation_ResetSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EvaluationServer).ResetSession(ctx, req.(*SessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Evaluation_ServiceDesc is the grpc.ServiceDesc for Evaluation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Evaluation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "classifierPerformance.Evaluation",
+	HandlerType: (*EvaluationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "EvaluateScalar",
+			Handler:    _Evaluation_EvaluateScalar_Handler,
+		},
+		{
+			MethodName: "EvaluateCurve",
+			Handler:    _Evaluation_EvaluateCurve_Handler,
+		},
+		{
+			MethodName: "Evaluate",
+			Handler:    _Evaluation_Evaluate_Handler,
+		},
+		{
+			MethodName: "ResetSession",
+			Handler:    _Evaluation_ResetSession_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AddPredictions",
+			Handler:       _Evaluation_AddPredictions_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "evaluationService.proto",
+}